@@ -0,0 +1,195 @@
+// machinesim pretends to be a washer or dryer for local testing. It reports one
+// cycle to the telemetry endpoint: door open while loading, running with the
+// time remaining, then stopped with the door open again for unloading.
+//
+//	go run ./cmd/machinesim -serial WASHER-01 -token <device token>
+//
+// With -commands it waits for its commands instead, it polls them, acknowledges
+// them and starts, stops or unlocks as told. -ignore leaves the first commands
+// unanswered to see them sent again.
+//
+// The device token comes from POST /machine/{serial_id}/credential.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+type simulator struct {
+	url    string
+	serial string
+	token  string
+	client *http.Client
+}
+
+// call sends body to the device endpoint at path and decodes the reply into out
+func (s *simulator) call(method string, path string, body any, out any) error {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequest(method, s.url+"/device/machine/"+s.serial+path, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+s.token)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	reply, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", response.Status, reply)
+	}
+
+	if out != nil {
+		return json.Unmarshal(reply, out)
+	}
+	return nil
+}
+
+func (s *simulator) report(telemetry model.MachineTelemetryDTO) error {
+	if err := s.call(http.MethodPost, "/telemetry", telemetry, nil); err != nil {
+		return err
+	}
+
+	log.Printf("reported door_open=%v running=%v cycle_remaining=%d error_code=%v",
+		telemetry.DoorOpen, telemetry.Running, telemetry.CycleRemaining, telemetry.ErrorCode)
+	return nil
+}
+
+// followCommands runs the machine by its commands until it's interrupted,
+// reporting its state every interval
+func (s *simulator) followCommands(cycle time.Duration, interval time.Duration, ignore int) {
+	var finishAt time.Time
+	running := false
+	doorOpen := true
+
+	for {
+		commands := []model.MachineCommand{}
+		if err := s.call(http.MethodGet, "/commands", nil, &commands); err != nil {
+			log.Println("ERR:", err)
+		}
+
+		for _, command := range commands {
+			if ignore > 0 {
+				ignore--
+				log.Printf("ignored %s command %s, attempt %d", command.CommandType, command.CommandID, command.Attempts)
+				continue
+			}
+
+			ack := model.MachineCommandAckDTO{Accepted: true}
+			switch command.CommandType {
+			case model.StartCommand:
+				if running {
+					reason := "already running"
+					ack = model.MachineCommandAckDTO{Error: &reason}
+					break
+				}
+				running, doorOpen = true, false
+				finishAt = time.Now().Add(cycle)
+			case model.StopCommand:
+				running = false
+			case model.UnlockCommand:
+				if running {
+					reason := "cannot unlock while running"
+					ack = model.MachineCommandAckDTO{Error: &reason}
+					break
+				}
+				doorOpen = true
+			}
+
+			if err := s.call(http.MethodPost, "/commands/"+command.CommandID+"/ack", ack, nil); err != nil {
+				log.Println("ERR:", err)
+				continue
+			}
+			log.Printf("%s command %s accepted=%v program=%v temperature=%v",
+				command.CommandType, command.CommandID, ack.Accepted, command.Program, command.Temperature)
+		}
+
+		if running && !time.Now().Before(finishAt) {
+			running = false
+		}
+
+		telemetry := model.MachineTelemetryDTO{DoorOpen: doorOpen, Running: running}
+		if running {
+			telemetry.CycleRemaining = int(time.Until(finishAt).Seconds())
+		}
+		if err := s.report(telemetry); err != nil {
+			log.Println("ERR:", err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func main() {
+	url := flag.String("url", "http://localhost:3000", "API base url")
+	serial := flag.String("serial", "", "machine serial")
+	token := flag.String("token", "", "device token of the machine")
+	cycle := flag.Duration("cycle", 2*time.Minute, "how long a cycle runs")
+	interval := flag.Duration("interval", 10*time.Second, "how often the machine reports while running")
+	loading := flag.Duration("loading", 5*time.Second, "how long the door stays open before the cycle starts")
+	errorCode := flag.String("error", "", "stop halfway through the cycle with this error code")
+	commands := flag.Bool("commands", false, "wait for start, stop and unlock commands instead of running one cycle")
+	ignore := flag.Int("ignore", 0, "with -commands, leave this many commands unanswered")
+	flag.Parse()
+
+	if *serial == "" || *token == "" {
+		flag.Usage()
+		log.Fatal("-serial and -token are required")
+	}
+
+	s := &simulator{url: *url, serial: *serial, token: *token, client: &http.Client{Timeout: 10 * time.Second}}
+
+	if *commands {
+		s.followCommands(*cycle, *interval, *ignore)
+		return
+	}
+
+	if err := s.report(model.MachineTelemetryDTO{DoorOpen: true}); err != nil {
+		log.Fatal(err)
+	}
+	time.Sleep(*loading)
+
+	finishAt := time.Now().Add(*cycle)
+	failAt := time.Now().Add(*cycle / 2)
+
+	for remaining := time.Until(finishAt); remaining > 0; remaining = time.Until(finishAt) {
+		if *errorCode != "" && time.Now().After(failAt) {
+			if err := s.report(model.MachineTelemetryDTO{ErrorCode: errorCode}); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		if err := s.report(model.MachineTelemetryDTO{Running: true, CycleRemaining: int(remaining.Seconds())}); err != nil {
+			log.Println("ERR:", err)
+		}
+		time.Sleep(min(*interval, remaining))
+	}
+
+	if err := s.report(model.MachineTelemetryDTO{}); err != nil {
+		log.Fatal(err)
+	}
+	if err := s.report(model.MachineTelemetryDTO{DoorOpen: true}); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -14,6 +14,12 @@ type Config struct {
 	JWT_ACCESS_TOKEN string
 	PORT             string
 	APP_ENV          string
+
+	PAYMENT_PROVIDER       string
+	PROMPTPAY_ID           string
+	PAYMENT_WEBHOOK_SECRET string
+
+	RECEIPT_FONT_PATH string
 }
 
 type RoutesRegister struct {
@@ -34,6 +40,10 @@ func Load() (*Config, error) {
 	jwtToken := os.Getenv("JWT_ACCESS_TOKEN")
 	port := os.Getenv("PORT")
 	appEnv := os.Getenv("APP_ENV")
+	paymentProvider := os.Getenv("PAYMENT_PROVIDER")
+	promptPayID := os.Getenv("PROMPTPAY_ID")
+	paymentWebhookSecret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	receiptFontPath := os.Getenv("RECEIPT_FONT_PATH")
 
 	return &Config{
 		FRONTEND_URL:     frontURL,
@@ -41,6 +51,12 @@ func Load() (*Config, error) {
 		JWT_ACCESS_TOKEN: jwtToken,
 		PORT:             port,
 		APP_ENV:          appEnv,
+
+		PAYMENT_PROVIDER:       paymentProvider,
+		PROMPTPAY_ID:           promptPayID,
+		PAYMENT_WEBHOOK_SECRET: paymentWebhookSecret,
+
+		RECEIPT_FONT_PATH: receiptFontPath,
 	}, nil
 }
 
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobController interface {
+	GetDeadJobs(c *fiber.Ctx) error
+	RetryDeadJob(c *fiber.Ctx) error
+}
+
+type jobController struct {
+	jobUsecase model.JobUsecase
+}
+
+func CreateNewJobController(jobUsecase model.JobUsecase) JobController {
+	return &jobController{jobUsecase: jobUsecase}
+}
+
+//	@Summary		Get dead jobs
+//	@Description	Retrieve every job that failed all of its attempts with its last error, newest first
+//	@Tags			Job
+//	@Produce		json
+//	@Success		200	{array}		model.Job	"OK"
+//	@Failure		500	{string}	string		"Internal Server Error"
+//	@Router			/job/dead [get]
+func (u *jobController) GetDeadJobs(c *fiber.Ctx) error {
+	response, err := u.jobUsecase.GetDeadJobs()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Retry dead job
+//	@Description	Give a dead job a fresh set of attempts, it runs right away
+//	@Tags			Job
+//	@Produce		json
+//	@Param			job_id	path		string		true	"Job ID"
+//	@Success		200		{object}	model.Job	"OK"
+//	@Failure		400		{string}	string		"Job Not Dead"
+//	@Failure		404		{string}	string		"Not Found"
+//	@Failure		500		{string}	string		"Internal Server Error"
+//	@Router			/job/{job_id}/retry [post]
+func (u *jobController) RetryDeadJob(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+
+	response, err := u.jobUsecase.RetryDeadJob(jobID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LoyaltyController interface {
+	GetHistory(c *fiber.Ctx) error
+}
+
+type loyaltyController struct {
+	loyaltyUsecase model.LoyaltyUsecase
+}
+
+func CreateNewLoyaltyController(loyaltyUsecase model.LoyaltyUsecase) LoyaltyController {
+	return &loyaltyController{loyaltyUsecase: loyaltyUsecase}
+}
+
+//	@Summary		Get points history
+//	@Description	Retrieve the loyalty points balance and every points transaction newest first, customers can only see their own
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		string					true	"User ID"
+//	@Success		200	{object}	model.LoyaltyHistory	"OK"
+//	@Failure		403	{string}	string					"Forbidden"
+//	@Failure		500	{string}	string					"Internal Server Error"
+//	@Router			/users/{id}/points [get]
+func (u *loyaltyController) GetHistory(c *fiber.Ctx) error {
+	userID := c.Params("id")
+
+	requesterID := getCookieData(c, "userID")
+	requesterRole := getCookieData(c, "positionID")
+
+	response, err := u.loyaltyUsecase.GetHistory(userID, requesterID, requesterRole)
+	if err != nil {
+		if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
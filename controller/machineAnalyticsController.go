@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strings"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MachineAnalyticsController interface {
+	GetAnalytics(c *fiber.Ctx) error
+}
+
+type machineAnalyticsController struct {
+	analyticsUsecase model.MachineAnalyticsUsecase
+}
+
+func CreateNewMachineAnalyticsController(analyticsUsecase model.MachineAnalyticsUsecase) MachineAnalyticsController {
+	return &machineAnalyticsController{analyticsUsecase: analyticsUsecase}
+}
+
+func machineAnalyticsErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Get machine analytics
+//	@Description	Cycles, busy, idle and out of service minutes, and revenue of every machine of a branch per day (Thailand time), with the cycles started in each hour of the week. Revenue is the list price of paid baskets before promo discounts. Reports accepted before their accept time was recorded are not counted as out of service. Manager of the branch only
+//	@Tags			Machine
+//	@Produce		json
+//	@Param			branch_id	query		string					true	"Branch ID"
+//	@Param			from		query		string					false	"YYYY-MM-DD, 6 days before to when empty"
+//	@Param			to			query		string					false	"YYYY-MM-DD, today when empty"
+//	@Success		200			{object}	model.MachineAnalytics	"OK"
+//	@Failure		400			{string}	string					"Bad Request"
+//	@Failure		403			{string}	string					"Forbidden"
+//	@Failure		404			{string}	string					"Branch Not Found"
+//	@Failure		406			{string}	string					"Not Acceptable"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/machine/analytics [get]
+func (u *machineAnalyticsController) GetAnalytics(c *fiber.Ctx) error {
+	branchID := c.Query("branch_id")
+	if branchID == "" {
+		return c.Status(fiber.StatusNotAcceptable).SendString("ERR: branch_id is required")
+	}
+
+	to := time.Now().In(model.ReportLocation)
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, value, model.ReportLocation)
+		if err != nil {
+			return c.Status(fiber.StatusNotAcceptable).SendString("ERR: to must be YYYY-MM-DD")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -6)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, value, model.ReportLocation)
+		if err != nil {
+			return c.Status(fiber.StatusNotAcceptable).SendString("ERR: from must be YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.analyticsUsecase.GetAnalytics(branchID, from, to, userID, userRole)
+	if err != nil {
+		return c.Status(machineAnalyticsErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
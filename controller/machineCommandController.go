@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MachineCommandController interface {
+	IssueCommand(c *fiber.Ctx) error
+	GetCommands(c *fiber.Ctx) error
+	PollCommands(c *fiber.Ctx) error
+	AckCommand(c *fiber.Ctx) error
+}
+
+type machineCommandController struct {
+	commandUsecase model.MachineCommandUsecase
+}
+
+func CreateNewMachineCommandController(commandUsecase model.MachineCommandUsecase) MachineCommandController {
+	return &machineCommandController{commandUsecase: commandUsecase}
+}
+
+func machineCommandErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	} else if strings.Contains(err.Error(), "401") {
+		return fiber.StatusUnauthorized
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Issue machine command
+//	@Description	Tell the machine to start, stop or unlock. Start and unlock need a paid basket in the machine, program and temperature only apply to start
+//	@Tags			Machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			serial_id	path		string							true	"Machine Serial"
+//	@Param			Command		body		model.IssueMachineCommandDTO	true	"Command"
+//	@Success		201			{object}	model.MachineCommand			"Created"
+//	@Failure		400			{string}	string							"Bad Request"
+//	@Failure		403			{string}	string							"Forbidden"
+//	@Failure		404			{string}	string							"Not Found"
+//	@Failure		406			{string}	string							"Not Acceptable"
+//	@Failure		500			{string}	string							"Internal Server Error"
+//	@Router			/machine/{serial_id}/command [post]
+func (u *machineCommandController) IssueCommand(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	command := new(model.IssueMachineCommandDTO)
+	if err := c.BodyParser(command); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(command); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	response, err := u.commandUsecase.IssueCommand(machineSerial, command, userID, userRole)
+	if err != nil {
+		return c.Status(machineCommandErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get machine commands
+//	@Description	Every command the machine was given, newest first
+//	@Tags			Machine
+//	@Produce		json
+//	@Param			serial_id	path		string					true	"Machine Serial"
+//	@Success		200			{array}		model.MachineCommand	"OK"
+//	@Failure		403			{string}	string					"Forbidden"
+//	@Failure		404			{string}	string					"Not Found"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/machine/{serial_id}/command [get]
+func (u *machineCommandController) GetCommands(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.commandUsecase.GetByMachineSerial(machineSerial, userID, userRole)
+	if err != nil {
+		return c.Status(machineCommandErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Poll machine commands
+//	@Description	Called by the machine itself with its device token as bearer token. Returns the commands waiting for the machine, each one has to be acknowledged
+//	@Tags			Machine
+//	@Produce		json
+//	@Param			Authorization	header		string					true	"Bearer device token"
+//	@Param			serial_id		path		string					true	"Machine Serial"
+//	@Success		200				{array}		model.MachineCommand	"OK"
+//	@Failure		401				{string}	string					"Unauthorized"
+//	@Failure		500				{string}	string					"Internal Server Error"
+//	@Router			/device/machine/{serial_id}/commands [get]
+func (u *machineCommandController) PollCommands(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	deviceToken := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if deviceToken == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	response, err := u.commandUsecase.PollCommands(machineSerial, deviceToken)
+	if err != nil {
+		return c.Status(machineCommandErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Acknowledge machine command
+//	@Description	Called by the machine itself with its device token as bearer token, to accept or reject a command it polled
+//	@Tags			Machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer device token"
+//	@Param			serial_id		path		string						true	"Machine Serial"
+//	@Param			command_id		path		string						true	"Command ID"
+//	@Param			Ack				body		model.MachineCommandAckDTO	true	"Answer"
+//	@Success		200				{object}	model.MachineCommand		"OK"
+//	@Failure		400				{string}	string						"Bad Request"
+//	@Failure		401				{string}	string						"Unauthorized"
+//	@Failure		404				{string}	string						"Not Found"
+//	@Failure		406				{string}	string						"Not Acceptable"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/device/machine/{serial_id}/commands/{command_id}/ack [post]
+func (u *machineCommandController) AckCommand(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+	commandID := c.Params("command_id")
+
+	deviceToken := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if deviceToken == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	ack := new(model.MachineCommandAckDTO)
+	if err := c.BodyParser(ack); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(ack); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	response, err := u.commandUsecase.AckCommand(machineSerial, deviceToken, commandID, ack)
+	if err != nil {
+		return c.Status(machineCommandErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"strings"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
 
@@ -120,13 +121,14 @@ func (u *machineReportController) GetAll(c *fiber.Ctx) error {
 }
 
 // @Summary		Update the status of a machine report
-// @Description	Update the status of a specific machine report
+// @Description	Update the status of a specific machine report. Pending can move to In Progress or Cancel, In Progress to Fixed or Cancel. The machine is out of service while any of its reports is In Progress
 // @Tags			Machine Reports
 // @Accept			json
 // @Produce		json
 // @Param			status	body		model.UpdateMachineReportStatusDTO	true	"Updated Status"
 // @Success		200		{object}	model.MachineReportDetail
 // @Failure		204		{string}	string	"Record not found"
+// @Failure		400		{string}	string	"Bad Request"
 // @Failure		401		{string}	string	"Unauthorized"
 // @Failure		500		{string}	string	"Invalid request"
 // @Security		BearerAuth
@@ -144,6 +146,8 @@ func (u *machineReportController) UpdateMachineReportStatus(c *fiber.Ctx) error
 			return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
 		} else if err.Error() == "record not found" {
 			return c.Status(fiber.StatusNoContent).SendString("record not found")
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 		}
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MachineTelemetryController interface {
+	IssueCredential(c *fiber.Ctx) error
+	Report(c *fiber.Ctx) error
+}
+
+type machineTelemetryController struct {
+	telemetryUsecase model.MachineTelemetryUsecase
+}
+
+func CreateNewMachineTelemetryController(telemetryUsecase model.MachineTelemetryUsecase) MachineTelemetryController {
+	return &machineTelemetryController{telemetryUsecase: telemetryUsecase}
+}
+
+func machineTelemetryErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "401") {
+		return fiber.StatusUnauthorized
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Issue machine credential
+//	@Description	Make a new device token for the machine to report its state with, the token is only shown once and the previous one stops working
+//	@Tags			Machine
+//	@Produce		json
+//	@Param			serial_id	path		string					true	"Machine Serial"
+//	@Success		200			{object}	model.MachineCredential	"OK"
+//	@Failure		403			{string}	string					"Forbidden"
+//	@Failure		404			{string}	string					"Not Found"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/machine/{serial_id}/credential [post]
+func (u *machineTelemetryController) IssueCredential(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.telemetryUsecase.IssueCredential(machineSerial, userID, userRole)
+	if err != nil {
+		return c.Status(machineTelemetryErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Report machine state
+//	@Description	Called by the machine itself with its device token as bearer token. The report drives whether the machine is available and when the basket in it is done
+//	@Tags			Machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer device token"
+//	@Param			serial_id		path		string						true	"Machine Serial"
+//	@Param			Telemetry		body		model.MachineTelemetryDTO	true	"Machine State"
+//	@Success		200				{object}	model.MachineState			"OK"
+//	@Failure		401				{string}	string						"Unauthorized"
+//	@Failure		406				{string}	string						"Not Acceptable"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/device/machine/{serial_id}/telemetry [post]
+func (u *machineTelemetryController) Report(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	deviceToken := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if deviceToken == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	report := new(model.MachineTelemetryDTO)
+	if err := c.BodyParser(report); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(report); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	response, err := u.telemetryUsecase.Report(machineSerial, deviceToken, report)
+	if err != nil {
+		return c.Status(machineTelemetryErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
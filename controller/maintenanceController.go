@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MaintenanceController interface {
+	SetPlan(c *fiber.Ctx) error
+	GetPlan(c *fiber.Ctx) error
+}
+
+type maintenanceController struct {
+	maintenanceUsecase model.MaintenanceUsecase
+}
+
+func CreateNewMaintenanceController(maintenanceUsecase model.MaintenanceUsecase) MaintenanceController {
+	return &maintenanceController{maintenanceUsecase: maintenanceUsecase}
+}
+
+func maintenanceErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Set machine maintenance plan
+//	@Description	Open a preventive report for the machine every N completed cycles or every N days since it was last serviced, whichever comes first. Fixing a report of the machine counts as a service
+//	@Tags			Machine
+//	@Accept			json
+//	@Produce		json
+//	@Param			serial_id	path		string						true	"Machine Serial"
+//	@Param			Plan		body		model.SetMaintenancePlanDTO	true	"Maintenance Plan"
+//	@Success		200			{object}	model.MaintenanceStatus		"OK"
+//	@Failure		403			{string}	string						"Forbidden"
+//	@Failure		404			{string}	string						"Not Found"
+//	@Failure		406			{string}	string						"Not Acceptable"
+//	@Failure		500			{string}	string						"Internal Server Error"
+//	@Router			/machine/{serial_id}/maintenance [put]
+func (u *maintenanceController) SetPlan(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	plan := new(model.SetMaintenancePlanDTO)
+	if err := c.BodyParser(plan); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(plan); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.maintenanceUsecase.SetPlan(machineSerial, plan, userID, userRole)
+	if err != nil {
+		return c.Status(maintenanceErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Get machine maintenance plan
+//	@Description	Get the maintenance plan of the machine with the cycles it completed since it was last serviced
+//	@Tags			Machine
+//	@Produce		json
+//	@Param			serial_id	path		string					true	"Machine Serial"
+//	@Success		200			{object}	model.MaintenanceStatus	"OK"
+//	@Failure		403			{string}	string					"Forbidden"
+//	@Failure		404			{string}	string					"Not Found"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/machine/{serial_id}/maintenance [get]
+func (u *maintenanceController) GetPlan(c *fiber.Ctx) error {
+	machineSerial := c.Params("serial_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.maintenanceUsecase.GetPlan(machineSerial, userID, userRole)
+	if err != nil {
+		return c.Status(maintenanceErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationController interface {
+	GetByBranchID(c *fiber.Ctx) error
+	MarkRead(c *fiber.Ctx) error
+}
+
+type notificationController struct {
+	notificationUsecase usecases.NotificationUsecase
+}
+
+func CreateNewNotificationController(notificationUsecase usecases.NotificationUsecase) NotificationController {
+	return &notificationController{notificationUsecase: notificationUsecase}
+}
+
+func notificationErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Get branch notifications
+//	@Description	Retrieve notifications of a branch newest first, staff of the branch only
+//	@Tags			Notification
+//	@Produce		json
+//	@Param			branch_id	path		string				true	"Branch ID"
+//	@Param			unread		query		bool				false	"only unread notifications"
+//	@Success		200			{array}		model.Notification	"OK"
+//	@Failure		403			{string}	string				"Forbidden"
+//	@Failure		404			{string}	string				"Not Found"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/notification/branch/{branch_id} [get]
+func (u *notificationController) GetByBranchID(c *fiber.Ctx) error {
+	branchID := c.Params("branch_id")
+	unreadOnly := c.QueryBool("unread", false)
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.notificationUsecase.GetByBranchID(branchID, unreadOnly, userID, userRole)
+	if err != nil {
+		return c.Status(notificationErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Mark notification as read
+//	@Description	Mark a branch notification as read
+//	@Tags			Notification
+//	@Produce		json
+//	@Param			notification_id	path		string				true	"Notification ID"
+//	@Success		200				{object}	model.Notification	"OK"
+//	@Failure		403				{string}	string				"Forbidden"
+//	@Failure		404				{string}	string				"Not Found"
+//	@Failure		500				{string}	string				"Internal Server Error"
+//	@Router			/notification/read/{notification_id} [put]
+func (u *notificationController) MarkRead(c *fiber.Ctx) error {
+	notificationID := c.Params("notification_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.notificationUsecase.MarkRead(notificationID, userID, userRole)
+	if err != nil {
+		return c.Status(notificationErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
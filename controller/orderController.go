@@ -1,9 +1,10 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
+	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
 	vboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
@@ -14,13 +15,16 @@ import (
 
 type OrderController interface {
 	CreateNewOrder(c *fiber.Ctx) error
+	QuoteOrder(c *fiber.Ctx) error
 	GetAll(c *fiber.Ctx) error
 	GetByHeaderID(c *fiber.Ctx) error
+	GetTimeline(c *fiber.Ctx) error
 	GetByBranchID(c *fiber.Ctx) error
 	GetByUserID(c *fiber.Ctx) error
 	UpdateStatus(c *fiber.Ctx) error
 	UpdateReview(c *fiber.Ctx) error
 	SoftDelete(c *fiber.Ctx) error
+	CancelOrder(c *fiber.Ctx) error
 }
 
 type orderController struct {
@@ -55,15 +59,104 @@ func catError(code int, message string) string {
 	).Error()
 }
 
+func capitalizeQuery(c *fiber.Ctx, key string) string {
+	value := c.Query(key)
+	if len(value) > 1 {
+		value = strings.ToUpper(value[:1]) + value[1:]
+	}
+	return value
+}
+
+func parseDateQuery(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ERR: %s must be RFC3339 or YYYY-MM-DD", key)
+}
+
+// parseOrderListFilter reads the paging, filtering and sorting query
+// shared by every order listing
+func parseOrderListFilter(c *fiber.Ctx) (*model.OrderListFilter, error) {
+	filter := model.OrderListFilter{
+		UserID:        c.Query("user_id"),
+		OrderStatus:   model.OrderStatus(capitalizeQuery(c, "order_status")),
+		PaymentStatus: model.PaymentStatus(capitalizeQuery(c, "payment_status")),
+		ServiceType:   model.ServiceType(capitalizeQuery(c, "service_type")),
+		SortBy:        model.OrderSortBy(c.Query("sort_by", string(model.SortByCreatedAt))),
+		SortDesc:      c.Query("order", "desc") != "asc",
+		Cursor:        c.Query("cursor"),
+		Limit:         c.QueryInt("limit", model.DefaultOrderPageLimit),
+	}
+
+	switch filter.OrderStatus {
+	case "", model.Waiting, model.Processing, model.Completed, model.Canceled, model.OrderExpired:
+	default:
+		return nil, errors.New("ERR: order_status option is not valid")
+	}
+
+	switch filter.PaymentStatus {
+	case "", model.Pending, model.Paid, model.Expired, model.Cancel:
+	default:
+		return nil, errors.New("ERR: payment_status option is not valid")
+	}
+
+	switch filter.ServiceType {
+	case "", model.Washing, model.Drying, model.Pickup, model.Delivery, model.Agents:
+	default:
+		return nil, errors.New("ERR: service_type option is not valid")
+	}
+
+	if filter.SortBy != model.SortByCreatedAt && filter.SortBy != model.SortByUpdatedAt {
+		return nil, errors.New("ERR: sort_by option is not valid")
+	}
+
+	if filter.Limit <= 0 || filter.Limit > model.MaxOrderPageLimit {
+		return nil, fmt.Errorf("ERR: limit must be between 1 and %d", model.MaxOrderPageLimit)
+	}
+
+	if onsite := c.Query("onsite"); onsite != "" {
+		isOnsite := c.QueryBool("onsite")
+		filter.ZuckOnsite = &isOnsite
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseDateQuery(c, "from"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = parseDateQuery(c, "to"); err != nil {
+		return nil, err
+	}
+
+	return &filter, nil
+}
+
+func orderListErrorStatus(err error) int {
+	if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	}
+	if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
 //	@Summary		Add new order
-//	@Description	Add a new order to the system
+//	@Description	Add a new order to the system, with pay_with_wallet the order is paid from the customer's wallet right away. promo_code and redeem_points take discounts off the order. program_id on a washing or drying basket picks its wash program, which sets how long it runs and its price
 //	@Tags			Order
 //	@Accept			json
 //	@Produce		json
 //	@Param			NewOrder	body		model.NewOrder	true	"New Order Data"
 //	@Success		201			{object}	model.FullOrder	"Created"
 //	@Failure		400			{string}	string			"Bad Request - Invalid input"
-//	@Failure		418			{string}	string			"ERR: mai wang ja"
+//	@Failure		409			{string}	string			"ERR: mai wang ja"
 //	@Failure		406			{string}	string			"Not Acceptable - Validation failed"
 //	@Failure		500			{string}	string			"Internal Server Error"
 //	@Router			/order/new [post]
@@ -82,11 +175,14 @@ func (u *orderController) CreateNewOrder(c *fiber.Ctx) error {
 
 	response, err := u.orderUsecase.CreateNewOrder(newOrder)
 
+	var busyErr *model.MachineBusyError
 	if err != nil {
-		if err.Error() == "ERR: mai wang ja" {
-			return c.Status(http.StatusTeapot).SendString(err.Error())
+		if errors.As(err, &busyErr) {
+			return c.Status(fiber.StatusConflict).SendString(err.Error())
 		} else if err.Error() == "null detected on one or more essential field(s)" {
 			return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 		} else {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
@@ -95,25 +191,78 @@ func (u *orderController) CreateNewOrder(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
-//	@Summary		Get all orders
-//	@Description	Retrieve all orders in the system
+//	@Summary		Quote new order
+//	@Description	Validate a new order and return its itemized price without creating anything, a promo_code adds a discount line
 //	@Tags			Order
+//	@Accept			json
 //	@Produce		json
-//	@Success		200	{array}		model.FullOrder	"OK"
-//	@Failure		404	{string}	string			"Not Found - No orders available"
-//	@Failure		500	{string}	string			"Internal Server Error"
-//	@Router			/order/all [get]
-func (u *orderController) GetAll(c *fiber.Ctx) error {
-	result, err := u.orderUsecase.GetAll()
+//	@Param			NewOrder	body		model.NewOrder		true	"New Order Data"
+//	@Success		200			{object}	model.OrderQuote	"OK"
+//	@Failure		400			{string}	string				"Bad Request - promo code can't be used"
+//	@Failure		409			{string}	string				"ERR: mai wang ja"
+//	@Failure		406			{string}	string				"Not Acceptable - Validation failed"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/order/quote [post]
+func (u *orderController) QuoteOrder(c *fiber.Ctx) error {
+	newOrder := new(model.NewOrder)
+
+	if err := c.BodyParser(newOrder); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	if err := vboi.Validate(newOrder); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
 
+	newOrder.UserID = getCookieData(c, "userID")
+
+	response, err := u.orderUsecase.QuoteOrder(newOrder)
+
+	var busyErr *model.MachineBusyError
 	if err != nil {
-		if err.Error() == "record not found" {
-			return c.SendStatus(fiber.StatusNoContent)
+		if errors.As(err, &busyErr) {
+			return c.Status(fiber.StatusConflict).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 		} else {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
 	}
 
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Get all orders
+//	@Description	Retrieve one page of every order in the system, newest first by default
+//	@Tags			Order
+//	@Produce		json
+//	@Param			limit			query		int				false	"page size, default 20, max 100"
+//	@Param			cursor			query		string			false	"next_cursor of the previous page"
+//	@Param			order_status	query		string			false	"order status: waiting, processing, completed, canceled, expired"
+//	@Param			payment_status	query		string			false	"payment status: pending, paid, expired, cancel"
+//	@Param			service_type	query		string			false	"orders with a basket of this service"
+//	@Param			from			query		string			false	"created at or after, RFC3339 or YYYY-MM-DD"
+//	@Param			to				query		string			false	"created before, RFC3339 or YYYY-MM-DD"
+//	@Param			onsite			query		bool			false	"onsite or online orders"
+//	@Param			user_id			query		string			false	"orders of this customer"
+//	@Param			sort_by			query		string			false	"created_at or updated_at"
+//	@Param			order			query		string			false	"asc or desc"
+//	@Success		200				{object}	model.OrderPage	"OK"
+//	@Failure		400				{string}	string			"Bad Request"
+//	@Failure		500				{string}	string			"Internal Server Error"
+//	@Router			/order/all [get]
+func (u *orderController) GetAll(c *fiber.Ctx) error {
+	filter, err := parseOrderListFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	result, err := u.orderUsecase.GetAll(*filter)
+
+	if err != nil {
+		return c.Status(orderListErrorStatus(err)).SendString(err.Error())
+	}
+
 	return c.Status(fiber.StatusOK).JSON(result)
 }
 
@@ -156,27 +305,71 @@ func (u *orderController) GetByHeaderID(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(result)
 }
 
+//	@Summary		Get order timeline
+//	@Description	Retrieve every event of an order oldest first, customers can only see their own order
+//	@Tags			Order
+//	@Produce		json
+//	@Param			order_header_id	path		string						true	"Order Header ID"
+//	@Success		200				{array}		model.OrderTimelineEvent	"OK"
+//	@Failure		403				{string}	string						"Forbidden"
+//	@Failure		404				{string}	string						"Not Found"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/order/{order_header_id}/timeline [get]
+func (u *orderController) GetTimeline(c *fiber.Ctx) error {
+	orderHeaderID := c.Params("order_header_id")
+	userID := getCookieData(c, "userID")
+	role := getCookieData(c, "positionID")
+
+	result, err := u.orderUsecase.GetTimeline(orderHeaderID, userID, role)
+
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.SendStatus(fiber.StatusNotFound)
+		} else if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		} else {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
 //	@Summary		Get full order by branch id
-//	@Description	Retrieve full order by branch id
+//	@Description	Retrieve one page of the orders of a branch, takes the same paging and filter query as /order/all. Super admin, the branch owner and its employees only
 //	@Tags			Order
 //	@Produce		json
-//	@Param			branch_id	path		string			true	"branch id"
-//	@Param			status		query		string			false	"status: pending, paid, expired, cancel"
-//	@Success		200			{array}		model.FullOrder	"OK"
-//	@Failure		404			{string}	string			"Not Found - No orders available"
-//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Param			branch_id		path		string			true	"branch id"
+//	@Param			status			query		string			false	"same as payment_status, kept for older clients"
+//	@Param			limit			query		int				false	"page size, default 20, max 100"
+//	@Param			cursor			query		string			false	"next_cursor of the previous page"
+//	@Param			order_status	query		string			false	"order status: waiting, processing, completed, canceled, expired"
+//	@Param			payment_status	query		string			false	"payment status: pending, paid, expired, cancel"
+//	@Param			service_type	query		string			false	"orders with a basket of this service"
+//	@Param			from			query		string			false	"created at or after, RFC3339 or YYYY-MM-DD"
+//	@Param			to				query		string			false	"created before, RFC3339 or YYYY-MM-DD"
+//	@Param			onsite			query		bool			false	"onsite or online orders"
+//	@Param			user_id			query		string			false	"orders of this customer"
+//	@Param			sort_by			query		string			false	"created_at or updated_at"
+//	@Param			order			query		string			false	"asc or desc"
+//	@Success		200				{object}	model.OrderPage	"OK"
+//	@Failure		400				{string}	string			"Bad Request"
+//	@Failure		403				{string}	string			"Forbidden"
+//	@Failure		500				{string}	string			"Internal Server Error"
 //	@Router			/order/branch/{branch_id} [get]
 func (u *orderController) GetByBranchID(c *fiber.Ctx) error {
 	branchID := c.Params("branch_id")
 
-	managerID := getCookieData(c, "userID")
-
-	status := c.Query("status")
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
 
-	if len(status) > 1 {
-		status = strings.ToUpper(status[:1]) + status[1:]
+	filter, err := parseOrderListFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	status := capitalizeQuery(c, "status")
+
 	if status != string(model.Pending) &&
 		status != string(model.Paid) &&
 		status != string(model.Expired) &&
@@ -185,13 +378,17 @@ func (u *orderController) GetByBranchID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("ERR: status option is not valid")
 	}
 
-	result, err := u.orderUsecase.GetByBranchID(branchID, managerID, status)
+	if status != "" {
+		filter.PaymentStatus = model.PaymentStatus(status)
+	}
+
+	result, err := u.orderUsecase.GetByBranchID(branchID, userID, userRole, *filter)
 
 	if err != nil {
 		if err.Error() == "record not found" {
 			return c.SendStatus(fiber.StatusNoContent)
 		} else {
-			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+			return c.Status(orderListErrorStatus(err)).SendString(err.Error())
 		}
 	}
 
@@ -199,23 +396,34 @@ func (u *orderController) GetByBranchID(c *fiber.Ctx) error {
 }
 
 //	@Summary		Get full order by user id
-//	@Description	Retrieve full order by user id
+//	@Description	Retrieve one page of the orders of the signed in user, takes the same paging and filter query as /order/all
 //	@Tags			Order
 //	@Produce		json
-//	@Param			status	query		string			false	"status: waiting, processing, completed, expired"
-//	@Success		200		{array}		model.FullOrder	"OK"
-//	@Failure		404		{string}	string			"Not Found - No orders available"
-//	@Failure		500		{string}	string			"Internal Server Error"
+//	@Param			status			query		string			false	"same as order_status, kept for older clients"
+//	@Param			limit			query		int				false	"page size, default 20, max 100"
+//	@Param			cursor			query		string			false	"next_cursor of the previous page"
+//	@Param			order_status	query		string			false	"order status: waiting, processing, completed, canceled, expired"
+//	@Param			payment_status	query		string			false	"payment status: pending, paid, expired, cancel"
+//	@Param			service_type	query		string			false	"orders with a basket of this service"
+//	@Param			from			query		string			false	"created at or after, RFC3339 or YYYY-MM-DD"
+//	@Param			to				query		string			false	"created before, RFC3339 or YYYY-MM-DD"
+//	@Param			onsite			query		bool			false	"onsite or online orders"
+//	@Param			sort_by			query		string			false	"created_at or updated_at"
+//	@Param			order			query		string			false	"asc or desc"
+//	@Success		200				{object}	model.OrderPage	"OK"
+//	@Failure		400				{string}	string			"Bad Request"
+//	@Failure		500				{string}	string			"Internal Server Error"
 //	@Router			/order/me [get]
 func (u *orderController) GetByUserID(c *fiber.Ctx) error {
 	userID := getCookieData(c, "userID")
 
-	status := c.Query("status")
-
-	if len(status) > 1 {
-		status = strings.ToUpper(status[:1]) + status[1:]
+	filter, err := parseOrderListFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
+	status := capitalizeQuery(c, "status")
+
 	if status != string(model.Waiting) &&
 		status != string(model.Processing) &&
 		status != string(model.Completed) &&
@@ -224,14 +432,14 @@ func (u *orderController) GetByUserID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("ERR: status option is not valid")
 	}
 
-	result, err := u.orderUsecase.GetByUserID(userID, status)
+	if status != "" {
+		filter.OrderStatus = model.OrderStatus(status)
+	}
+
+	result, err := u.orderUsecase.GetByUserID(userID, *filter)
 
 	if err != nil {
-		if err.Error() == "record not found" {
-			return c.SendStatus(fiber.StatusNoContent)
-		} else {
-			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
-		}
+		return c.Status(orderListErrorStatus(err)).SendString(err.Error())
 	}
 
 	return c.Status(fiber.StatusOK).JSON(result)
@@ -244,6 +452,7 @@ func (u *orderController) GetByUserID(c *fiber.Ctx) error {
 //	@Success		200			{object}	model.FullOrder		"OK"
 //	@Failure		400			{string}	string				"Bad Request"
 //	@Failure		404			{string}	string				"Not Found"
+//	@Failure		409			{string}	string				"ERR: mai wang ja"
 //	@Failure		500			{string}	string				"Internal Server Error"
 //	@Router			/order/update [put]
 func (u *orderController) UpdateStatus(c *fiber.Ctx) error {
@@ -265,9 +474,15 @@ func (u *orderController) UpdateStatus(c *fiber.Ctx) error {
 
 	result, err := u.orderUsecase.UpdateStatus(*order)
 
+	var busyErr *model.MachineBusyError
+	var transitionErr *model.InvalidOrderTransitionError
 	if err != nil {
 		if err.Error() == "record not found" {
 			return c.SendStatus(fiber.StatusNoContent)
+		} else if errors.As(err, &busyErr) {
+			return c.Status(fiber.StatusConflict).SendString(err.Error())
+		} else if errors.As(err, &transitionErr) {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 		} else if strings.Contains(err.Error(), "400") {
 			//	return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 			return c.Status(fiber.StatusBadRequest).SendString(catError(400, err.Error()))
@@ -339,3 +554,35 @@ func (u *orderController) SoftDelete(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(result)
 }
+
+//	@Summary		Cancel an order
+//	@Description	Customer cancels their own order while every basket is still waiting, a paid order gets a refund
+//	@Tags			Order
+//	@Produce		json
+//	@Param			order_header_id	path		string			true	"Order Header ID"
+//	@Success		200				{object}	model.FullOrder	"OK"
+//	@Failure		400				{string}	string			"Bad Request"
+//	@Failure		403				{string}	string			"Forbidden"
+//	@Failure		404				{string}	string			"Not Found"
+//	@Failure		500				{string}	string			"Internal Server Error"
+//	@Router			/order/{order_header_id}/cancel [post]
+func (u *orderController) CancelOrder(c *fiber.Ctx) error {
+	orderHeaderID := c.Params("order_header_id")
+	userID := getCookieData(c, "userID")
+
+	result, err := u.orderUsecase.CancelOrder(orderHeaderID, userID)
+
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.SendStatus(fiber.StatusNotFound)
+		} else if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		} else {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
@@ -2,16 +2,25 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/paymentgateway"
+	"zuck-my-clothe/zuck-my-clothe-backend/promptpay"
 
 	"github.com/gofiber/fiber/v2"
 	_ "github.com/golang-jwt/jwt/v5"
 )
 
+// qrImageSize is the width and height of payment QR images in pixels
+const qrImageSize = 512
+
 type PaymentController interface {
 	CreatePayment(c *fiber.Ctx) error
 	FindByPaymentID(c *fiber.Ctx) error
 	UpdatePaymenstatus(c *fiber.Ctx) error
+	CreateCharge(c *fiber.Ctx) error
+	GetQR(c *fiber.Ctx) error
+	HandleWebhook(c *fiber.Ctx) error
 }
 
 type paymentController struct {
@@ -66,12 +75,13 @@ func (u *paymentController) FindByPaymentID(c *fiber.Ctx) error {
 }
 
 //	@Summary		Update payment status
-//	@Description	Set the status of payment
+//	@Description	Set the status of payment by staff, a payment can only become Paid through the payment webhook
 //	@Tags			Payment
 //	@Param			paymentID	path		string			true	"Machine Serial ID"
 //	@Param			status		path		string			true	"Set status (Pending/Paid/Expired/Cancel)"
 //	@Success		200			{object}	model.Payments	"OK"
 //	@Failure		202			{string}	string			"Accepted"
+//	@Failure		403			{string}	string			"Forbidden"
 //	@Failure		406			{string}	string			"err: not valid status"
 //	@Router			/payment/update/{paymentID}/setstatus/{status} [put]
 func (u *paymentController) UpdatePaymenstatus(c *fiber.Ctx) error {
@@ -82,7 +92,123 @@ func (u *paymentController) UpdatePaymenstatus(c *fiber.Ctx) error {
 	}
 	reponse, err := u.paymentUsecase.UpdatePaymentStatus(paymentID, model.PaymentStatus(status))
 	if err != nil {
+		if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		}
 		return c.Status(fiber.StatusAccepted).SendString(err.Error())
 	}
 	return c.Status(fiber.StatusOK).JSON(reponse)
 }
+
+//	@Summary		Create payment charge
+//	@Description	Get what the customer needs to pay a pending payment, e.g. the PromptPay QR payload
+//	@Tags			Payment
+//	@Produce		json
+//	@Param			paymentID	path		string				true	"PaymentID"
+//	@Success		200			{object}	model.PaymentCharge	"OK"
+//	@Failure		400			{string}	string				"Bad Request"
+//	@Failure		403			{string}	string				"Forbidden"
+//	@Failure		404			{string}	string				"Not Found"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/payment/{paymentID}/charge [post]
+func (u *paymentController) CreateCharge(c *fiber.Ctx) error {
+	paymentID := c.Params("paymentID")
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	charge, err := u.paymentUsecase.CreateCharge(paymentID, userID, userRole)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(charge)
+}
+
+//	@Summary		Get payment QR
+//	@Description	Get the PromptPay QR of a pending payment that is not due yet, as a PNG image or as the raw EMVCo payload with format=text. For the customer of the order and staff of its branch only
+//	@Tags			Payment
+//	@Produce		png
+//	@Produce		plain
+//	@Param			paymentID	path		string	true	"PaymentID"
+//	@Param			format		query		string	false	"png (default) or text"
+//	@Success		200			{file}		file	"OK"
+//	@Failure		400			{string}	string	"Bad Request"
+//	@Failure		403			{string}	string	"Forbidden"
+//	@Failure		404			{string}	string	"Not Found"
+//	@Failure		500			{string}	string	"Internal Server Error"
+//	@Router			/payment/{paymentID}/qr [get]
+func (u *paymentController) GetQR(c *fiber.Ctx) error {
+	paymentID := c.Params("paymentID")
+	format := c.Query("format", "png")
+	if format != "png" && format != "text" {
+		return c.Status(fiber.StatusBadRequest).SendString("ERR 400: format must be png or text")
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	charge, err := u.paymentUsecase.CreateCharge(paymentID, userID, userRole)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "403") {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	// the QR is only valid until the payment is due, never let it be cached
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	if format == "text" {
+		return c.Status(fiber.StatusOK).SendString(charge.QRPayload)
+	}
+
+	image, err := promptpay.PNG(charge.QRPayload, qrImageSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Status(fiber.StatusOK).Send(image)
+}
+
+//	@Summary		Payment provider webhook
+//	@Description	Called by the payment provider when a payment is paid, the body must be signed with HMAC-SHA256 in X-Zuck-Signature
+//	@Tags			Payment
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Zuck-Signature	header		string						true	"hex HMAC-SHA256 of the body"
+//	@Param			Event				body		model.PaymentWebhookEvent	true	"Paid event"
+//	@Success		200					{object}	model.Payments				"OK"
+//	@Failure		400					{string}	string						"Bad Request"
+//	@Failure		401					{string}	string						"Invalid signature"
+//	@Failure		404					{string}	string						"Not Found"
+//	@Failure		500					{string}	string						"Internal Server Error"
+//	@Router			/webhook/payment [post]
+func (u *paymentController) HandleWebhook(c *fiber.Ctx) error {
+	signature := c.Get(paymentgateway.SignatureHeader)
+
+	payment, err := u.paymentUsecase.HandleWebhook(c.Body(), signature)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "401") {
+			return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
+		} else if strings.Contains(err.Error(), "400") {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(payment)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentPolicyController interface {
+	SetPolicy(c *fiber.Ctx) error
+	FindByBranchID(c *fiber.Ctx) error
+	ExtendPayment(c *fiber.Ctx) error
+}
+
+type paymentPolicyController struct {
+	paymentPolicyUsecase model.PaymentPolicyUsecase
+}
+
+func CreateNewPaymentPolicyController(paymentPolicyUsecase model.PaymentPolicyUsecase) PaymentPolicyController {
+	return &paymentPolicyController{paymentPolicyUsecase: paymentPolicyUsecase}
+}
+
+func paymentPolicyErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Set payment policy
+//	@Description	Set how long payments of a branch and order type can be paid, leave branch_id empty for the default policy of every branch
+//	@Tags			Payment
+//	@Accept			json
+//	@Produce		json
+//	@Param			PaymentPolicy	body		model.SetPaymentPolicyDTO	true	"Policy Data"
+//	@Success		200				{object}	model.PaymentPolicy			"OK"
+//	@Failure		403				{string}	string						"Forbidden"
+//	@Failure		404				{string}	string						"Branch Not Found"
+//	@Failure		406				{string}	string						"Not Acceptable"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/payment/policy [put]
+func (u *paymentPolicyController) SetPolicy(c *fiber.Ctx) error {
+	newPolicy := new(model.SetPaymentPolicyDTO)
+	if err := c.BodyParser(newPolicy); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(newPolicy); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.paymentPolicyUsecase.SetPolicy(newPolicy, userID, userRole)
+	if err != nil {
+		return c.Status(paymentPolicyErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Get payment policies by branch id
+//	@Description	Get the policies of the branch including the default policies, orders without any policy are due in 10 minutes
+//	@Tags			Payment
+//	@Produce		json
+//	@Param			branch_id	path		string					true	"Branch ID"
+//	@Success		200			{array}		model.PaymentPolicy		"OK"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/payment/policy/branch/{branch_id} [get]
+func (u *paymentPolicyController) FindByBranchID(c *fiber.Ctx) error {
+	branchID := c.Params("branch_id")
+
+	response, err := u.paymentPolicyUsecase.FindByBranchID(branchID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Extend payment
+//	@Description	Push the due date of a pending payment by the extension of its policy. Staff of the branch only
+//	@Tags			Payment
+//	@Produce		json
+//	@Param			paymentID	path		string			true	"PaymentID"
+//	@Success		200			{object}	model.Payments	"OK"
+//	@Failure		400			{string}	string			"Bad Request"
+//	@Failure		403			{string}	string			"Forbidden"
+//	@Failure		404			{string}	string			"Not Found"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Router			/payment/{paymentID}/extend [post]
+func (u *paymentPolicyController) ExtendPayment(c *fiber.Ctx) error {
+	paymentID := c.Params("paymentID")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.paymentPolicyUsecase.ExtendPayment(paymentID, userID, userRole)
+	if err != nil {
+		return c.Status(paymentPolicyErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
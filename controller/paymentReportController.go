@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentReportController interface {
+	GetDailyReport(c *fiber.Ctx) error
+}
+
+type paymentReportController struct {
+	paymentReportUsecase model.PaymentReportUsecase
+}
+
+func CreateNewPaymentReportController(paymentReportUsecase model.PaymentReportUsecase) PaymentReportController {
+	return &paymentReportController{paymentReportUsecase: paymentReportUsecase}
+}
+
+func paymentReportErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+func formatBaht(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// paymentReportCSV flattens the report into one row per summary, payment and refund,
+// the first column tells which kind of row it is
+func paymentReportCSV(report *model.PaymentReport) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	writer := csv.NewWriter(buffer)
+
+	rows := [][]string{
+		{"record", "branch_id", "date", "payment_id", "order_header_id", "refund_id", "status", "method", "count", "amount", "received_amount", "created_at", "note"},
+	}
+
+	for _, s := range report.ByStatus {
+		rows = append(rows, []string{"status_total", report.BranchID, report.Date, "", "", "", string(s.PaymentStatus), "", strconv.Itoa(s.Count), formatBaht(s.Total), "", "", ""})
+	}
+	for _, s := range report.ByMethod {
+		rows = append(rows, []string{"method_total", report.BranchID, report.Date, "", "", "", string(model.Paid), s.Method, strconv.Itoa(s.Count), formatBaht(s.Total), "", "", ""})
+	}
+	for _, s := range report.Refunds {
+		rows = append(rows, []string{"refund_total", report.BranchID, report.Date, "", "", "", string(s.RefundStatus), "", strconv.Itoa(s.Count), formatBaht(s.Total), "", "", ""})
+	}
+	rows = append(rows, []string{"net_total", report.BranchID, report.Date, "", "", "", "", "", "", formatBaht(report.NetTotal), "", "", ""})
+
+	unmatched := map[string]bool{}
+	for _, line := range report.Unmatched {
+		unmatched[line.PaymentID] = true
+	}
+
+	for _, line := range report.Payments {
+		received := ""
+		if line.ReceivedAmount != nil {
+			received = formatBaht(*line.ReceivedAmount)
+		}
+		note := ""
+		if unmatched[line.PaymentID] {
+			note = "unmatched"
+		}
+		rows = append(rows, []string{"payment", report.BranchID, report.Date, line.PaymentID, line.OrderHeaderID, "", string(line.PaymentStatus), line.Method, "", formatBaht(line.Amount), received, line.CreatedAt.Format(time.RFC3339), note})
+	}
+
+	for _, refund := range report.RefundLines {
+		rows = append(rows, []string{"refund", report.BranchID, report.Date, refund.PaymentID, refund.OrderHeaderID, refund.RefundID, string(refund.RefundStatus), "", "", formatBaht(refund.Amount), "", refund.CreatedAt.Format(time.RFC3339), refund.Reason})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+//	@Summary		Get daily payment report
+//	@Description	Reconcile a branch for one day (Thailand time): payments by status and method, unmatched and expired payments, and refunds. Manager of the branch only
+//	@Tags			Payment
+//	@Produce		json
+//	@Produce		text/csv
+//	@Param			branch_id	query		string				true	"Branch ID"
+//	@Param			date		query		string				false	"YYYY-MM-DD, today when empty"
+//	@Param			format		query		string				false	"json (default) or csv"
+//	@Success		200			{object}	model.PaymentReport	"OK"
+//	@Failure		403			{string}	string				"Forbidden"
+//	@Failure		404			{string}	string				"Branch Not Found"
+//	@Failure		406			{string}	string				"Not Acceptable"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/payment/report [get]
+func (u *paymentReportController) GetDailyReport(c *fiber.Ctx) error {
+	branchID := c.Query("branch_id")
+	if branchID == "" {
+		return c.Status(fiber.StatusNotAcceptable).SendString("ERR: branch_id is required")
+	}
+
+	date := time.Now().In(model.ReportLocation)
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, value, model.ReportLocation)
+		if err != nil {
+			return c.Status(fiber.StatusNotAcceptable).SendString("ERR: date must be YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusNotAcceptable).SendString("ERR: format must be json or csv")
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	report, err := u.paymentReportUsecase.GetDailyReport(branchID, date, userID, userRole)
+	if err != nil {
+		return c.Status(paymentReportErrorStatus(err)).SendString(err.Error())
+	}
+
+	if format == "json" {
+		return c.Status(fiber.StatusOK).JSON(report)
+	}
+
+	body, err := paymentReportCSV(report)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="payment-report-%s-%s.csv"`, report.BranchID, report.Date))
+	return c.Status(fiber.StatusOK).Send(body)
+}
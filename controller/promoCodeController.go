@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PromoCodeController interface {
+	CreatePromoCode(c *fiber.Ctx) error
+	GetAll(c *fiber.Ctx) error
+	GetReport(c *fiber.Ctx) error
+}
+
+type promoCodeController struct {
+	promoCodeUsecase model.PromoCodeUsecase
+}
+
+func CreateNewPromoCodeController(promoCodeUsecase model.PromoCodeUsecase) PromoCodeController {
+	return &promoCodeController{promoCodeUsecase: promoCodeUsecase}
+}
+
+func promoCodeErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Add new promo code
+//	@Description	Add a percentage or fixed discount code, leave branch_id empty for a code that works in every branch
+//	@Tags			Promo
+//	@Accept			json
+//	@Produce		json
+//	@Param			PromoCode	body		model.NewPromoCode	true	"New Promo Code Data"
+//	@Success		201			{object}	model.PromoCode		"Created"
+//	@Failure		400			{string}	string				"Bad Request"
+//	@Failure		404			{string}	string				"Branch Not Found"
+//	@Failure		406			{string}	string				"Not Acceptable"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/promo/add [post]
+func (u *promoCodeController) CreatePromoCode(c *fiber.Ctx) error {
+	newPromoCode := new(model.NewPromoCode)
+	if err := c.BodyParser(newPromoCode); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(newPromoCode); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+
+	response, err := u.promoCodeUsecase.CreatePromoCode(newPromoCode, userID)
+	if err != nil {
+		return c.Status(promoCodeErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get all promo codes
+//	@Description	Retrieve every promo code newest first
+//	@Tags			Promo
+//	@Produce		json
+//	@Success		200	{array}		model.PromoCode	"OK"
+//	@Failure		500	{string}	string			"Internal Server Error"
+//	@Router			/promo/all [get]
+func (u *promoCodeController) GetAll(c *fiber.Ctx) error {
+	response, err := u.promoCodeUsecase.GetAll()
+	if err != nil {
+		return c.Status(promoCodeErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Get promo code report
+//	@Description	Retrieve every redemption of a promo code with the number of uses, customers and the total discount given
+//	@Tags			Promo
+//	@Produce		json
+//	@Param			promo_code_id	path		string					true	"Promo Code ID"
+//	@Success		200				{object}	model.PromoCodeReport	"OK"
+//	@Failure		404				{string}	string					"Not Found"
+//	@Failure		500				{string}	string					"Internal Server Error"
+//	@Router			/promo/{promo_code_id}/report [get]
+func (u *promoCodeController) GetReport(c *fiber.Ctx) error {
+	promoCodeID := c.Params("promo_code_id")
+
+	response, err := u.promoCodeUsecase.GetReport(promoCodeID)
+	if err != nil {
+		return c.Status(promoCodeErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/receipt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReceiptController interface {
+	GetReceipt(c *fiber.Ctx) error
+}
+
+type receiptController struct {
+	receiptUsecase model.ReceiptUsecase
+	fontPath       string
+}
+
+func CreateNewReceiptController(receiptUsecase model.ReceiptUsecase, fontPath string) ReceiptController {
+	return &receiptController{
+		receiptUsecase: receiptUsecase,
+		fontPath:       fontPath,
+	}
+}
+
+func receiptErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Get order receipt
+//	@Description	Get the receipt / tax invoice of a paid order as PDF or HTML, it's numbered per branch the first time it's asked for. Customers can only get their own receipts
+//	@Tags			Order
+//	@Produce		application/pdf
+//	@Produce		html
+//	@Param			order_header_id	path		string	true	"Order Header ID"
+//	@Param			format			query		string	false	"pdf (default) or html"
+//	@Success		200				{file}		file	"OK"
+//	@Failure		400				{string}	string	"Order Not Paid"
+//	@Failure		403				{string}	string	"Forbidden"
+//	@Failure		404				{string}	string	"Not Found"
+//	@Failure		406				{string}	string	"Not Acceptable"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/order/{order_header_id}/receipt [get]
+func (u *receiptController) GetReceipt(c *fiber.Ctx) error {
+	orderHeaderID := c.Params("order_header_id")
+
+	format := c.Query("format", "pdf")
+	if format != "pdf" && format != "html" {
+		return c.Status(fiber.StatusNotAcceptable).SendString("ERR: format must be pdf or html")
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	document, err := u.receiptUsecase.GetReceipt(orderHeaderID, userID, userRole)
+	if err != nil {
+		return c.Status(receiptErrorStatus(err)).SendString(err.Error())
+	}
+
+	if format == "html" {
+		body, err := receipt.HTML(document)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Status(fiber.StatusOK).Send(body)
+	}
+
+	body, err := receipt.PDF(document, u.fontPath)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s.pdf"`, document.ReceiptNumber))
+	return c.Status(fiber.StatusOK).Send(body)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RefundController interface {
+	CreateRefund(c *fiber.Ctx) error
+	GetByPaymentID(c *fiber.Ctx) error
+	CompleteRefund(c *fiber.Ctx) error
+	RejectRefund(c *fiber.Ctx) error
+}
+
+type refundController struct {
+	refundUsecase model.RefundUsecase
+}
+
+func CreateNewRefundController(refundUsecase model.RefundUsecase) RefundController {
+	return &refundController{refundUsecase: refundUsecase}
+}
+
+func refundErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Refund a payment
+//	@Description	Request a refund of part or all of a paid payment, all refunds of a payment can't be more than its amount. Manager of the branch only
+//	@Tags			Refund
+//	@Accept			json
+//	@Produce		json
+//	@Param			paymentID	path		string			true	"PaymentID"
+//	@Param			Refund		body		model.NewRefund	true	"Refund amount and reason"
+//	@Success		201			{object}	model.Refund	"Created"
+//	@Failure		400			{string}	string			"Bad Request"
+//	@Failure		403			{string}	string			"Forbidden"
+//	@Failure		404			{string}	string			"Not Found"
+//	@Failure		406			{string}	string			"Not Acceptable"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Router			/refund/payment/{paymentID} [post]
+func (u *refundController) CreateRefund(c *fiber.Ctx) error {
+	paymentID := c.Params("paymentID")
+
+	newRefund := new(model.NewRefund)
+	if err := c.BodyParser(newRefund); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(newRefund); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.refundUsecase.CreateRefund(paymentID, *newRefund, userID, userRole)
+	if err != nil {
+		return c.Status(refundErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get payment refunds
+//	@Description	Retrieve every refund of a payment oldest first, manager of the branch only
+//	@Tags			Refund
+//	@Produce		json
+//	@Param			paymentID	path		string			true	"PaymentID"
+//	@Success		200			{array}		model.Refund	"OK"
+//	@Failure		403			{string}	string			"Forbidden"
+//	@Failure		404			{string}	string			"Not Found"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Router			/refund/payment/{paymentID} [get]
+func (u *refundController) GetByPaymentID(c *fiber.Ctx) error {
+	paymentID := c.Params("paymentID")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.refundUsecase.GetByPaymentID(paymentID, userID, userRole)
+	if err != nil {
+		return c.Status(refundErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Complete refund
+//	@Description	Mark a requested refund as completed once the money is sent back to the customer
+//	@Tags			Refund
+//	@Produce		json
+//	@Param			refund_id	path		string			true	"Refund ID"
+//	@Success		200			{object}	model.Refund	"OK"
+//	@Failure		400			{string}	string			"Bad Request"
+//	@Failure		403			{string}	string			"Forbidden"
+//	@Failure		404			{string}	string			"Not Found"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Router			/refund/{refund_id}/complete [put]
+func (u *refundController) CompleteRefund(c *fiber.Ctx) error {
+	refundID := c.Params("refund_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.refundUsecase.CompleteRefund(refundID, userID, userRole)
+	if err != nil {
+		return c.Status(refundErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Reject refund
+//	@Description	Turn down a requested refund, its amount can be refunded again
+//	@Tags			Refund
+//	@Produce		json
+//	@Param			refund_id	path		string			true	"Refund ID"
+//	@Success		200			{object}	model.Refund	"OK"
+//	@Failure		400			{string}	string			"Bad Request"
+//	@Failure		403			{string}	string			"Forbidden"
+//	@Failure		404			{string}	string			"Not Found"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Router			/refund/{refund_id}/reject [put]
+func (u *refundController) RejectRefund(c *fiber.Ctx) error {
+	refundID := c.Params("refund_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.refundUsecase.RejectRefund(refundID, userID, userRole)
+	if err != nil {
+		return c.Status(refundErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ServicePriceController interface {
+	CreateServicePrice(c *fiber.Ctx) error
+	FindByBranchID(c *fiber.Ctx) error
+	UpdateServicePrice(c *fiber.Ctx) error
+	DeleteServicePrice(c *fiber.Ctx) error
+}
+
+type servicePriceController struct {
+	servicePriceUsecase model.ServicePriceUsecase
+}
+
+func CreateNewServicePriceController(servicePriceUsecase model.ServicePriceUsecase) ServicePriceController {
+	return &servicePriceController{servicePriceUsecase: servicePriceUsecase}
+}
+
+func servicePriceErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "403") {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Add new service price
+//	@Description	Add a price to the catalog, leave branch_id empty for the default price of every branch
+//	@Tags			Price
+//	@Accept			json
+//	@Produce		json
+//	@Param			ServicePrice	body		model.AddServicePriceDTO	true	"New Price Data"
+//	@Success		201				{object}	model.ServicePrice			"Created"
+//	@Failure		403				{string}	string						"Forbidden"
+//	@Failure		406				{string}	string						"Not Acceptable"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/price/add [post]
+func (u *servicePriceController) CreateServicePrice(c *fiber.Ctx) error {
+	newPrice := new(model.AddServicePriceDTO)
+	if err := c.BodyParser(newPrice); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(newPrice); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.servicePriceUsecase.CreateServicePrice(newPrice, userID, userRole)
+	if err != nil {
+		return c.Status(servicePriceErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get service prices by branch id
+//	@Description	Get every price of the branch including the default prices
+//	@Tags			Price
+//	@Produce		json
+//	@Param			branch_id	path		string				true	"Branch ID"
+//	@Success		200			{array}		model.ServicePrice	"OK"
+//	@Failure		500			{string}	string				"Internal Server Error"
+//	@Router			/price/branch/{branch_id} [get]
+func (u *servicePriceController) FindByBranchID(c *fiber.Ctx) error {
+	branchID := c.Params("branch_id")
+
+	response, err := u.servicePriceUsecase.FindByBranchID(branchID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Update service price
+//	@Description	Change the price of a catalog entry from the effective date, the old price is kept for orders placed before
+//	@Tags			Price
+//	@Accept			json
+//	@Produce		json
+//	@Param			ServicePrice	body		model.UpdateServicePriceDTO	true	"Updated Price Data"
+//	@Success		200				{object}	model.ServicePrice			"OK"
+//	@Failure		403				{string}	string						"Forbidden"
+//	@Failure		404				{string}	string						"Not Found"
+//	@Failure		406				{string}	string						"Not Acceptable"
+//	@Failure		500				{string}	string						"Internal Server Error"
+//	@Router			/price/update [put]
+func (u *servicePriceController) UpdateServicePrice(c *fiber.Ctx) error {
+	price := new(model.UpdateServicePriceDTO)
+	if err := c.BodyParser(price); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(price); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	response, err := u.servicePriceUsecase.UpdateServicePrice(price, userID, userRole)
+	if err != nil {
+		return c.Status(servicePriceErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Delete service price
+//	@Description	Soft delete a catalog entry
+//	@Tags			Price
+//	@Param			price_id	path		string	true	"Price ID"
+//	@Success		200			{string}	string	"OK"
+//	@Failure		403			{string}	string	"Forbidden"
+//	@Failure		404			{string}	string	"Not Found"
+//	@Failure		500			{string}	string	"Internal Server Error"
+//	@Router			/price/delete/{price_id} [delete]
+func (u *servicePriceController) DeleteServicePrice(c *fiber.Ctx) error {
+	priceID := c.Params("price_id")
+
+	userID := getCookieData(c, "userID")
+	userRole := getCookieData(c, "positionID")
+
+	if err := u.servicePriceUsecase.DeleteServicePrice(priceID, userID, userRole); err != nil {
+		return c.Status(servicePriceErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"strings"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WalletController interface {
+	TopUp(c *fiber.Ctx) error
+	GetMyStatement(c *fiber.Ctx) error
+	GetStatement(c *fiber.Ctx) error
+}
+
+type walletController struct {
+	walletUsecase model.WalletUsecase
+}
+
+func CreateNewWalletController(walletUsecase model.WalletUsecase) WalletController {
+	return &walletController{walletUsecase: walletUsecase}
+}
+
+func walletErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	} else if strings.Contains(err.Error(), "400") {
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusInternalServerError
+}
+
+func (u *walletController) statement(c *fiber.Ctx, userID string) error {
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	response, err := u.walletUsecase.GetStatement(userID, from, to)
+	if err != nil {
+		return c.Status(walletErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Top up wallet
+//	@Description	Add cash a customer handed to a branch manager to the customer's wallet
+//	@Tags			Wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			TopUp	body		model.WalletTopUpRequest	true	"Customer and amount"
+//	@Success		201		{object}	model.WalletTransaction		"Created"
+//	@Failure		400		{string}	string						"Bad Request"
+//	@Failure		404		{string}	string						"Not Found"
+//	@Failure		406		{string}	string						"Not Acceptable"
+//	@Failure		500		{string}	string						"Internal Server Error"
+//	@Router			/wallet/topup [post]
+func (u *walletController) TopUp(c *fiber.Ctx) error {
+	topUp := new(model.WalletTopUpRequest)
+	if err := c.BodyParser(topUp); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(topUp); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	staffID := getCookieData(c, "userID")
+
+	response, err := u.walletUsecase.TopUp(*topUp, staffID)
+	if err != nil {
+		return c.Status(walletErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get my wallet statement
+//	@Description	Retrieve the balance and the ledger of the user's own wallet, to is exclusive
+//	@Tags			Wallet
+//	@Produce		json
+//	@Param			from	query		string					false	"RFC3339 or YYYY-MM-DD"
+//	@Param			to		query		string					false	"RFC3339 or YYYY-MM-DD"
+//	@Success		200		{object}	model.WalletStatement	"OK"
+//	@Failure		400		{string}	string					"Bad Request"
+//	@Failure		500		{string}	string					"Internal Server Error"
+//	@Router			/wallet/me [get]
+func (u *walletController) GetMyStatement(c *fiber.Ctx) error {
+	return u.statement(c, getCookieData(c, "userID"))
+}
+
+//	@Summary		Get wallet statement
+//	@Description	Retrieve the balance and the ledger of a customer's wallet, to is exclusive
+//	@Tags			Wallet
+//	@Produce		json
+//	@Param			user_id	path		string					true	"User ID"
+//	@Param			from	query		string					false	"RFC3339 or YYYY-MM-DD"
+//	@Param			to		query		string					false	"RFC3339 or YYYY-MM-DD"
+//	@Success		200		{object}	model.WalletStatement	"OK"
+//	@Failure		400		{string}	string					"Bad Request"
+//	@Failure		500		{string}	string					"Internal Server Error"
+//	@Router			/wallet/statement/{user_id} [get]
+func (u *walletController) GetStatement(c *fiber.Ctx) error {
+	return u.statement(c, c.Params("user_id"))
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WashProgramController interface {
+	CreateProgram(c *fiber.Ctx) error
+	FindByMachineType(c *fiber.Ctx) error
+	UpdateProgram(c *fiber.Ctx) error
+	DeleteProgram(c *fiber.Ctx) error
+}
+
+type washProgramController struct {
+	washProgramUsecase model.WashProgramUsecase
+}
+
+func CreateNewWashProgramController(washProgramUsecase model.WashProgramUsecase) WashProgramController {
+	return &washProgramController{washProgramUsecase: washProgramUsecase}
+}
+
+func washProgramErrorStatus(err error) int {
+	if err.Error() == "record not found" {
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+//	@Summary		Add new wash program
+//	@Description	Add a program every machine of the type can run, with its duration and price modifier
+//	@Tags			Program
+//	@Accept			json
+//	@Produce		json
+//	@Param			WashProgram	body		model.AddWashProgramDTO	true	"New Program Data"
+//	@Success		201			{object}	model.WashProgram		"Created"
+//	@Failure		406			{string}	string					"Not Acceptable"
+//	@Failure		500			{string}	string					"Internal Server Error"
+//	@Router			/program/add [post]
+func (u *washProgramController) CreateProgram(c *fiber.Ctx) error {
+	newProgram := new(model.AddWashProgramDTO)
+	if err := c.BodyParser(newProgram); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(newProgram); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+
+	response, err := u.washProgramUsecase.CreateProgram(newProgram, userID)
+	if err != nil {
+		return c.Status(washProgramErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+//	@Summary		Get wash programs by machine type
+//	@Description	Get the programs a customer can pick for a Washer or Dryer
+//	@Tags			Program
+//	@Produce		json
+//	@Param			machine_type	path		string				true	"Machine Type"
+//	@Success		200				{array}		model.WashProgram	"OK"
+//	@Failure		500				{string}	string				"Internal Server Error"
+//	@Router			/program/machine_type/{machine_type} [get]
+func (u *washProgramController) FindByMachineType(c *fiber.Ctx) error {
+	machineType := model.MachineType(c.Params("machine_type"))
+
+	response, err := u.washProgramUsecase.FindByMachineType(machineType)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Update wash program
+//	@Description	Update name, duration, temperature and price modifier of a program
+//	@Tags			Program
+//	@Accept			json
+//	@Produce		json
+//	@Param			WashProgram	body		model.UpdateWashProgramDTO	true	"Updated Program Data"
+//	@Success		200			{object}	model.WashProgram			"OK"
+//	@Failure		404			{string}	string						"Not Found"
+//	@Failure		406			{string}	string						"Not Acceptable"
+//	@Failure		500			{string}	string						"Internal Server Error"
+//	@Router			/program/update [put]
+func (u *washProgramController) UpdateProgram(c *fiber.Ctx) error {
+	program := new(model.UpdateWashProgramDTO)
+	if err := c.BodyParser(program); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+	if err := validatorboi.Validate(program); err != nil {
+		return c.Status(fiber.StatusNotAcceptable).SendString(err.Error())
+	}
+
+	userID := getCookieData(c, "userID")
+
+	response, err := u.washProgramUsecase.UpdateProgram(program, userID)
+	if err != nil {
+		return c.Status(washProgramErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+//	@Summary		Delete wash program
+//	@Description	Soft delete a program, it can't be picked anymore
+//	@Tags			Program
+//	@Param			program_id	path		string	true	"Program ID"
+//	@Success		200			{string}	string	"OK"
+//	@Failure		404			{string}	string	"Not Found"
+//	@Failure		500			{string}	string	"Internal Server Error"
+//	@Router			/program/delete/{program_id} [delete]
+func (u *washProgramController) DeleteProgram(c *fiber.Ctx) error {
+	programID := c.Params("program_id")
+
+	userID := getCookieData(c, "userID")
+
+	if err := u.washProgramUsecase.DeleteProgram(programID, userID); err != nil {
+		return c.Status(washProgramErrorStatus(err)).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
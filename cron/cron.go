@@ -14,22 +14,43 @@ type KonNaCron struct {
 	CronUsecase usecases.KonCronUsecase
 }
 
+// SummonKonCron runs the sweeps that don't belong to a single payment or basket,
+// those are expired and completed on time by the job worker, see SummonJobWorker
 func SummonKonCron(db *platform.Postgres) KonNaCron {
 	c := cron.New()
-	paymentRepo := repository.CreateNewPaymentRepository(db)
+	jobRepo := repository.CreateNewJobRepository(db)
 	orderDetailRepo := repository.CreateOrderDetailRepository(db)
-	usecase := usecases.CreateNewKonCronUsecase(paymentRepo, orderDetailRepo)
+	machineRepo := repository.CreateMachineRepository(db)
+	machineAssignment := usecases.CreateNewMachineAssignmentUsecase(orderDetailRepo, machineRepo)
+	loyaltyRepo := repository.CreateNewLoyaltyRepository(db)
+	unitOfWork := repository.CreateNewUnitOfWork(db)
+	loyaltyUsecase := usecases.CreateNewLoyaltyUsecase(loyaltyRepo, unitOfWork)
+	maintenanceUsecase := usecases.CreateNewMaintenanceUsecase(
+		repository.CreateNewMaintenanceRepository(db),
+		repository.CreateNewMachineReportRepository(db),
+		machineRepo,
+		repository.CreateNewBranchRepository(db),
+		repository.CreateNotificationRepository(db),
+		unitOfWork)
+	usecase := usecases.CreateNewKonCronUsecase(jobRepo, machineAssignment, loyaltyUsecase, maintenanceUsecase)
 	scheduler := KonNaCron{Cron: c, CronUsecase: usecase}
 
 	c.AddFunc("@every 1m", func() {
-		if err := scheduler.CronUsecase.CleanupExpiredPayment(); err != nil {
-			log.Default()
+		if err := scheduler.CronUsecase.ScheduleMissingJobs(); err != nil {
+			log.Println("ERR: cron cannot schedule missing jobs", err)
 		}
-		if err := scheduler.CronUsecase.CleanUpExpiredOrder(); err != nil {
-			log.Default()
+		// the job hooks do these as soon as a basket completes, this catches anything they missed
+		if err := scheduler.CronUsecase.AssignWaitingBasket(); err != nil {
+			log.Println("ERR: cron cannot assign machine", err)
 		}
-		if err := scheduler.CronUsecase.CompleteZuckProcess(); err != nil {
-			log.Default()
+		if err := scheduler.CronUsecase.CreditLoyaltyPoints(); err != nil {
+			log.Println("ERR: cron cannot credit loyalty points", err)
+		}
+		if err := scheduler.CronUsecase.ExpireLoyaltyPoints(); err != nil {
+			log.Println("ERR: cron cannot expire loyalty points", err)
+		}
+		if err := scheduler.CronUsecase.OpenDueMaintenance(); err != nil {
+			log.Println("ERR: cron cannot open preventive maintenance", err)
 		}
 	})
 
@@ -0,0 +1,81 @@
+package nacronsritammarat
+
+import (
+	"log"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+const (
+	jobPollInterval = time.Second
+	jobBatchSize    = 50
+)
+
+type JobWorker struct {
+	JobUsecase model.JobUsecase
+}
+
+// SummonJobWorker runs payment expiry, basket completion, machine commands and the
+// hooks subscribed to them. Several workers can run side by side, each job is
+// handed to one of them.
+func SummonJobWorker(db *platform.Postgres) JobWorker {
+	jobRepo := repository.CreateNewJobRepository(db)
+	paymentRepo := repository.CreateNewPaymentRepository(db)
+	paymentPolicyRepo := repository.CreateNewPaymentPolicyRepository(db)
+	orderDetailRepo := repository.CreateOrderDetailRepository(db)
+	machineRepo := repository.CreateMachineRepository(db)
+	machineAssignment := usecases.CreateNewMachineAssignmentUsecase(orderDetailRepo, machineRepo)
+	loyaltyRepo := repository.CreateNewLoyaltyRepository(db)
+	unitOfWork := repository.CreateNewUnitOfWork(db)
+	loyaltyUsecase := usecases.CreateNewLoyaltyUsecase(loyaltyRepo, unitOfWork)
+	usecase := usecases.CreateNewJobUsecase(jobRepo, paymentRepo, paymentPolicyRepo, orderDetailRepo, unitOfWork)
+
+	commandRepo := repository.CreateNewMachineCommandRepository(db)
+	orderHeaderRepo := repository.CreateOrderHeaderRepository(db)
+	branchRepo := repository.CreateNewBranchRepository(db)
+	contractRepo := repository.CreateNewEmployeeContractRepository(db)
+	washProgramRepo := repository.CreateNewWashProgramRepository(db)
+	commandUsecase := usecases.CreateNewMachineCommandUsecase(commandRepo, machineRepo, orderHeaderRepo, orderDetailRepo, paymentRepo, branchRepo, contractRepo, jobRepo, washProgramRepo, unitOfWork)
+
+	usecase.Handle(model.StartMachinesJob, commandUsecase.StartPaidBaskets)
+	usecase.Handle(model.CheckCommandJob, commandUsecase.CheckCommand)
+
+	// a completed basket frees its machine for the next paid basket in line
+	usecase.Subscribe(model.BasketCompletedEvent, "assign-machine", func(orderBasketID string) error {
+		return machineAssignment.AssignWaitingBasket()
+	})
+	// and may complete its order, which earns points
+	usecase.Subscribe(model.BasketCompletedEvent, "loyalty-points", func(orderBasketID string) error {
+		return loyaltyUsecase.CreditCompletedOrders()
+	})
+	// an expired order gives back the machines it had reserved
+	usecase.Subscribe(model.PaymentExpiredEvent, "assign-machine", func(paymentID string) error {
+		return machineAssignment.AssignWaitingBasket()
+	})
+
+	return JobWorker{JobUsecase: usecase}
+}
+
+// StartJobWorker polls for due jobs in the background, so jobs run at most
+// a poll interval after their time
+func (w *JobWorker) StartJobWorker() {
+	go func() {
+		ticker := time.NewTicker(jobPollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for {
+				ran, err := w.JobUsecase.RunDueJobs(jobBatchSize)
+				if err != nil {
+					log.Println("ERR: job worker cannot claim jobs", err)
+				}
+				if ran < jobBatchSize {
+					break
+				}
+			}
+		}
+	}()
+}
@@ -8,6 +8,7 @@ import (
 	nacronsritammarat "zuck-my-clothe/zuck-my-clothe-backend/cron"
 	"zuck-my-clothe/zuck-my-clothe-backend/docs"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 	"zuck-my-clothe/zuck-my-clothe-backend/routes"
 	validatorboi "zuck-my-clothe/zuck-my-clothe-backend/validator"
 
@@ -37,6 +38,10 @@ func main() {
 		log.Fatal("Can not Init Database", dbErr)
 	}
 
+	if err := repository.Migrate(db); err != nil {
+		log.Fatal("Can not migrate Database", err)
+	}
+
 	valErr := validatorboi.CreateValidator()
 
 	if valErr != "success" {
@@ -46,6 +51,9 @@ func main() {
 	konCron := nacronsritammarat.SummonKonCron(db)
 	konCron.StartKonKron()
 
+	jobWorker := nacronsritammarat.SummonJobWorker(db)
+	jobWorker.StartJobWorker()
+
 	api := fiber.New()
 
 	if cfg.APP_ENV == "PRODUCTION" {
@@ -17,6 +17,8 @@ type Branch struct {
 	BranchLat    float64        `json:"branch_lat" gorm:"column:branch_lat"`
 	BranchLon    float64        `json:"branch_long" gorm:"column:branch_long"`
 	OwnerUserID  string         `json:"owner_user_id" gorm:"column:owner_user_id"`
+	PromptPayID  *string        `json:"promptpay_id" gorm:"column:promptpay_id"`
+	TaxID        *string        `json:"tax_id" gorm:"column:tax_id"`
 	CreatedAt    time.Time      `json:"created_at" gorm:"column:created_at"`
 	CreatedBy    string         `json:"created_by" gorm:"column:created_by"`
 	UpdatedAt    time.Time      `json:"updated_at" gorm:"column:updated_at"`
@@ -31,6 +33,8 @@ type CreateBranch struct {
 	BranchLat    float64 `json:"branch_lat" validate:"required"`
 	BranchLon    float64 `json:"branch_long" validate:"required"`
 	OwnerUserID  string  `json:"owner_user_id" validate:"required"`
+	PromptPayID  *string `json:"promptpay_id" validate:"omitempty,promptpayID"`
+	TaxID        *string `json:"tax_id" validate:"omitempty,numeric,len=13"`
 }
 type UpdateBranch struct {
 	BranchID     string  `json:"branch_id" validate:"required"`
@@ -39,6 +43,8 @@ type UpdateBranch struct {
 	BranchLat    float64 `json:"branch_lat" validate:"required"`
 	BranchLon    float64 `json:"branch_long" validate:"required"`
 	OwnerUserID  string  `json:"owner_user_id" validate:"required"`
+	PromptPayID  *string `json:"promptpay_id" validate:"omitempty,promptpayID"`
+	TaxID        *string `json:"tax_id" validate:"omitempty,numeric,len=13"`
 }
 
 type BranchDetail struct {
@@ -76,6 +82,11 @@ type MachineInBranch struct {
 	IsActive      bool        `json:"is_active" gorm:"column:is_active"`
 	Weight        int16       `json:"weight" gorm:"column:weight"`
 	FinishedAt    *time.Time  `json:"finished_at" gorm:"column:finished_at"`
+	// what the machine reported, nil when it doesn't report or its report is stale
+	DoorOpen   *bool      `json:"door_open" gorm:"column:door_open"`
+	Running    *bool      `json:"running" gorm:"column:running"`
+	ErrorCode  *string    `json:"error_code" gorm:"column:error_code"`
+	ReportedAt *time.Time `json:"reported_at" gorm:"column:reported_at"`
 	// IsAvailable   bool        `json:"is_available" gorm:"column:is_available"`
 }
 
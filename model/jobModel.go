@@ -0,0 +1,127 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"github.com/google/uuid"
+)
+
+func (Job) TableName() string {
+	return "Jobs"
+}
+
+type JobType string
+
+const (
+	// ExpirePaymentJob expires a payment that wasn't paid by its due date, with its baskets
+	ExpirePaymentJob JobType = "ExpirePayment"
+	// CompleteBasketJob completes a washing or drying basket once its machine is done
+	CompleteBasketJob JobType = "CompleteBasket"
+	// HookJob runs one subscriber of an event
+	HookJob JobType = "Hook"
+	// StartMachinesJob starts the machines of an onsite order once it's paid
+	StartMachinesJob JobType = "StartMachines"
+	// CheckCommandJob sends a machine command again or times it out when it isn't acknowledged
+	CheckCommandJob JobType = "CheckCommand"
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "Pending"
+	JobRunning JobStatus = "Running"
+	JobDone    JobStatus = "Done"
+	// JobDead is a job that failed every attempt, it waits for someone to look at it
+	JobDead JobStatus = "Dead"
+)
+
+// JobEvent is something other modules can subscribe to, see JobUsecase.Subscribe
+type JobEvent string
+
+const (
+	PaymentExpiredEvent  JobEvent = "PaymentExpired"
+	BasketCompletedEvent JobEvent = "BasketCompleted"
+)
+
+const (
+	// JobMaxAttempts is how many times a job runs before it's dead
+	JobMaxAttempts = 5
+	// JobLease is how long a worker has to finish a job before another worker takes it over
+	JobLease = 2 * time.Minute
+)
+
+// Job is one piece of work that has to run at RunAt. There is at most one job
+// per type and key, scheduling it again moves it instead of adding another.
+// SubjectID is what the job is about, e.g. the payment or basket id.
+type Job struct {
+	JobID       string     `json:"job_id" gorm:"column:job_id;primaryKey"`
+	JobType     JobType    `json:"job_type" gorm:"column:job_type;uniqueIndex:Jobs_type_key_idx,priority:1"`
+	JobKey      string     `json:"job_key" gorm:"column:job_key;uniqueIndex:Jobs_type_key_idx,priority:2"`
+	SubjectID   string     `json:"subject_id" gorm:"column:subject_id"`
+	Event       *JobEvent  `json:"event" gorm:"column:event"`
+	Subscriber  *string    `json:"subscriber" gorm:"column:subscriber"`
+	Status      JobStatus  `json:"status" gorm:"column:status;index:Jobs_status_run_at_idx,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"column:run_at;index:Jobs_status_run_at_idx,priority:2"`
+	Attempts    int        `json:"attempts" gorm:"column:attempts"`
+	MaxAttempts int        `json:"max_attempts" gorm:"column:max_attempts"`
+	LastError   *string    `json:"last_error" gorm:"column:last_error"`
+	LockedUntil *time.Time `json:"locked_until" gorm:"column:locked_until"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// NewJob is a job of jobType about subjectID that runs at runAt
+func NewJob(jobType JobType, subjectID string, runAt time.Time) Job {
+	now := time.Now().UTC()
+	return Job{
+		JobID:       uuid.New().String(),
+		JobType:     jobType,
+		JobKey:      subjectID,
+		SubjectID:   subjectID,
+		Status:      JobPending,
+		RunAt:       runAt.UTC(),
+		MaxAttempts: JobMaxAttempts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewHookJob is a job that tells subscriber event happened to subjectID, right away
+func NewHookJob(event JobEvent, subscriber string, subjectID string) Job {
+	job := NewJob(HookJob, subjectID, time.Now().UTC())
+	job.JobKey = string(event) + ":" + subscriber + ":" + subjectID
+	job.Event = &event
+	job.Subscriber = &subscriber
+	return job
+}
+
+type JobRepository interface {
+	Enqueue(job *Job) error
+	EnqueueMissing() (int64, error)
+	ClaimDue(limit int, lease time.Duration) (*[]Job, error)
+	Complete(job *Job) error
+	Reschedule(job *Job, runAt time.Time) error
+	Retry(job *Job, runAt time.Time, lastError string) error
+	Bury(job *Job, lastError string) error
+	FindByJobID(jobID string) (*Job, error)
+	GetDead() (*[]Job, error)
+	Revive(jobID string) error
+	WithTx(tx *platform.Postgres) JobRepository
+}
+
+// JobHook is called with the id of the payment or basket the event happened to.
+// It can be called more than once for the same event, returning an error retries it.
+type JobHook func(subjectID string) error
+
+// JobHandler runs a job of a type other modules take care of, see JobUsecase.Handle.
+// Returning a time runs the job again at that time, without counting an attempt.
+type JobHandler func(subjectID string) (*time.Time, error)
+
+type JobUsecase interface {
+	Subscribe(event JobEvent, subscriber string, hook JobHook)
+	Handle(jobType JobType, handler JobHandler)
+	RunDueJobs(limit int) (int, error)
+	GetDeadJobs() (*[]Job, error)
+	RetryDeadJob(jobID string) (*Job, error)
+}
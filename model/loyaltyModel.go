@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (LoyaltyTransaction) TableName() string {
+	return "LoyaltyTransactions"
+}
+
+type LoyaltyTransactionType string
+
+const (
+	PointsEarned   LoyaltyTransactionType = "Earned"
+	PointsRedeemed LoyaltyTransactionType = "Redeemed"
+	PointsRestored LoyaltyTransactionType = "Restored"
+	PointsExpired  LoyaltyTransactionType = "Expired"
+)
+
+const (
+	// BahtPerPoint is how much has to be paid for one point
+	BahtPerPoint = 10
+	// PointValue is how many baht one point takes off an order
+	PointValue = 0.1
+	// PointsLifetime is how long earned points can be used
+	PointsLifetime = 365 * 24 * time.Hour
+)
+
+// PointsLine is the service type of the price line redeemed points add to an order,
+// its amount is negative
+const PointsLine ServiceType = "Points"
+
+// LoyaltyTransaction is one line of the points ledger. Points are positive for
+// Earned and Restored and negative for Redeemed and Expired. An order earns,
+// redeems and restores at most once, the database enforces it.
+type LoyaltyTransaction struct {
+	TransactionID   string                 `json:"transaction_id" gorm:"column:transaction_id;primaryKey"`
+	UserID          string                 `json:"user_id" gorm:"column:user_id;index"`
+	TransactionType LoyaltyTransactionType `json:"transaction_type" gorm:"column:transaction_type;uniqueIndex:LoyaltyTransactions_order_type_idx,priority:2"`
+	Points          int64                  `json:"points" gorm:"column:points"`
+	OrderHeaderID   *string                `json:"order_header_id" gorm:"column:order_header_id;uniqueIndex:LoyaltyTransactions_order_type_idx,priority:1"`
+	ExpiresAt       *time.Time             `json:"expires_at" gorm:"column:expires_at"`
+	CreatedAt       time.Time              `json:"created_at" gorm:"column:created_at"`
+}
+
+type LoyaltyHistory struct {
+	UserID       string               `json:"user_id"`
+	Balance      int64                `json:"balance"`
+	Transactions []LoyaltyTransaction `json:"transactions"`
+}
+
+type LoyaltyRepository interface {
+	LockAccount(userID string) error
+	GetBalance(userID string) (int64, error)
+	ExpirePoints(userID string) error
+	FindUsersWithExpiredPoints() ([]string, error)
+	CreditCompletedOrders() (int64, error)
+	CreateTransaction(transaction *LoyaltyTransaction) error
+	RestoreRedeemed(orderHeaderID string) error
+	GetTransactions(userID string) (*[]LoyaltyTransaction, error)
+	WithTx(tx *platform.Postgres) LoyaltyRepository
+}
+
+type LoyaltyUsecase interface {
+	CreditCompletedOrders() error
+	ExpirePoints() error
+	GetHistory(userID string, requesterID string, requesterRole string) (*LoyaltyHistory, error)
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// MaxAnalyticsDays is the longest range machine analytics are computed for
+const MaxAnalyticsDays = 92
+
+// MachineCycle is one washing or drying basket a machine ran. Baskets started before
+// their start was recorded have StartedAt worked out from when they finished and how
+// long their program runs. Revenue is the list price of the basket before promo
+// discounts, 0 when its order isn't paid.
+type MachineCycle struct {
+	MachineSerial string    `json:"machine_serial" gorm:"column:machine_serial"`
+	OrderBasketID string    `json:"order_basket_id" gorm:"column:order_basket_id"`
+	StartedAt     time.Time `json:"started_at" gorm:"column:started_at"`
+	FinishedAt    time.Time `json:"finished_at" gorm:"column:finished_at"`
+	Revenue       float64   `json:"revenue" gorm:"column:revenue"`
+}
+
+// MachineDowntime is the time a report kept the machine out of service,
+// To is nil while the report is still accepted. Reports accepted before
+// accepted_at was recorded don't know when they started, so they aren't counted.
+type MachineDowntime struct {
+	MachineSerial string     `json:"machine_serial" gorm:"column:machine_serial"`
+	ReportID      string     `json:"report_id" gorm:"column:report_id"`
+	From          time.Time  `json:"from" gorm:"column:accepted_at"`
+	To            *time.Time `json:"to" gorm:"column:closed_at"`
+}
+
+type MachineDayUsage struct {
+	Date        string  `json:"date"`
+	Cycles      int     `json:"cycles"`
+	BusyMinutes float64 `json:"busy_minutes"`
+	Revenue     float64 `json:"revenue"`
+}
+
+// MachineUsage is how a machine was used over the range. Idle is the time it was
+// in service without running, Utilization is busy over the time it was in service.
+type MachineUsage struct {
+	MachineSerial   string            `json:"machine_serial"`
+	MachineLabel    string            `json:"machine_label"`
+	MachineType     MachineType       `json:"machine_type"`
+	Cycles          int               `json:"cycles"`
+	BusyMinutes     float64           `json:"busy_minutes"`
+	IdleMinutes     float64           `json:"idle_minutes"`
+	DowntimeMinutes float64           `json:"downtime_minutes"`
+	Utilization     float64           `json:"utilization"`
+	Revenue         float64           `json:"revenue"`
+	Days            []MachineDayUsage `json:"days"`
+}
+
+// MachineAnalytics covers the days from From up to To of a branch, in Thailand time.
+// PeakHours counts the cycles started in each hour, rows are weekdays from Sunday.
+type MachineAnalytics struct {
+	BranchID  string         `json:"branch_id"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Cycles    int            `json:"cycles"`
+	Revenue   float64        `json:"revenue"`
+	Machines  []MachineUsage `json:"machines"`
+	PeakHours [7][24]int     `json:"peak_hours"`
+}
+
+type MachineAnalyticsUsecase interface {
+	GetAnalytics(branchID string, from time.Time, to time.Time, userID string, userRole string) (*MachineAnalytics, error)
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (MachineCommand) TableName() string {
+	return "MachineCommands"
+}
+
+type MachineCommandType string
+
+const (
+	StartCommand  MachineCommandType = "Start"
+	StopCommand   MachineCommandType = "Stop"
+	UnlockCommand MachineCommandType = "Unlock"
+)
+
+type MachineCommandStatus string
+
+const (
+	// CommandPending waits for the machine to pick it up
+	CommandPending MachineCommandStatus = "Pending"
+	// CommandSent was picked up, the machine has CommandAckTimeout to acknowledge it
+	CommandSent     MachineCommandStatus = "Sent"
+	CommandAcked    MachineCommandStatus = "Acked"
+	CommandRejected MachineCommandStatus = "Rejected"
+	// CommandTimedOut was never acknowledged, it's not sent again
+	CommandTimedOut MachineCommandStatus = "TimedOut"
+)
+
+const (
+	// CommandAckTimeout is how long a machine has to acknowledge a command it picked up
+	CommandAckTimeout = 30 * time.Second
+	// CommandMaxAttempts is how many times a command is sent before it times out
+	CommandMaxAttempts = 3
+)
+
+// MachineCommand is something a machine is told to do. Machines pick their
+// commands up when they poll and acknowledge each one, every command is kept.
+// IdempotencyKey makes sure work that may run twice only issues one command.
+type MachineCommand struct {
+	CommandID      string               `json:"command_id" gorm:"column:command_id;primaryKey"`
+	MachineSerial  string               `json:"machine_serial" gorm:"column:machine_serial;index"`
+	CommandType    MachineCommandType   `json:"command_type" gorm:"column:command_type"`
+	Program        *string              `json:"program" gorm:"column:program"`
+	Temperature    *int                 `json:"temperature" gorm:"column:temperature"`
+	OrderBasketID  *string              `json:"order_basket_id" gorm:"column:order_basket_id"`
+	IdempotencyKey *string              `json:"-" gorm:"column:idempotency_key;uniqueIndex"`
+	Status         MachineCommandStatus `json:"status" gorm:"column:status"`
+	Attempts       int                  `json:"attempts" gorm:"column:attempts"`
+	SentAt         *time.Time           `json:"sent_at" gorm:"column:sent_at"`
+	AckedAt        *time.Time           `json:"acked_at" gorm:"column:acked_at"`
+	ExpiresAt      time.Time            `json:"expires_at" gorm:"column:expires_at"`
+	Error          *string              `json:"error" gorm:"column:error"`
+	IssuedBy       string               `json:"issued_by" gorm:"column:issued_by"`
+	CreatedAt      time.Time            `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt      time.Time            `json:"updated_at" gorm:"column:updated_at"`
+}
+
+type IssueMachineCommandDTO struct {
+	CommandType MachineCommandType `json:"command_type" validate:"required,oneof=Start Stop Unlock"`
+	Program     *string            `json:"program" validate:"omitempty,max=32"`
+	Temperature *int               `json:"temperature" validate:"omitempty,gte=0,lte=95"`
+}
+
+// MachineCommandAckDTO is the machine's answer to a command, Error says why it was rejected
+type MachineCommandAckDTO struct {
+	Accepted bool    `json:"accepted"`
+	Error    *string `json:"error" validate:"omitempty,max=255"`
+}
+
+type MachineCommandRepository interface {
+	CreateCommand(command *MachineCommand) (bool, error)
+	LockCommand(commandID string) (*MachineCommand, error)
+	ClaimPending(machineSerial string) (*[]MachineCommand, error)
+	SaveCommand(command *MachineCommand) error
+	GetByMachineSerial(machineSerial string) (*[]MachineCommand, error)
+	WithTx(tx *platform.Postgres) MachineCommandRepository
+}
+
+type MachineCommandUsecase interface {
+	IssueCommand(machineSerial string, command *IssueMachineCommandDTO, userID string, userRole string) (*MachineCommand, error)
+	GetByMachineSerial(machineSerial string, userID string, userRole string) (*[]MachineCommand, error)
+	StartPaidBaskets(paymentID string) (*time.Time, error)
+	PollCommands(machineSerial string, deviceToken string) (*[]MachineCommand, error)
+	AckCommand(machineSerial string, deviceToken string, commandID string, ack *MachineCommandAckDTO) (*MachineCommand, error)
+	CheckCommand(commandID string) (*time.Time, error)
+}
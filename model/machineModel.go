@@ -30,6 +30,9 @@ type Machine struct {
 	UpdatedBy     *string        `json:"updated_by" gorm:"column:updated_by"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
 	DeletedBy     *string        `json:"deleted_by" gorm:"column:deleted_by"`
+
+	// DeviceTokenHash is the sha256 of the token the machine reports with, see MachineCredential
+	DeviceTokenHash *string `json:"-" gorm:"column:device_token_hash"`
 }
 
 type AddMachine struct {
@@ -51,6 +54,16 @@ type MachineDetail struct {
 	FinishedAt    *time.Time  `json:"finished_at"`
 }
 
+// MachineBusyError is returned when a machine is already running
+// or reserved for another basket.
+type MachineBusyError struct {
+	MachineSerial string
+}
+
+func (e *MachineBusyError) Error() string {
+	return "ERR: mai wang ja"
+}
+
 type MachineWithTime struct {
 	FinishedAt *time.Time `json:"finished_at"`
 	Machine
@@ -2,6 +2,7 @@ package model
 
 import (
 	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
 	"gorm.io/gorm"
 )
@@ -27,6 +28,18 @@ type MachineReports struct {
 	ReportStatus      MachineReportStatus `json:"report_status" gorm:"column:report_status"`
 	CreatedAt         time.Time           `json:"created_at" gorm:"column:created_at"`
 	DeletedAt         gorm.DeletedAt      `json:"deleted_at" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
+
+	// Preventive reports are opened by the maintenance plan of the machine, see MaintenancePlan
+	Preventive bool `json:"preventive" gorm:"column:preventive"`
+
+	// the machine is out of service from AcceptedAt until ClosedAt, see MachineDowntime
+	AcceptedAt *time.Time `json:"accepted_at" gorm:"column:accepted_at"`
+	ClosedAt   *time.Time `json:"closed_at" gorm:"column:closed_at"`
+
+	// DeactivatedMachine is set on the accepted report that took the machine out of service,
+	// the machine is only put back when that report is closed. A machine switched off by
+	// hand stays off.
+	DeactivatedMachine bool `json:"deactivated_machine" gorm:"column:deactivated_machine"`
 }
 
 type AddMachineReportDTO struct {
@@ -48,6 +61,7 @@ type MachineReportDetail struct {
 	CreatedAt         time.Time           `json:"created_at"`
 	DeletedAt         *gorm.DeletedAt     `json:"deleted_at,omitempty" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
 	BranchInfo        BranchDetail        `json:"branch"`
+	Preventive        bool                `json:"preventive"`
 }
 
 type MachineReportsRepository interface {
@@ -58,6 +72,12 @@ type MachineReportsRepository interface {
 	GetAll() (*[]MachineReports, error)
 	UpdateMachineReportStatus(updateReport UpdateMachineReportStatusDTO) error
 	DeleteMachineReport(reportID string) error
+	CreateOpenPreventive(newReport *MachineReports) (bool, error)
+	CountAccepted(machineSerial string) (int64, error)
+	MarkDeactivatedMachine(reportID string) error
+	HandOverDeactivation(machineSerial string) error
+	GetDowntime(branchID string, from time.Time, to time.Time) (*[]MachineDowntime, error)
+	WithTx(tx *platform.Postgres) MachineReportsRepository
 }
 
 type MachineReportsUsecase interface {
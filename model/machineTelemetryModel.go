@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (MachineState) TableName() string {
+	return "MachineStates"
+}
+
+// MachineStateStale is how long a report is trusted. A machine that stopped
+// reporting is treated like one that never did, its baskets tell if it's in use.
+const MachineStateStale = 2 * time.Minute
+
+// MachineState is the last thing a machine reported about itself
+type MachineState struct {
+	MachineSerial  string    `json:"machine_serial" gorm:"column:machine_serial;primaryKey"`
+	DoorOpen       bool      `json:"door_open" gorm:"column:door_open"`
+	Running        bool      `json:"running" gorm:"column:running"`
+	CycleRemaining int       `json:"cycle_remaining" gorm:"column:cycle_remaining"`
+	ErrorCode      *string   `json:"error_code" gorm:"column:error_code"`
+	ReportedAt     time.Time `json:"reported_at" gorm:"column:reported_at"`
+}
+
+// MachineTelemetryDTO is what a machine reports. CycleRemaining is in seconds
+// and only means something while the machine is running.
+type MachineTelemetryDTO struct {
+	DoorOpen       bool    `json:"door_open"`
+	Running        bool    `json:"running"`
+	CycleRemaining int     `json:"cycle_remaining" validate:"gte=0,lte=86400"`
+	ErrorCode      *string `json:"error_code" validate:"omitempty,max=32"`
+}
+
+// MachineCredential is the token a machine reports with, it's only shown once
+type MachineCredential struct {
+	MachineSerial string `json:"machine_serial"`
+	DeviceToken   string `json:"device_token"`
+}
+
+type MachineTelemetryRepository interface {
+	SetDeviceToken(machineSerial string, tokenHash string) error
+	LockState(machineSerial string) (*MachineState, error)
+	SaveState(state *MachineState) error
+	WithTx(tx *platform.Postgres) MachineTelemetryRepository
+}
+
+type MachineTelemetryUsecase interface {
+	IssueCredential(machineSerial string, userID string, userRole string) (*MachineCredential, error)
+	Report(machineSerial string, deviceToken string, report *MachineTelemetryDTO) (*MachineState, error)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (MaintenancePlan) TableName() string {
+	return "MaintenancePlans"
+}
+
+// MaintenancePlan is when a machine is due for preventive maintenance, after
+// EveryCycles completed baskets or EveryDays days since it was last serviced,
+// whichever comes first. A preventive report is opened for it when it's due,
+// and fixing any report of the machine counts as a service.
+type MaintenancePlan struct {
+	MachineSerial string    `json:"machine_serial" gorm:"column:machine_serial;primaryKey"`
+	EveryCycles   *int      `json:"every_cycles" gorm:"column:every_cycles"`
+	EveryDays     *int      `json:"every_days" gorm:"column:every_days"`
+	LastServiceAt time.Time `json:"last_service_at" gorm:"column:last_service_at"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy     string    `json:"updated_by" gorm:"column:updated_by"`
+}
+
+type SetMaintenancePlanDTO struct {
+	EveryCycles *int `json:"every_cycles" validate:"required_without=EveryDays,omitempty,gte=1"`
+	EveryDays   *int `json:"every_days" validate:"required_without=EveryCycles,omitempty,gte=1"`
+}
+
+// MaintenanceStatus is a plan with how much the machine was used since its last service
+type MaintenanceStatus struct {
+	MaintenancePlan
+	BranchID string `json:"branch_id" gorm:"column:branch_id"`
+	Cycles   int64  `json:"cycles" gorm:"column:cycles"`
+}
+
+type MaintenanceRepository interface {
+	SavePlan(plan *MaintenancePlan) error
+	FindPlan(machineSerial string) (*MaintenanceStatus, error)
+	GetPlans() (*[]MaintenanceStatus, error)
+	MarkServiced(machineSerial string, servicedAt time.Time) error
+	WithTx(tx *platform.Postgres) MaintenanceRepository
+}
+
+type MaintenanceUsecase interface {
+	SetPlan(machineSerial string, plan *SetMaintenancePlanDTO, userID string, userRole string) (*MaintenanceStatus, error)
+	GetPlan(machineSerial string, userID string, userRole string) (*MaintenanceStatus, error)
+	OpenDueMaintenance() (int, error)
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+func (Notification) TableName() string {
+	return "Notifications"
+}
+
+// Notification is a message for the staff of a branch, e.g. a customer canceled an order
+type Notification struct {
+	NotificationID string     `json:"notification_id" gorm:"column:notification_id;primaryKey"`
+	BranchID       string     `json:"branch_id" gorm:"column:branch_id;index"`
+	OrderHeaderID  *string    `json:"order_header_id" gorm:"column:order_header_id"`
+	Title          string     `json:"title" gorm:"column:title"`
+	Message        string     `json:"message" gorm:"column:message"`
+	IsRead         bool       `json:"is_read" gorm:"column:is_read"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+	ReadAt         *time.Time `json:"read_at" gorm:"column:read_at"`
+	ReadBy         *string    `json:"read_by" gorm:"column:read_by"`
+}
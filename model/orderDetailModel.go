@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -30,15 +31,6 @@ const (
 	Agents   ServiceType = "Agents"
 )
 
-const (
-	SevenKGMachinePrice     int = 50
-	FourteenKGMachinePrice  int = 100
-	TwentyOneKGMachinePrice int = 150
-	DeliveryPrice           int = 20
-	PickupPrice             int = 20
-	AgentsPrice             int = 20
-)
-
 type OrderDetail struct {
 	OrderBasketID string          `json:"order_basket_id"`
 	OrderHeaderID string          `json:"order_header_id"`
@@ -46,6 +38,8 @@ type OrderDetail struct {
 	Weight        int16           `json:"weight"`
 	OrderStatus   OrderStatus     `json:"order_status"`
 	ServiceType   ServiceType     `json:"service_type"`
+	ProgramID     *string         `json:"program_id" gorm:"column:program_id"`
+	StartedAt     *time.Time      `json:"started_at" gorm:"column:started_at"`
 	FinishedAt    *time.Time      `json:"finished_at"`
 	CreatedAt     *time.Time      `json:"created_at,omitempty"`
 	CreatedBy     *string         `json:"created_by,omitempty"`
@@ -53,12 +47,26 @@ type OrderDetail struct {
 	UpdatedBy     *string         `json:"updated_by,omitempty" gorm:"column:updated_by"`
 	DeletedAt     *gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
 	DeletedBy     *string         `json:"deleted_by,omitempty" gorm:"column:deleted_by"`
+	NextStatus    []OrderStatus   `json:"next_status,omitempty" gorm:"-"`
+}
+
+// InvalidOrderTransitionError is returned when a basket is asked
+// to move to a status the order state machine doesn't allow.
+type InvalidOrderTransitionError struct {
+	From   OrderStatus
+	To     OrderStatus
+	Reason string
+}
+
+func (e *InvalidOrderTransitionError) Error() string {
+	return fmt.Sprintf("ERR: cannot move basket from %s to %s, %s", e.From, e.To, e.Reason)
 }
 
 type NewOrderDetail struct {
 	MachineSerial *string     `json:"machine_serial"`
 	Weight        int16       `json:"weight"`
 	ServiceType   ServiceType `json:"service_type"`
+	ProgramID     *string     `json:"program_id"`
 }
 
 type UpdateOrder struct {
@@ -68,3 +76,11 @@ type UpdateOrder struct {
 	FinishedAt    *time.Time  `json:"finished_at"`
 	UpdatedBy     string
 }
+
+type BasketToAssign struct {
+	OrderBasketID string      `json:"order_basket_id" gorm:"column:order_basket_id"`
+	OrderHeaderID string      `json:"order_header_id" gorm:"column:order_header_id"`
+	BranchID      string      `json:"branch_id" gorm:"column:branch_id"`
+	ServiceType   ServiceType `json:"service_type" gorm:"column:service_type"`
+	Weight        int16       `json:"weight" gorm:"column:weight"`
+}
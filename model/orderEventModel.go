@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+type OrderEventType string
+
+const (
+	OrderCreated       OrderEventType = "Created"
+	OrderStatusChanged OrderEventType = "StatusChanged"
+	MachineAssigned    OrderEventType = "MachineAssigned"
+	OrderReviewed      OrderEventType = "Reviewed"
+	OrderDeleted       OrderEventType = "Deleted"
+	// MachineOutOfService flags a basket whose machine was taken out of service while running it
+	MachineOutOfService OrderEventType = "MachineOutOfService"
+)
+
+// SystemActor is written as the actor of events made by cron jobs and auto assignment
+const SystemActor = "system"
+
+func (OrderEvent) TableName() string {
+	return "OrderEvents"
+}
+
+// OrderEvent is one entry of the append-only order history,
+// basket events carry OrderBasketID, order wide events leave it empty.
+type OrderEvent struct {
+	EventID       string         `json:"event_id" gorm:"column:event_id;primaryKey"`
+	OrderHeaderID string         `json:"order_header_id" gorm:"column:order_header_id;index"`
+	OrderBasketID *string        `json:"order_basket_id" gorm:"column:order_basket_id"`
+	EventType     OrderEventType `json:"event_type" gorm:"column:event_type"`
+	FromStatus    *OrderStatus   `json:"from_status" gorm:"column:from_status"`
+	ToStatus      *OrderStatus   `json:"to_status" gorm:"column:to_status"`
+	MachineSerial *string        `json:"machine_serial" gorm:"column:machine_serial"`
+	ActorID       string         `json:"actor_id" gorm:"column:actor_id"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"column:created_at;index"`
+}
+
+type OrderTimelineEvent struct {
+	EventID       string         `json:"event_id"`
+	OrderBasketID *string        `json:"order_basket_id"`
+	EventType     OrderEventType `json:"event_type"`
+	FromStatus    *OrderStatus   `json:"from_status"`
+	ToStatus      *OrderStatus   `json:"to_status"`
+	MachineSerial *string        `json:"machine_serial"`
+	ActorID       string         `json:"actor_id"`
+	Actor         *UserDetailDTO `json:"actor"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
@@ -40,28 +40,42 @@ type NewOrder struct {
 	DeliveryLat     *float64         `json:"delivery_lat"`
 	DeliveryLong    *float64         `json:"delivery_long"`
 	OrderDetails    []NewOrderDetail `json:"order_details" validate:"required"`
+	PayWithWallet   bool             `json:"pay_with_wallet"`
+	PromoCode       *string          `json:"promo_code"`
+	RedeemPoints    int64            `json:"redeem_points" validate:"gte=0"`
 }
 
 type FullOrder struct {
-	OrderHeaderID   string          `json:"order_header_id"`
-	UserID          string          `json:"user_id"`
-	UserDetail      UserDetailDTO   `json:"user_detail"`
-	BranchID        string          `json:"branch_id"`
-	OrderNote       *string         `json:"order_note"`
-	PaymentID       string          `json:"payment_id"`
-	ZuckOnsite      bool            `json:"zuck_onsite"`
-	DeliveryAddress *string         `json:"delivery_address"`
-	DeliveryLat     *float64        `json:"delivery_lat"`
-	DeliveryLong    *float64        `json:"delivery_long"`
-	StarRating      *int16          `json:"star_rating"`
-	ReviewComment   *string         `json:"review_comment"`
-	CreatedAt       *time.Time      `json:"created_at,omitempty"`
-	CreatedBy       *string         `json:"created_by,omitempty"`
-	UpdatedAt       *time.Time      `json:"updated_at,omitempty"`
-	UpdatedBy       *string         `json:"updated_by,omitempty"`
-	DeletedAt       *gorm.DeletedAt `json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
-	DeletedBy       *string         `json:"deleted_by,omitempty"`
-	OrderDetails    []OrderDetail   `json:"order_details"`
+	OrderHeaderID   string           `json:"order_header_id"`
+	UserID          string           `json:"user_id"`
+	UserDetail      UserDetailDTO    `json:"user_detail"`
+	BranchID        string           `json:"branch_id"`
+	OrderNote       *string          `json:"order_note"`
+	PaymentID       string           `json:"payment_id"`
+	ZuckOnsite      bool             `json:"zuck_onsite"`
+	DeliveryAddress *string          `json:"delivery_address"`
+	DeliveryLat     *float64         `json:"delivery_lat"`
+	DeliveryLong    *float64         `json:"delivery_long"`
+	StarRating      *int16           `json:"star_rating"`
+	ReviewComment   *string          `json:"review_comment"`
+	CreatedAt       *time.Time       `json:"created_at,omitempty"`
+	CreatedBy       *string          `json:"created_by,omitempty"`
+	UpdatedAt       *time.Time       `json:"updated_at,omitempty"`
+	UpdatedBy       *string          `json:"updated_by,omitempty"`
+	DeletedAt       *gorm.DeletedAt  `json:"deleted_at,omitempty" swaggertype:"string" example:"null"`
+	DeletedBy       *string          `json:"deleted_by,omitempty"`
+	OrderStatus     OrderStatus      `json:"order_status"`
+	OrderDetails    []OrderDetail    `json:"order_details"`
+	PriceLines      []OrderPriceLine `json:"price_lines,omitempty"`
+	Refunds         []Refund         `json:"refunds,omitempty"`
+}
+
+type OrderQuote struct {
+	BranchID   string           `json:"branch_id"`
+	ZuckOnsite bool             `json:"zuck_onsite"`
+	PriceLines []OrderPriceLine `json:"price_lines"`
+	Discount   float64          `json:"discount"`
+	TotalPrice float64          `json:"total_price"`
 }
 
 type OrderReview struct {
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type OrderSortBy string
+
+const (
+	SortByCreatedAt OrderSortBy = "created_at"
+	SortByUpdatedAt OrderSortBy = "updated_at"
+)
+
+const (
+	DefaultOrderPageLimit = 20
+	MaxOrderPageLimit     = 100
+)
+
+// OrderListFilter narrows an order listing, zero value fields are not filtered on.
+// Cursor is the next_cursor of the previous page, empty for the first page.
+type OrderListFilter struct {
+	BranchID      string
+	UserID        string
+	OrderStatus   OrderStatus
+	PaymentStatus PaymentStatus
+	ServiceType   ServiceType
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	ZuckOnsite    *bool
+	SortBy        OrderSortBy
+	SortDesc      bool
+	Cursor        string
+	Limit         int
+}
+
+// OrderCursor is the position of the last order of a page,
+// the next page starts right after it in the same sort order
+type OrderCursor struct {
+	SortValue     time.Time `json:"v"`
+	OrderHeaderID string    `json:"id"`
+}
+
+type OrderPage struct {
+	Data       []FullOrder `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+	Limit      int         `json:"limit"`
+}
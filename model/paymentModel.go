@@ -2,6 +2,7 @@ package model
 
 import (
 	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
 	"gorm.io/gorm"
 )
@@ -10,6 +11,10 @@ func (Payments) TableName() string {
 	return "Payments"
 }
 
+func (PaymentWebhookEvent) TableName() string {
+	return "PaymentWebhookEvents"
+}
+
 type PaymentStatus string
 
 const (
@@ -19,24 +24,74 @@ const (
 	Cancel  PaymentStatus = "Cancel"
 )
 
+// Payments is what an order has to pay. PolicyID is the PaymentPolicy the due date
+// came from, nil for the built-in default.
 type Payments struct {
 	PaymentID      string         `json:"payment_id" db:"payment_id"`
 	Amount         float64        `json:"amount" db:"amount" validate:"required"`
 	Payment_Status PaymentStatus  `json:"payment_status" db:"payment_status"`
 	DueDate        time.Time      `json:"due_date" db:"due_date"`
+	PolicyID       *string        `json:"policy_id" db:"policy_id"`
+	ExtensionCount int            `json:"extension_count" db:"extension_count"`
+	PaidAt         *time.Time     `json:"paid_at" db:"paid_at"`
 	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" db:"deleted_at" swaggertype:"string" example:"null"`
 }
 
+// PaymentCharge is what the customer needs to pay a payment through the provider
+type PaymentCharge struct {
+	PaymentID string    `json:"payment_id"`
+	Provider  string    `json:"provider"`
+	Amount    float64   `json:"amount"`
+	QRPayload string    `json:"qr_payload"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PaymentWebhookEvent is a verified notification from the provider, EventID is the
+// idempotency key so a notification sent twice is only applied once
+type PaymentWebhookEvent struct {
+	EventID    string        `json:"event_id" gorm:"column:event_id;primaryKey"`
+	Provider   string        `json:"provider" gorm:"column:provider"`
+	PaymentID  string        `json:"payment_id" gorm:"column:payment_id;index"`
+	Status     PaymentStatus `json:"status" gorm:"column:status"`
+	Amount     float64       `json:"amount" gorm:"column:amount"`
+	PaidAt     time.Time     `json:"paid_at" gorm:"column:paid_at"`
+	ReceivedAt time.Time     `json:"received_at" gorm:"column:received_at"`
+}
+
+// PaymentProvider is the gateway customers pay through, it's the only thing
+// that can tell the system a payment is paid. merchantID is who receives the money,
+// empty to use the provider's default merchant.
+type PaymentProvider interface {
+	Name() string
+	CreateCharge(payment Payments, merchantID string) (*PaymentCharge, error)
+	VerifyWebhook(body []byte, signature string) (*PaymentWebhookEvent, error)
+}
+
 type PaymentRepository interface {
 	CreatePayment(newPayment Payments) (*Payments, error)
 	FindByPaymentID(paymentID string) (*Payments, error)
 	UpdatePaymentStatus(paymentID string, status PaymentStatus) error
-	CleanupExpiredPayment() error
+	ExpirePayment(paymentID string) error
+	LockPayment(paymentID string) (*Payments, error)
+	CancelPayment(paymentID string) error
+	MarkPaid(paymentID string) error
+	RecordWebhookEvent(event *PaymentWebhookEvent) (bool, error)
+	FindMerchantID(paymentID string) (string, error)
+	ExtendPayment(paymentID string, dueDate time.Time) error
+	GetReportLines(branchID string, from time.Time, to time.Time) (*[]PaymentReportLine, error)
+	WithTx(tx *platform.Postgres) PaymentRepository
 }
 
 type PaymentUsecase interface {
 	CreatePayment(newPayment Payments) (*Payments, error)
+	CreateOrderPayment(amount float64, branchID string, zuckOnsite bool) (*Payments, error)
 	FindByPaymentID(paymentID string) (*Payments, error)
 	UpdatePaymentStatus(paymentID string, status PaymentStatus) (*Payments, error)
+	CancelPayment(paymentID string, orderHeaderID string, canceledBy string) (*Payments, error)
+	PayWithWallet(paymentID string, userID string) (*Payments, error)
+	AfterPaid(paymentID string)
+	CreateCharge(paymentID string, userID string, userRole string) (*PaymentCharge, error)
+	HandleWebhook(body []byte, signature string) (*Payments, error)
+	WithTx(tx *platform.Postgres) PaymentUsecase
 }
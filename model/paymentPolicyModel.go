@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+func (PaymentPolicy) TableName() string {
+	return "PaymentPolicies"
+}
+
+const (
+	// DefaultDueMinutes is how long a payment can be paid when no policy is set
+	DefaultDueMinutes = 10
+	// DefaultExtendMinutes is how much staff push the due date when no policy is set
+	DefaultExtendMinutes = 10
+	// DefaultMaxExtensions is how many times staff can extend when no policy is set
+	DefaultMaxExtensions = 1
+)
+
+// PaymentPolicy is how long the payment of an order can be paid. Policies
+// without BranchID are the default for every branch, there is at most one
+// policy per branch and order type (onsite or online).
+//
+// DueMinutes is the window from when the order is placed. Once it's over the
+// payment can't be paid anymore, but the cron waits GraceMinutes more before
+// expiring it so a payment already on its way through the provider isn't lost.
+// Staff can push the due date by ExtendMinutes up to MaxExtensions times.
+type PaymentPolicy struct {
+	PolicyID      string    `json:"policy_id" gorm:"column:policy_id;primaryKey"`
+	BranchID      *string   `json:"branch_id" gorm:"column:branch_id;index"`
+	ZuckOnsite    bool      `json:"zuck_onsite" gorm:"column:zuck_onsite"`
+	DueMinutes    int       `json:"due_minutes" gorm:"column:due_minutes"`
+	GraceMinutes  int       `json:"grace_minutes" gorm:"column:grace_minutes"`
+	ExtendMinutes int       `json:"extend_minutes" gorm:"column:extend_minutes"`
+	MaxExtensions int       `json:"max_extensions" gorm:"column:max_extensions"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string    `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy     string    `json:"updated_by" gorm:"column:updated_by"`
+}
+
+// DefaultPaymentPolicy is used when neither the branch nor the default policy is set,
+// it's the window payments always had
+func DefaultPaymentPolicy(zuckOnsite bool) PaymentPolicy {
+	return PaymentPolicy{
+		ZuckOnsite:    zuckOnsite,
+		DueMinutes:    DefaultDueMinutes,
+		GraceMinutes:  0,
+		ExtendMinutes: DefaultExtendMinutes,
+		MaxExtensions: DefaultMaxExtensions,
+	}
+}
+
+type SetPaymentPolicyDTO struct {
+	BranchID      *string `json:"branch_id"`
+	ZuckOnsite    bool    `json:"zuck_onsite"`
+	DueMinutes    int     `json:"due_minutes" validate:"gte=1,lte=1440"`
+	GraceMinutes  int     `json:"grace_minutes" validate:"gte=0,lte=60"`
+	ExtendMinutes int     `json:"extend_minutes" validate:"gte=1,lte=1440"`
+	MaxExtensions int     `json:"max_extensions" validate:"gte=0,lte=10"`
+}
+
+type PaymentPolicyRepository interface {
+	SavePolicy(policy *PaymentPolicy) error
+	FindByPolicyID(policyID string) (*PaymentPolicy, error)
+	FindPolicy(branchID *string, zuckOnsite bool) (*PaymentPolicy, error)
+	FindByBranchID(branchID string) (*[]PaymentPolicy, error)
+	FindEffectivePolicy(branchID string, zuckOnsite bool) (*PaymentPolicy, error)
+}
+
+type PaymentPolicyUsecase interface {
+	SetPolicy(policy *SetPaymentPolicyDTO, userID string, userRole string) (*PaymentPolicy, error)
+	FindByBranchID(branchID string) (*[]PaymentPolicy, error)
+	ExtendPayment(paymentID string, userID string, userRole string) (*Payments, error)
+}
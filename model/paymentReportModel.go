@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// ReportLocation is the time zone report days are counted in, every branch is in Thailand
+var ReportLocation = time.FixedZone("ICT", 7*60*60)
+
+// WalletMethod is the method of payments paid from the customer's wallet
+const WalletMethod = "Wallet"
+
+// PaymentReportLine is one payment of a branch as it stands now. Method is Wallet
+// or the provider that notified the payment, empty when no money came in.
+// ReceivedAmount is what the provider notified, nil when it never did.
+type PaymentReportLine struct {
+	PaymentID      string        `json:"payment_id" gorm:"column:payment_id"`
+	OrderHeaderID  string        `json:"order_header_id" gorm:"column:order_header_id"`
+	Amount         float64       `json:"amount" gorm:"column:amount"`
+	PaymentStatus  PaymentStatus `json:"payment_status" gorm:"column:payment_status"`
+	Method         string        `json:"method" gorm:"column:method"`
+	ReceivedAmount *float64      `json:"received_amount" gorm:"column:received_amount"`
+	DueDate        time.Time     `json:"due_date" gorm:"column:due_date"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"column:created_at"`
+}
+
+type PaymentStatusSummary struct {
+	PaymentStatus PaymentStatus `json:"payment_status"`
+	Count         int           `json:"count"`
+	Total         float64       `json:"total"`
+}
+
+type PaymentMethodSummary struct {
+	Method string  `json:"method"`
+	Count  int     `json:"count"`
+	Total  float64 `json:"total"`
+}
+
+type RefundStatusSummary struct {
+	RefundStatus RefundStatus `json:"refund_status"`
+	Count        int          `json:"count"`
+	Total        float64      `json:"total"`
+}
+
+// PaymentReport is what a branch has to reconcile for one day: payments created
+// that day and refunds requested that day, whichever day their payment is from.
+//
+// Unmatched are payments where the money and the status don't agree, money
+// came in for a payment that isn't paid or a paid payment has no money to show for it.
+// NetTotal is what was paid less the refunds completed.
+type PaymentReport struct {
+	BranchID    string                 `json:"branch_id"`
+	Date        string                 `json:"date"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	ByStatus    []PaymentStatusSummary `json:"by_status"`
+	ByMethod    []PaymentMethodSummary `json:"by_method"`
+	Refunds     []RefundStatusSummary  `json:"refunds_by_status"`
+	NetTotal    float64                `json:"net_total"`
+	Payments    []PaymentReportLine    `json:"payments"`
+	Unmatched   []PaymentReportLine    `json:"unmatched"`
+	Expired     []PaymentReportLine    `json:"expired"`
+	RefundLines []Refund               `json:"refunds"`
+}
+
+type PaymentReportUsecase interface {
+	GetDailyReport(branchID string, date time.Time, userID string, userRole string) (*PaymentReport, error)
+}
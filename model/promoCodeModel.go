@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (PromoCode) TableName() string {
+	return "PromoCodes"
+}
+
+func (PromoRedemption) TableName() string {
+	return "PromoRedemptions"
+}
+
+type PromoDiscountType string
+
+const (
+	PercentageDiscount PromoDiscountType = "Percentage"
+	FixedDiscount      PromoDiscountType = "Fixed"
+)
+
+// DiscountLine is the service type of the price line a promo code adds to an order,
+// its amount is negative
+const DiscountLine ServiceType = "Discount"
+
+// PromoCode is a discount customers enter at checkout. Codes without BranchID
+// work in every branch, nil caps and ValidUntil mean no limit.
+type PromoCode struct {
+	PromoCodeID           string            `json:"promo_code_id" gorm:"column:promo_code_id;primaryKey"`
+	Code                  string            `json:"code" gorm:"column:code;uniqueIndex"`
+	DiscountType          PromoDiscountType `json:"discount_type" gorm:"column:discount_type"`
+	DiscountValue         float64           `json:"discount_value" gorm:"column:discount_value"`
+	MaxDiscount           *float64          `json:"max_discount" gorm:"column:max_discount"`
+	BranchID              *string           `json:"branch_id" gorm:"column:branch_id;index"`
+	MinOrderAmount        float64           `json:"min_order_amount" gorm:"column:min_order_amount"`
+	MaxRedemptions        *int              `json:"max_redemptions" gorm:"column:max_redemptions"`
+	MaxRedemptionsPerUser *int              `json:"max_redemptions_per_user" gorm:"column:max_redemptions_per_user"`
+	ValidFrom             time.Time         `json:"valid_from" gorm:"column:valid_from"`
+	ValidUntil            *time.Time        `json:"valid_until" gorm:"column:valid_until"`
+	CreatedAt             time.Time         `json:"created_at" gorm:"column:created_at"`
+	CreatedBy             string            `json:"created_by" gorm:"column:created_by"`
+}
+
+// PromoRedemption is one use of a promo code, an order can only use one code
+type PromoRedemption struct {
+	RedemptionID   string    `json:"redemption_id" gorm:"column:redemption_id;primaryKey"`
+	PromoCodeID    string    `json:"promo_code_id" gorm:"column:promo_code_id;index:PromoRedemptions_promo_user_idx,priority:1"`
+	OrderHeaderID  string    `json:"order_header_id" gorm:"column:order_header_id;uniqueIndex"`
+	UserID         string    `json:"user_id" gorm:"column:user_id;index:PromoRedemptions_promo_user_idx,priority:2"`
+	BranchID       string    `json:"branch_id" gorm:"column:branch_id"`
+	DiscountAmount float64   `json:"discount_amount" gorm:"column:discount_amount"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+type NewPromoCode struct {
+	Code                  string            `json:"code" validate:"required,alphanum,max=32"`
+	DiscountType          PromoDiscountType `json:"discount_type" validate:"required,oneof=Percentage Fixed"`
+	DiscountValue         float64           `json:"discount_value" validate:"gt=0"`
+	MaxDiscount           *float64          `json:"max_discount" validate:"omitempty,gt=0"`
+	BranchID              *string           `json:"branch_id"`
+	MinOrderAmount        float64           `json:"min_order_amount" validate:"gte=0"`
+	MaxRedemptions        *int              `json:"max_redemptions" validate:"omitempty,gt=0"`
+	MaxRedemptionsPerUser *int              `json:"max_redemptions_per_user" validate:"omitempty,gt=0"`
+	ValidFrom             *time.Time        `json:"valid_from"`
+	ValidUntil            *time.Time        `json:"valid_until"`
+}
+
+// PromoUsage is how many times a code was used, overall and by one user
+type PromoUsage struct {
+	Total  int64 `gorm:"column:total"`
+	ByUser int64 `gorm:"column:by_user"`
+}
+
+type PromoCodeReport struct {
+	PromoCode       PromoCode         `json:"promo_code"`
+	RedemptionCount int               `json:"redemption_count"`
+	UserCount       int               `json:"user_count"`
+	TotalDiscount   float64           `json:"total_discount"`
+	Redemptions     []PromoRedemption `json:"redemptions"`
+}
+
+type PromoCodeRepository interface {
+	CreatePromoCode(promoCode *PromoCode) error
+	FindByPromoCodeID(promoCodeID string) (*PromoCode, error)
+	FindByCode(code string) (*PromoCode, error)
+	GetAll() (*[]PromoCode, error)
+	LockPromoCode(promoCodeID string) (*PromoCode, error)
+	CountRedemptions(promoCodeID string, userID string) (*PromoUsage, error)
+	CreateRedemption(redemption *PromoRedemption) error
+	GetRedemptions(promoCodeID string) (*[]PromoRedemption, error)
+	WithTx(tx *platform.Postgres) PromoCodeRepository
+}
+
+type PromoCodeUsecase interface {
+	CreatePromoCode(newPromoCode *NewPromoCode, userID string) (*PromoCode, error)
+	GetAll() (*[]PromoCode, error)
+	GetReport(promoCodeID string) (*PromoCodeReport, error)
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"fmt"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (Receipt) TableName() string {
+	return "Receipts"
+}
+
+func (ReceiptSequence) TableName() string {
+	return "ReceiptSequences"
+}
+
+// VATRate is the Thai VAT in percent, prices already include it
+const VATRate = 7
+
+// Receipt is the numbered receipt of a paid order. Numbers run per branch
+// without gaps, one is only taken when the receipt is stored. Amounts are
+// fixed when the receipt is issued, a later refund doesn't change them.
+type Receipt struct {
+	ReceiptID     string    `json:"receipt_id" gorm:"column:receipt_id;primaryKey"`
+	BranchID      string    `json:"branch_id" gorm:"column:branch_id;uniqueIndex:Receipts_branch_no_idx,priority:1"`
+	ReceiptNo     int64     `json:"receipt_no" gorm:"column:receipt_no;uniqueIndex:Receipts_branch_no_idx,priority:2"`
+	OrderHeaderID string    `json:"order_header_id" gorm:"column:order_header_id;uniqueIndex"`
+	PaymentID     string    `json:"payment_id" gorm:"column:payment_id"`
+	Subtotal      float64   `json:"subtotal" gorm:"column:subtotal;type:numeric(12,2)"`
+	VAT           float64   `json:"vat" gorm:"column:vat;type:numeric(12,2)"`
+	Total         float64   `json:"total" gorm:"column:total;type:numeric(12,2)"`
+	PaidAt        time.Time `json:"paid_at" gorm:"column:paid_at"`
+	IssuedAt      time.Time `json:"issued_at" gorm:"column:issued_at"`
+	IssuedBy      string    `json:"issued_by" gorm:"column:issued_by"`
+}
+
+// ReceiptSequence is the last receipt number taken by a branch
+type ReceiptSequence struct {
+	BranchID   string `json:"branch_id" gorm:"column:branch_id;primaryKey"`
+	LastNumber int64  `json:"last_number" gorm:"column:last_number"`
+}
+
+// Number is the receipt number printed on the receipt
+func (r Receipt) Number() string {
+	return fmt.Sprintf("%s-%08d", ReceiptPrefix(r.BranchID), r.ReceiptNo)
+}
+
+// ReceiptPrefix tells receipts of different branches apart
+func ReceiptPrefix(branchID string) string {
+	prefix := branchID
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	return "RC" + prefix
+}
+
+type ReceiptLine struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// ReceiptDocument is everything printed on a receipt
+type ReceiptDocument struct {
+	Receipt
+	ReceiptNumber   string        `json:"receipt_number"`
+	BranchName      string        `json:"branch_name"`
+	BranchDetail    string        `json:"branch_detail"`
+	BranchTaxID     *string       `json:"branch_tax_id"`
+	CustomerName    string        `json:"customer_name"`
+	CustomerEmail   string        `json:"customer_email"`
+	ZuckOnsite      bool          `json:"zuck_onsite"`
+	PaymentMethod   string        `json:"payment_method"`
+	Lines           []ReceiptLine `json:"lines"`
+	DeliveryAddress *string       `json:"delivery_address"`
+}
+
+type ReceiptRepository interface {
+	NextNumber(branchID string) (int64, error)
+	CreateReceipt(receipt *Receipt) error
+	FindByOrderHeaderID(orderHeaderID string) (*Receipt, error)
+	WithTx(tx *platform.Postgres) ReceiptRepository
+}
+
+type ReceiptUsecase interface {
+	GetReceipt(orderHeaderID string, userID string, userRole string) (*ReceiptDocument, error)
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (Refund) TableName() string {
+	return "Refunds"
+}
+
+type RefundStatus string
+
+const (
+	RefundRequested RefundStatus = "Requested"
+	RefundCompleted RefundStatus = "Completed"
+	RefundRejected  RefundStatus = "Rejected"
+)
+
+// Refund is money owed back to the customer for a payment that was already paid
+type Refund struct {
+	RefundID      string       `json:"refund_id" gorm:"column:refund_id;primaryKey"`
+	PaymentID     string       `json:"payment_id" gorm:"column:payment_id;index"`
+	OrderHeaderID string       `json:"order_header_id" gorm:"column:order_header_id;index"`
+	Amount        float64      `json:"amount" gorm:"column:amount"`
+	RefundStatus  RefundStatus `json:"refund_status" gorm:"column:refund_status"`
+	Reason        string       `json:"reason" gorm:"column:reason"`
+	CreatedAt     time.Time    `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string       `json:"created_by" gorm:"column:created_by"`
+	ProcessedAt   *time.Time   `json:"processed_at" gorm:"column:processed_at"`
+	ProcessedBy   *string      `json:"processed_by" gorm:"column:processed_by"`
+}
+
+type NewRefund struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	Reason string  `json:"reason" validate:"required"`
+}
+
+type RefundRepository interface {
+	CreateRefund(refund *Refund) error
+	FindByRefundID(refundID string) (*Refund, error)
+	FindByPaymentID(paymentID string) (*[]Refund, error)
+	SumByPaymentID(paymentID string) (float64, error)
+	GetByBranchID(branchID string, from time.Time, to time.Time) (*[]Refund, error)
+	UpdateRefundStatus(refundID string, status RefundStatus, processedBy string) error
+	WithTx(tx *platform.Postgres) RefundRepository
+}
+
+type RefundUsecase interface {
+	CreateRefund(paymentID string, newRefund NewRefund, userID string, userRole string) (*Refund, error)
+	GetByPaymentID(paymentID string, userID string, userRole string) (*[]Refund, error)
+	CompleteRefund(refundID string, userID string, userRole string) (*Refund, error)
+	RejectRefund(refundID string, userID string, userRole string) (*Refund, error)
+}
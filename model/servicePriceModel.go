@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (ServicePrice) TableName() string {
+	return "ServicePrices"
+}
+
+func (OrderPriceLine) TableName() string {
+	return "OrderPriceLines"
+}
+
+// ServicePrice is one entry of the price catalog. Entries without BranchID are
+// the default for every branch, services that are not priced by weight use weight 0.
+type ServicePrice struct {
+	PriceID       string         `json:"price_id" gorm:"column:price_id;primaryKey"`
+	BranchID      *string        `json:"branch_id" gorm:"column:branch_id;index"`
+	ServiceType   ServiceType    `json:"service_type" gorm:"column:service_type"`
+	Weight        int16          `json:"weight" gorm:"column:weight"`
+	Price         float64        `json:"price" gorm:"column:price"`
+	EffectiveFrom time.Time      `json:"effective_from" gorm:"column:effective_from"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"column:created_at"`
+	CreatedBy     string         `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy     string         `json:"updated_by" gorm:"column:updated_by"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
+	DeletedBy     *string        `json:"deleted_by" gorm:"column:deleted_by"`
+}
+
+type AddServicePriceDTO struct {
+	BranchID      *string     `json:"branch_id"`
+	ServiceType   ServiceType `json:"service_type" validate:"required,serviceType"`
+	Weight        int16       `json:"weight" validate:"gte=0"`
+	Price         float64     `json:"price" validate:"gte=0"`
+	EffectiveFrom *time.Time  `json:"effective_from"`
+}
+
+type UpdateServicePriceDTO struct {
+	PriceID       string     `json:"price_id" validate:"required"`
+	Price         float64    `json:"price" validate:"gte=0"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+}
+
+// OrderPriceLine is the price an order was charged for one service,
+// copied from the catalog when the order is placed and scaled by the
+// program picked for the baskets. A promo code adds one more line of
+// DiscountLine with a negative amount.
+type OrderPriceLine struct {
+	PriceLineID   string      `json:"price_line_id,omitempty" gorm:"column:price_line_id;primaryKey"`
+	OrderHeaderID string      `json:"order_header_id,omitempty" gorm:"column:order_header_id;index"`
+	LineNo        int         `json:"line_no" gorm:"column:line_no"`
+	PriceID       *string     `json:"price_id" gorm:"column:price_id"`
+	PromoCodeID   *string     `json:"promo_code_id,omitempty" gorm:"column:promo_code_id"`
+	ProgramID     *string     `json:"program_id,omitempty" gorm:"column:program_id"`
+	ProgramName   *string     `json:"program_name,omitempty" gorm:"column:program_name"`
+	ServiceType   ServiceType `json:"service_type" gorm:"column:service_type"`
+	Weight        int16       `json:"weight" gorm:"column:weight"`
+	Quantity      int         `json:"quantity" gorm:"column:quantity"`
+	UnitPrice     float64     `json:"unit_price" gorm:"column:unit_price"`
+	Amount        float64     `json:"amount" gorm:"column:amount"`
+	CreatedAt     time.Time   `json:"created_at" gorm:"column:created_at"`
+}
+
+type ServicePriceRepository interface {
+	CreateServicePrice(newPrice *ServicePrice) error
+	FindByPriceID(priceID string) (*ServicePrice, error)
+	FindByBranchID(branchID string) (*[]ServicePrice, error)
+	FindEffectivePrice(branchID string, serviceType ServiceType, weight int16, at time.Time) (*ServicePrice, error)
+	DeleteServicePrice(priceID string, deletedBy string) error
+}
+
+type ServicePriceUsecase interface {
+	CreateServicePrice(newPrice *AddServicePriceDTO, userID string, userRole string) (*ServicePrice, error)
+	FindByBranchID(branchID string) (*[]ServicePrice, error)
+	UpdateServicePrice(price *UpdateServicePriceDTO, userID string, userRole string) (*ServicePrice, error)
+	DeleteServicePrice(priceID string, userID string, userRole string) error
+}
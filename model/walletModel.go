@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+func (Wallet) TableName() string {
+	return "Wallets"
+}
+
+func (WalletTransaction) TableName() string {
+	return "WalletTransactions"
+}
+
+type WalletTransactionType string
+
+const (
+	WalletTopUp  WalletTransactionType = "TopUp"
+	WalletDebit  WalletTransactionType = "Debit"
+	WalletRefund WalletTransactionType = "Refund"
+)
+
+// Wallet is the prepaid balance of a customer, the database refuses a negative balance
+type Wallet struct {
+	UserID    string    `json:"user_id" gorm:"column:user_id;primaryKey"`
+	Balance   float64   `json:"balance" gorm:"column:balance;type:numeric(12,2);not null;default:0;check:wallet_balance_not_negative,balance >= 0"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// WalletTransaction is one line of the wallet ledger, rows are only ever inserted.
+// Amount is always positive, TransactionType tells which way the money went.
+type WalletTransaction struct {
+	TransactionID   string                `json:"transaction_id" gorm:"column:transaction_id;primaryKey"`
+	UserID          string                `json:"user_id" gorm:"column:user_id;index:WalletTransactions_user_created_idx,priority:1"`
+	TransactionType WalletTransactionType `json:"transaction_type" gorm:"column:transaction_type"`
+	Amount          float64               `json:"amount" gorm:"column:amount;type:numeric(12,2)"`
+	BalanceAfter    float64               `json:"balance_after" gorm:"column:balance_after;type:numeric(12,2)"`
+	PaymentID       *string               `json:"payment_id" gorm:"column:payment_id;index"`
+	RefundID        *string               `json:"refund_id" gorm:"column:refund_id"`
+	Note            *string               `json:"note" gorm:"column:note"`
+	CreatedAt       time.Time             `json:"created_at" gorm:"column:created_at;index:WalletTransactions_user_created_idx,priority:2"`
+	CreatedBy       string                `json:"created_by" gorm:"column:created_by"`
+}
+
+type WalletTopUpRequest struct {
+	UserID string  `json:"user_id" validate:"required"`
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	Note   *string `json:"note"`
+}
+
+type WalletStatement struct {
+	UserID       string              `json:"user_id"`
+	Balance      float64             `json:"balance"`
+	From         *time.Time          `json:"from"`
+	To           *time.Time          `json:"to"`
+	Transactions []WalletTransaction `json:"transactions"`
+}
+
+type WalletRepository interface {
+	GetWallet(userID string) (*Wallet, error)
+	Credit(userID string, amount float64) (float64, error)
+	Debit(userID string, amount float64) (float64, error)
+	CreateTransaction(transaction *WalletTransaction) error
+	FindDebitByPaymentID(paymentID string) (*WalletTransaction, error)
+	GetTransactions(userID string, from *time.Time, to *time.Time) (*[]WalletTransaction, error)
+	WithTx(tx *platform.Postgres) WalletRepository
+}
+
+type WalletUsecase interface {
+	TopUp(topUp WalletTopUpRequest, staffID string) (*WalletTransaction, error)
+	GetStatement(userID string, from *time.Time, to *time.Time) (*WalletStatement, error)
+}
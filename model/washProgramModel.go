@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (WashProgram) TableName() string {
+	return "WashPrograms"
+}
+
+// DefaultCycleMinutes is how long a basket runs when no program was picked for it
+const DefaultCycleMinutes = 25
+
+// WashProgram is a cycle every machine of MachineType can run, e.g. quick wash
+// or dryer low heat. The basket it's picked for is done after DurationMinutes,
+// and its service price is scaled by PriceModifier, 1.2 costs 20% more.
+type WashProgram struct {
+	ProgramID       string         `json:"program_id" gorm:"column:program_id;primaryKey"`
+	MachineType     MachineType    `json:"machine_type" gorm:"column:machine_type;index"`
+	Name            string         `json:"name" gorm:"column:name"`
+	DurationMinutes int            `json:"duration_minutes" gorm:"column:duration_minutes"`
+	Temperature     *int           `json:"temperature" gorm:"column:temperature"`
+	PriceModifier   float64        `json:"price_modifier" gorm:"column:price_modifier"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"column:created_at"`
+	CreatedBy       string         `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy       string         `json:"updated_by" gorm:"column:updated_by"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;index" swaggertype:"string" example:"null"`
+	DeletedBy       *string        `json:"deleted_by" gorm:"column:deleted_by"`
+}
+
+type AddWashProgramDTO struct {
+	MachineType     MachineType `json:"machine_type" validate:"required,machineType"`
+	Name            string      `json:"name" validate:"required,max=32"`
+	DurationMinutes int         `json:"duration_minutes" validate:"required,gte=1,lte=240"`
+	Temperature     *int        `json:"temperature" validate:"omitempty,gte=0,lte=95"`
+	PriceModifier   float64     `json:"price_modifier" validate:"required,gt=0,lte=5"`
+}
+
+type UpdateWashProgramDTO struct {
+	ProgramID       string  `json:"program_id" validate:"required"`
+	Name            string  `json:"name" validate:"required,max=32"`
+	DurationMinutes int     `json:"duration_minutes" validate:"required,gte=1,lte=240"`
+	Temperature     *int    `json:"temperature" validate:"omitempty,gte=0,lte=95"`
+	PriceModifier   float64 `json:"price_modifier" validate:"required,gt=0,lte=5"`
+}
+
+type WashProgramRepository interface {
+	CreateProgram(newProgram *WashProgram) error
+	FindByProgramID(programID string) (*WashProgram, error)
+	FindByMachineType(machineType MachineType) (*[]WashProgram, error)
+	UpdateProgram(program *WashProgram) error
+	DeleteProgram(programID string, deletedBy string) error
+}
+
+type WashProgramUsecase interface {
+	CreateProgram(newProgram *AddWashProgramDTO, userID string) (*WashProgram, error)
+	FindByMachineType(machineType MachineType) (*[]WashProgram, error)
+	UpdateProgram(program *UpdateWashProgramDTO, userID string) (*WashProgram, error)
+	DeleteProgram(programID string, userID string) error
+}
@@ -0,0 +1,48 @@
+package paymentgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+// FakeProvider never talks to a bank, it's for tests and local development.
+// PaidEvent builds the webhook a real provider would send once the customer paid.
+type FakeProvider struct {
+	webhookSecret string
+}
+
+func CreateFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{webhookSecret: webhookSecret}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateCharge(payment model.Payments, merchantID string) (*model.PaymentCharge, error) {
+	return &model.PaymentCharge{
+		PaymentID: payment.PaymentID,
+		Provider:  p.Name(),
+		Amount:    payment.Amount,
+		QRPayload: fmt.Sprintf("FAKE|%s|%.2f", payment.PaymentID, payment.Amount),
+		ExpiresAt: payment.DueDate,
+	}, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(body []byte, signature string) (*model.PaymentWebhookEvent, error) {
+	return verifyWebhook(p.webhookSecret, body, signature)
+}
+
+// PaidEvent returns a signed webhook body telling paymentID was paid amount baht
+func (p *FakeProvider) PaidEvent(eventID string, paymentID string, amount float64) ([]byte, string) {
+	body, _ := json.Marshal(model.PaymentWebhookEvent{
+		EventID:   eventID,
+		PaymentID: paymentID,
+		Status:    model.Paid,
+		Amount:    amount,
+		PaidAt:    time.Now().UTC(),
+	})
+	return body, Sign(p.webhookSecret, body)
+}
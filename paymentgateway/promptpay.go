@@ -0,0 +1,53 @@
+package paymentgateway
+
+import (
+	"errors"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/promptpay"
+)
+
+// promptPayProvider shows the customer a PromptPay QR for the exact amount,
+// the bank confirms the transfer through the signed webhook.
+// merchantID is used for branches that have no PromptPay id of their own.
+type promptPayProvider struct {
+	merchantID    string
+	webhookSecret string
+}
+
+func CreatePromptPayProvider(merchantID string, webhookSecret string) model.PaymentProvider {
+	return &promptPayProvider{
+		merchantID:    merchantID,
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (p *promptPayProvider) Name() string {
+	return "promptpay"
+}
+
+func (p *promptPayProvider) CreateCharge(payment model.Payments, merchantID string) (*model.PaymentCharge, error) {
+	if merchantID == "" {
+		merchantID = p.merchantID
+	}
+
+	if merchantID == "" {
+		return nil, errors.New("ERR: promptpay id is not configured")
+	}
+
+	payload, err := promptpay.Payload(merchantID, payment.Amount, payment.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PaymentCharge{
+		PaymentID: payment.PaymentID,
+		Provider:  p.Name(),
+		Amount:    payment.Amount,
+		QRPayload: payload,
+		ExpiresAt: payment.DueDate,
+	}, nil
+}
+
+func (p *promptPayProvider) VerifyWebhook(body []byte, signature string) (*model.PaymentWebhookEvent, error) {
+	return verifyWebhook(p.webhookSecret, body, signature)
+}
@@ -0,0 +1,19 @@
+package paymentgateway
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+// CreateProvider picks the payment provider from config, PromptPay unless
+// PAYMENT_PROVIDER=fake. The fake provider is refused in production.
+func CreateProvider(cfg *config.Config) model.PaymentProvider {
+	if cfg.PAYMENT_PROVIDER == "fake" {
+		if cfg.APP_ENV == "PRODUCTION" {
+			panic("fake payment provider cannot be used in production")
+		}
+		return CreateFakeProvider(cfg.PAYMENT_WEBHOOK_SECRET)
+	}
+
+	return CreatePromptPayProvider(cfg.PROMPTPAY_ID, cfg.PAYMENT_WEBHOOK_SECRET)
+}
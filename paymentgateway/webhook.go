@@ -0,0 +1,52 @@
+package paymentgateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the raw webhook body
+const SignatureHeader = "X-Zuck-Signature"
+
+// Sign returns the signature the provider is expected to send with body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhook checks the signature before anything in the body is trusted
+func verifyWebhook(secret string, body []byte, signature string) (*model.PaymentWebhookEvent, error) {
+	if secret == "" {
+		return nil, errors.New("ERR: payment webhook secret is not configured")
+	}
+
+	expected, err := hex.DecodeString(Sign(secret, body))
+	if err != nil {
+		return nil, err
+	}
+
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return nil, errors.New("ERR 401: invalid webhook signature")
+	}
+
+	event := new(model.PaymentWebhookEvent)
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, errors.New("ERR 400: invalid webhook body")
+	}
+
+	if event.EventID == "" || event.PaymentID == "" {
+		return nil, errors.New("ERR 400: event_id and payment_id are required")
+	}
+
+	if event.Status != model.Paid {
+		return nil, errors.New("ERR 400: only paid events are supported")
+	}
+
+	return event, nil
+}
@@ -0,0 +1,35 @@
+package paymentgateway
+
+import (
+	"testing"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	provider := CreateFakeProvider("secret")
+	body, signature := provider.PaidEvent("event-1", "payment-1", 150)
+
+	tests := []struct {
+		secret    string
+		body      []byte
+		signature string
+		expectErr bool
+	}{
+		{"secret", body, signature, false},                                                       // signed by the provider
+		{"other-secret", body, signature, true},                                                  // signed with another secret
+		{"secret", append([]byte(" "), body...), signature, true},                                // body changed after signing
+		{"secret", body, "not-hex", true},                                                        // garbage signature
+		{"secret", body, "", true},                                                               // no signature
+		{"", body, Sign("", body), true},                                                         // secret not configured
+		{"secret", []byte(`{"event_id":"e"}`), Sign("secret", []byte(`{"event_id":"e"}`)), true}, // signed but no payment
+	}
+
+	for i, test := range tests {
+		event, err := verifyWebhook(test.secret, test.body, test.signature)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Case %d: expected error %v, but got %v", i, test.expectErr, err)
+		}
+		if err == nil && (event.EventID != "event-1" || event.PaymentID != "payment-1" || event.Amount != 150) {
+			t.Errorf("Case %d: event was not parsed, got %+v", i, event)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package promptpay
+
+import (
+	"github.com/skip2/go-qrcode"
+)
+
+// PNG renders payload as a QR image of size x size pixels
+func PNG(payload string, size int) ([]byte, error) {
+	return qrcode.Encode(payload, qrcode.Medium, size)
+}
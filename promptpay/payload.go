@@ -0,0 +1,136 @@
+package promptpay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ids of the EMVCo merchant presented QR fields PromptPay uses
+const (
+	idPayloadFormat        = "00"
+	idPointOfInitiation    = "01"
+	idMerchantPromptPay    = "29"
+	idTransactionCurrency  = "53"
+	idTransactionAmount    = "54"
+	idCountryCode          = "58"
+	idAdditionalData       = "62"
+	idCRC                  = "63"
+	promptPayApplicationID = "A000000677010111"
+
+	subIDApplication = "00"
+	subIDMobile      = "01"
+	subIDTaxID       = "02"
+	subIDEWallet     = "03"
+	subIDReference   = "05" // reference label inside additional data
+
+	maxReferenceLength = 25
+
+	payloadFormatEMV = "01"
+	dynamicQR        = "12" // QR for one payment with the amount in it
+	currencyTHB      = "764"
+	countryTH        = "TH"
+)
+
+// field encodes one EMVCo TLV field, id + 2 digit length + value
+func field(id string, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// ValidateID checks merchantID can receive PromptPay payments
+func ValidateID(merchantID string) error {
+	_, err := merchantTarget(merchantID)
+	return err
+}
+
+// reference keeps the letters and digits of ref, cut to what the reference label can hold
+func reference(ref string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			return r
+		}
+		return -1
+	}, ref)
+
+	cleaned = strings.ToUpper(cleaned)
+	if len(cleaned) > maxReferenceLength {
+		cleaned = cleaned[:maxReferenceLength]
+	}
+	return cleaned
+}
+
+// merchantTarget turns a PromptPay id into its sub field,
+// 10 digit mobile numbers, 13 digit tax ids and 15 digit e-wallet ids are accepted
+func merchantTarget(merchantID string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return 'x'
+	}, merchantID)
+
+	if strings.Contains(digits, "x") {
+		return "", errors.New("ERR: promptpay id must only contain digits")
+	}
+
+	switch len(digits) {
+	case 10:
+		// 0812345678 -> 0066812345678
+		return field(subIDMobile, "0066"+digits[1:]), nil
+	case 13:
+		return field(subIDTaxID, digits), nil
+	case 15:
+		return field(subIDEWallet, digits), nil
+	}
+
+	return "", errors.New("ERR: promptpay id must be a mobile number, tax id or e-wallet id")
+}
+
+// Payload builds the QR payload that asks for amount baht to be paid to merchantID.
+// ref is shown to the payer as the reference of the transfer, e.g. the payment id,
+// only its first 25 letters and digits fit.
+func Payload(merchantID string, amount float64, ref string) (string, error) {
+	target, err := merchantTarget(merchantID)
+	if err != nil {
+		return "", err
+	}
+
+	if amount <= 0 {
+		return "", errors.New("ERR: amount must be more than 0")
+	}
+
+	payload := field(idPayloadFormat, payloadFormatEMV) +
+		field(idPointOfInitiation, dynamicQR) +
+		field(idMerchantPromptPay, field(subIDApplication, promptPayApplicationID)+target) +
+		field(idTransactionCurrency, currencyTHB) +
+		field(idTransactionAmount, fmt.Sprintf("%.2f", amount)) +
+		field(idCountryCode, countryTH)
+
+	if ref := reference(ref); ref != "" {
+		payload += field(idAdditionalData, field(subIDReference, ref))
+	}
+
+	payload += idCRC + "04"
+
+	return payload + fmt.Sprintf("%04X", CRC16(payload)), nil
+}
+
+// CRC16 is the CRC-16/CCITT-FALSE checksum EMVCo uses,
+// computed over the whole payload including the id and length of the CRC field
+func CRC16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
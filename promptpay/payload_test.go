@@ -0,0 +1,101 @@
+package promptpay
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint16
+	}{
+		{"123456789", 0x29B1},
+		{"", 0xFFFF},
+		{"A", 0xB915},
+	}
+
+	for _, test := range tests {
+		result := CRC16(test.input)
+		if result != test.expected {
+			t.Errorf("For input '%s', expected %04X, but got %04X", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestPayload(t *testing.T) {
+	tests := []struct {
+		merchantID string
+		amount     float64
+		ref        string
+		contains   []string
+	}{
+		{"0812345678", 50, "", []string{"29370016A000000677010111011300668123456785", "540550.00"}},
+		{"081-234-5678", 120.5, "", []string{"01130066812345678", "5406120.50"}},
+		{"1234567890123", 80, "", []string{"02131234567890123"}},
+		{"123456789012345", 80, "", []string{"0315123456789012345"}},
+		{"0812345678", 40, "b6a1-0c2d", []string{"62120508B6A10C2D"}},
+		{"0812345678", 40, "0123456789abcdef0123456789abcdef", []string{"622905250123456789ABCDEF012345678"}},
+	}
+
+	for _, test := range tests {
+		result, err := Payload(test.merchantID, test.amount, test.ref)
+		if err != nil {
+			t.Errorf("For input '%s', expected no error, but got %v", test.merchantID, err)
+			continue
+		}
+
+		if !strings.HasPrefix(result, "000201010212") {
+			t.Errorf("For input '%s', expected a dynamic EMVCo payload, but got %s", test.merchantID, result)
+		}
+
+		for _, part := range test.contains {
+			if !strings.Contains(result, part) {
+				t.Errorf("For input '%s', expected %s in the payload, but got %s", test.merchantID, part, result)
+			}
+		}
+
+		body, checksum := result[:len(result)-4], result[len(result)-4:]
+		if !strings.HasSuffix(body, "6304") || checksum != fmt.Sprintf("%04X", CRC16(body)) {
+			t.Errorf("For input '%s', expected a valid CRC, but got %s", test.merchantID, result)
+		}
+	}
+}
+
+func TestPayloadInvalid(t *testing.T) {
+	tests := []struct {
+		merchantID string
+		amount     float64
+	}{
+		{"", 50},
+		{"08123", 50},
+		{"08123456xx", 50},
+		{"0812345678", 0},
+		{"0812345678", -10},
+	}
+
+	for _, test := range tests {
+		_, err := Payload(test.merchantID, test.amount, "")
+		if err == nil {
+			t.Errorf("For input '%s' %v, expected an error, but got nil", test.merchantID, test.amount)
+		}
+	}
+}
+
+func TestPNG(t *testing.T) {
+	payload, err := Payload("0812345678", 50, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image, err := PNG(payload, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(image, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("For input '%s', expected a PNG image, but got %d bytes", payload, len(image))
+	}
+}
@@ -0,0 +1,34 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+// formatBaht prints an amount with thousands separators and two decimals, e.g. 1,234.50
+func formatBaht(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	text := fmt.Sprintf("%.2f", amount)
+	whole, decimals := text[:len(text)-3], text[len(text)-3:]
+
+	groups := []string{}
+	for len(whole) > 3 {
+		groups = append([]string{whole[len(whole)-3:]}, groups...)
+		whole = whole[:len(whole)-3]
+	}
+	groups = append([]string{whole}, groups...)
+
+	return sign + strings.Join(groups, ",") + decimals
+}
+
+// formatDatetime prints t in Thailand time
+func formatDatetime(t time.Time) string {
+	return t.In(model.ReportLocation).Format("02/01/2006 15:04")
+}
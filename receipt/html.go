@@ -0,0 +1,68 @@
+package receipt
+
+import (
+	"bytes"
+	"html/template"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"baht":     formatBaht,
+	"datetime": formatDatetime,
+}).Parse(`<!DOCTYPE html>
+<html lang="th">
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.ReceiptNumber}}</title>
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: 24px auto; color: #222; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+table { width: 100%; border-collapse: collapse; margin-top: 16px; }
+th, td { padding: 6px 4px; border-bottom: 1px solid #ddd; text-align: left; }
+.num { text-align: right; }
+.total td { font-weight: bold; border-bottom: none; }
+.muted { color: #666; font-size: 14px; }
+</style>
+</head>
+<body>
+<h1>Receipt / Tax Invoice</h1>
+<div class="muted">No. {{.ReceiptNumber}}</div>
+<p>
+<strong>{{.BranchName}}</strong><br>
+{{.BranchDetail}}<br>
+{{if .BranchTaxID}}Tax ID {{.BranchTaxID}}<br>{{end}}
+</p>
+<p>
+Customer: {{.CustomerName}}<br>
+{{if .CustomerEmail}}{{.CustomerEmail}}<br>{{end}}
+{{if .DeliveryAddress}}Delivery: {{.DeliveryAddress}}<br>{{end}}
+Order: {{.OrderHeaderID}}
+</p>
+<table>
+<tr><th>Item</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{baht .UnitPrice}}</td><td class="num">{{baht .Amount}}</td></tr>
+{{end}}<tr class="total"><td colspan="3" class="num">Price before VAT</td><td class="num">{{baht .Subtotal}}</td></tr>
+<tr class="total"><td colspan="3" class="num">VAT {{.VATRate}}%</td><td class="num">{{baht .VAT}}</td></tr>
+<tr class="total"><td colspan="3" class="num">Total</td><td class="num">{{baht .Total}}</td></tr>
+</table>
+<p class="muted">
+Paid by {{.PaymentMethod}} at {{datetime .PaidAt}}<br>
+Issued at {{datetime .IssuedAt}}
+</p>
+</body>
+</html>
+`))
+
+type htmlReceipt struct {
+	model.ReceiptDocument
+	VATRate int
+}
+
+// HTML renders the receipt as a printable page
+func HTML(document *model.ReceiptDocument) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	if err := htmlTemplate.Execute(buffer, htmlReceipt{ReceiptDocument: *document, VATRate: model.VATRate}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
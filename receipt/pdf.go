@@ -0,0 +1,97 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const fontFamily = "receipt"
+
+// PDF renders the receipt as an A4 page. fontPath is a TrueType font with the
+// glyphs the branch and customer names need, e.g. a Thai font. Without one the
+// built in Helvetica is used and characters it doesn't have print as ?.
+func PDF(document *model.ReceiptDocument, fontPath string) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Receipt "+document.ReceiptNumber, true)
+	pdf.AddPage()
+
+	family := "Helvetica"
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+	if fontPath != "" {
+		pdf.AddUTF8Font(fontFamily, "", fontPath)
+		pdf.AddUTF8Font(fontFamily, "B", fontPath)
+		family = fontFamily
+		text = func(s string) string { return s }
+	}
+
+	pdf.SetFont(family, "B", 16)
+	pdf.CellFormat(0, 8, text("Receipt / Tax Invoice"), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 10)
+	pdf.CellFormat(0, 6, text("No. "+document.ReceiptNumber), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(family, "B", 11)
+	pdf.CellFormat(0, 6, text(document.BranchName), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 10)
+	pdf.MultiCell(0, 5, text(document.BranchDetail), "", "L", false)
+	if document.BranchTaxID != nil {
+		pdf.CellFormat(0, 5, text("Tax ID "+*document.BranchTaxID), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.CellFormat(0, 5, text("Customer: "+document.CustomerName), "", 1, "L", false, 0, "")
+	if document.CustomerEmail != "" {
+		pdf.CellFormat(0, 5, text(document.CustomerEmail), "", 1, "L", false, 0, "")
+	}
+	if document.DeliveryAddress != nil {
+		pdf.MultiCell(0, 5, text("Delivery: "+*document.DeliveryAddress), "", "L", false)
+	}
+	pdf.CellFormat(0, 5, text("Order: "+document.OrderHeaderID), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{100, 20, 35, 35}
+
+	pdf.SetFont(family, "B", 10)
+	for i, title := range []string{"Item", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, text(title), "B", 0, align, false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(family, "", 10)
+	for _, line := range document.Lines {
+		pdf.CellFormat(widths[0], 7, text(line.Description), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 7, fmt.Sprint(line.Quantity), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 7, formatBaht(line.UnitPrice), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 7, formatBaht(line.Amount), "B", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont(family, "B", 10)
+	totals := [][2]string{
+		{"Price before VAT", formatBaht(document.Subtotal)},
+		{fmt.Sprintf("VAT %d%%", model.VATRate), formatBaht(document.VAT)},
+		{"Total", formatBaht(document.Total)},
+	}
+	for _, total := range totals {
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], 7, text(total[0]), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 7, total[1], "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont(family, "", 9)
+	pdf.CellFormat(0, 5, text("Paid by "+document.PaymentMethod+" at "+formatDatetime(document.PaidAt)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, text("Issued at "+formatDatetime(document.IssuedAt)), "", 1, "L", false, 0, "")
+
+	buffer := new(bytes.Buffer)
+	if err := pdf.Output(buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,78 @@
+package receipt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+func testDocument() *model.ReceiptDocument {
+	return &model.ReceiptDocument{
+		Receipt: model.Receipt{
+			BranchID:      "branch-1",
+			ReceiptNo:     12,
+			OrderHeaderID: "order-1",
+			Subtotal:      1121.50,
+			VAT:           78.50,
+			Total:         1200,
+			PaidAt:        time.Date(2024, 10, 1, 17, 30, 0, 0, time.UTC),
+			IssuedAt:      time.Date(2024, 10, 2, 1, 0, 0, 0, time.UTC),
+		},
+		ReceiptNumber: "RCbranch-1-00000012",
+		BranchName:    "Zuck <Siam>",
+		BranchDetail:  "Bangkok",
+		CustomerName:  "Somchai Jaidee",
+		PaymentMethod: "PromptPay",
+		Lines: []model.ReceiptLine{
+			{Description: "Washing 21 kg", Quantity: 8, UnitPrice: 150, Amount: 1200},
+		},
+	}
+}
+
+func TestFormatBaht(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		expected string
+	}{
+		{0, "0.00"},
+		{50, "50.00"},
+		{1234.5, "1,234.50"},
+		{1234567.891, "1,234,567.89"},
+		{-13, "-13.00"},
+		{999.999, "1,000.00"},
+	}
+
+	for _, test := range tests {
+		result := formatBaht(test.amount)
+		if result != test.expected {
+			t.Errorf("For input '%v', expected %s, but got %s", test.amount, test.expected, result)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	body, err := HTML(testDocument())
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	html := string(body)
+	for _, expected := range []string{"RCbranch-1-00000012", "Zuck &lt;Siam&gt;", "1,200.00", "78.50", "VAT 7%", "02/10/2024 00:30"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected receipt to contain %s", expected)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	body, err := PDF(testDocument(), "")
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	if !bytes.HasPrefix(body, []byte("%PDF-")) {
+		t.Errorf("expected a PDF, but got %q", body[:8])
+	}
+}
@@ -66,12 +66,12 @@ func (u *branchReopository) GetByBranchOwner(ownerUserID string) (*[]model.Branc
 }
 
 func (u *branchReopository) UpdateBranch(branch *model.Branch) error {
-	dbTx := u.db.Where("branch_id = ?", branch.BranchID).Updates(&model.Branch{BranchName: branch.BranchName, BranchDetail: branch.BranchDetail, BranchLat: branch.BranchLat, BranchLon: branch.BranchLon, OwnerUserID: branch.OwnerUserID})
+	dbTx := u.db.Where("branch_id = ?", branch.BranchID).Updates(&model.Branch{BranchName: branch.BranchName, BranchDetail: branch.BranchDetail, BranchLat: branch.BranchLat, BranchLon: branch.BranchLon, OwnerUserID: branch.OwnerUserID, PromptPayID: branch.PromptPayID, TaxID: branch.TaxID})
 	return dbTx.Error
 }
 
 func (u *branchReopository) ManagerUpdateBranch(branch *model.Branch) error {
-	dbTx := u.db.Where("branch_id = ?", branch.BranchID).Updates(&model.Branch{BranchName: branch.BranchName, BranchDetail: branch.BranchDetail, BranchLat: branch.BranchLat, BranchLon: branch.BranchLon})
+	dbTx := u.db.Where("branch_id = ?", branch.BranchID).Updates(&model.Branch{BranchName: branch.BranchName, BranchDetail: branch.BranchDetail, BranchLat: branch.BranchLat, BranchLon: branch.BranchLon, PromptPayID: branch.PromptPayID, TaxID: branch.TaxID})
 	return dbTx.Error
 }
 
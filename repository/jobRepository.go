@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type jobRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewJobRepository(db *platform.Postgres) model.JobRepository {
+	return &jobRepository{db: db}
+}
+
+func (u *jobRepository) WithTx(tx *platform.Postgres) model.JobRepository {
+	return &jobRepository{db: tx}
+}
+
+// Enqueue schedules the job, a job of the same type and key that is already
+// there is moved to the new run at instead. A running job is left alone,
+// its handler looks at the latest state before it finishes.
+func (u *jobRepository) Enqueue(job *model.Job) error {
+	dbTx := u.db.Exec(`
+	INSERT INTO "Jobs" (job_id, job_type, job_key, subject_id, event, subscriber, status, run_at, attempts, max_attempts, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0, $9, $10, $10)
+	ON CONFLICT (job_type, job_key) DO UPDATE
+	SET status = EXCLUDED.status, run_at = EXCLUDED.run_at, attempts = 0, last_error = NULL, locked_until = NULL, updated_at = EXCLUDED.updated_at
+	WHERE "Jobs".status <> 'Running';`,
+		job.JobID, job.JobType, job.JobKey, job.SubjectID, job.Event, job.Subscriber, model.JobPending, job.RunAt, job.MaxAttempts, time.Now().UTC())
+	return dbTx.Error
+}
+
+// EnqueueMissing schedules the pending payments and running baskets that have no job,
+// e.g. ones from before jobs existed or created somewhere that doesn't schedule its own.
+// Dead jobs stay dead until someone retries them.
+func (u *jobRepository) EnqueueMissing() (int64, error) {
+	dbTx := u.db.Exec(`
+	INSERT INTO "Jobs" (job_id, job_type, job_key, subject_id, status, run_at, attempts, max_attempts, created_at, updated_at)
+	SELECT gen_random_uuid(), $1, PM.payment_id, PM.payment_id, 'Pending', PM.due_date, 0, $3, $4, $4
+	FROM "Payments" AS PM
+	WHERE PM.payment_status = 'Pending' AND PM.deleted_at IS NULL
+	UNION ALL
+	SELECT gen_random_uuid(), $2, OD.order_basket_id, OD.order_basket_id, 'Pending', OD.finished_at, 0, $3, $4, $4
+	FROM "OrderDetails" AS OD
+	WHERE OD.order_status = 'Processing' AND OD.service_type IN ('Washing', 'Drying')
+		AND OD.finished_at IS NOT NULL AND OD.deleted_at IS NULL
+	ON CONFLICT (job_type, job_key) DO UPDATE
+	SET status = EXCLUDED.status, run_at = EXCLUDED.run_at, attempts = 0, last_error = NULL, locked_until = NULL, updated_at = EXCLUDED.updated_at
+	WHERE "Jobs".status = 'Done';`,
+		model.ExpirePaymentJob, model.CompleteBasketJob, model.JobMaxAttempts, time.Now().UTC())
+
+	if dbTx.Error != nil {
+		return 0, dbTx.Error
+	}
+
+	return dbTx.RowsAffected, nil
+}
+
+// ClaimDue takes up to limit jobs that are due, oldest first, and counts the attempt.
+// Jobs of a worker that didn't finish within its lease are taken over. Other
+// workers skip the rows being claimed, so a job is only handed out once.
+func (u *jobRepository) ClaimDue(limit int, lease time.Duration) (*[]model.Job, error) {
+	jobs := new([]model.Job)
+	now := time.Now().UTC()
+	dbTx := u.db.Raw(`
+	UPDATE "Jobs"
+	SET status = 'Running', attempts = attempts + 1, locked_until = $2, updated_at = $1
+	WHERE job_id IN (
+		SELECT job_id
+		FROM "Jobs"
+		WHERE (status = 'Pending' AND run_at <= $1) OR (status = 'Running' AND locked_until < $1)
+		ORDER BY run_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED)
+	RETURNING *;`, now, now.Add(lease), limit).Scan(jobs)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	return jobs, nil
+}
+
+// finish moves a job this worker still holds, the attempt it was claimed with
+// tells it apart from a worker that took the job over after the lease ran out
+func (u *jobRepository) finish(job *model.Job, values map[string]interface{}) error {
+	values["locked_until"] = nil
+	values["updated_at"] = time.Now().UTC()
+	dbTx := u.db.Model(&model.Job{}).
+		Where("job_id = ? AND status = ? AND attempts = ?", job.JobID, model.JobRunning, job.Attempts).
+		Updates(values)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return errors.New("ERR: job " + job.JobID + " was taken over by another worker")
+	}
+
+	return nil
+}
+
+func (u *jobRepository) Complete(job *model.Job) error {
+	return u.finish(job, map[string]interface{}{"status": model.JobDone})
+}
+
+// Reschedule puts the job back for later without counting this run as an attempt
+func (u *jobRepository) Reschedule(job *model.Job, runAt time.Time) error {
+	return u.finish(job, map[string]interface{}{
+		"status":   model.JobPending,
+		"run_at":   runAt.UTC(),
+		"attempts": job.Attempts - 1,
+	})
+}
+
+func (u *jobRepository) Retry(job *model.Job, runAt time.Time, lastError string) error {
+	return u.finish(job, map[string]interface{}{
+		"status":     model.JobPending,
+		"run_at":     runAt.UTC(),
+		"last_error": lastError,
+	})
+}
+
+func (u *jobRepository) Bury(job *model.Job, lastError string) error {
+	return u.finish(job, map[string]interface{}{
+		"status":     model.JobDead,
+		"last_error": lastError,
+	})
+}
+
+func (u *jobRepository) FindByJobID(jobID string) (*model.Job, error) {
+	job := new(model.Job)
+	dbTx := u.db.First(job, "job_id = ?", jobID)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return job, nil
+}
+
+func (u *jobRepository) GetDead() (*[]model.Job, error) {
+	jobs := new([]model.Job)
+	dbTx := u.db.Where("status = ?", model.JobDead).Order("updated_at DESC").Find(jobs)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return jobs, nil
+}
+
+// Revive gives a dead job a fresh set of attempts starting now, the last error is kept
+func (u *jobRepository) Revive(jobID string) error {
+	now := time.Now().UTC()
+	dbTx := u.db.Model(&model.Job{}).
+		Where("job_id = ? AND status = ?", jobID, model.JobDead).
+		Updates(map[string]interface{}{
+			"status":     model.JobPending,
+			"run_at":     now,
+			"attempts":   0,
+			"updated_at": now,
+		})
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
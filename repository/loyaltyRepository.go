@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+// loyaltyPointsUsage sums up the ledger of each user. Points are spent oldest
+// first and every earned point lives as long, so the earned points that passed
+// their expiry and weren't spent are expired_earned - consumed.
+const loyaltyPointsUsage = `
+	SELECT user_id,
+		COALESCE(SUM(points) FILTER (WHERE transaction_type = 'Earned' AND expires_at <= $1), 0) AS expired_earned,
+		COALESCE(-SUM(points) FILTER (WHERE transaction_type IN ('Redeemed', 'Expired')), 0)
+			- COALESCE(SUM(points) FILTER (WHERE transaction_type = 'Restored'), 0) AS consumed
+	FROM "LoyaltyTransactions"`
+
+type loyaltyRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewLoyaltyRepository(db *platform.Postgres) model.LoyaltyRepository {
+	return &loyaltyRepository{db: db}
+}
+
+func (u *loyaltyRepository) WithTx(tx *platform.Postgres) model.LoyaltyRepository {
+	return &loyaltyRepository{db: tx}
+}
+
+// LockAccount serializes changes to the points of a user until the surrounding transaction ends
+func (u *loyaltyRepository) LockAccount(userID string) error {
+	return u.db.Exec(`SELECT pg_advisory_xact_lock(hashtext('loyalty:' || $1));`, userID).Error
+}
+
+func (u *loyaltyRepository) GetBalance(userID string) (int64, error) {
+	var balance int64
+
+	result := u.db.Raw(`
+	SELECT COALESCE(SUM(points), 0)
+	FROM "LoyaltyTransactions"
+	WHERE user_id = $1;`, userID).Scan(&balance)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return balance, nil
+}
+
+// ExpirePoints writes off the points of the user that passed their expiry,
+// running it again right after changes nothing. Hold LockAccount first.
+func (u *loyaltyRepository) ExpirePoints(userID string) error {
+	now := time.Now().UTC()
+
+	return u.db.Exec(`
+	INSERT INTO "LoyaltyTransactions" (transaction_id, user_id, transaction_type, points, created_at)
+	SELECT gen_random_uuid(), L.user_id, 'Expired', -(L.expired_earned - L.consumed), $1
+	FROM (`+loyaltyPointsUsage+` WHERE user_id = $2 GROUP BY user_id) AS L
+	WHERE L.expired_earned > L.consumed;`, now, userID).Error
+}
+
+func (u *loyaltyRepository) FindUsersWithExpiredPoints() ([]string, error) {
+	var userIDs []string
+
+	result := u.db.Raw(`
+	SELECT L.user_id
+	FROM (`+loyaltyPointsUsage+` GROUP BY user_id) AS L
+	WHERE L.expired_earned > L.consumed;`, time.Now().UTC()).Scan(&userIDs)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return userIDs, nil
+}
+
+// CreditCompletedOrders gives points for every paid order that is completed and
+// didn't earn yet, refunded money doesn't earn. The unique index on the order
+// and type makes a second run a no-op, returns how many orders earned.
+func (u *loyaltyRepository) CreditCompletedOrders() (int64, error) {
+	now := time.Now().UTC()
+
+	result := u.db.Exec(`
+	INSERT INTO "LoyaltyTransactions" (transaction_id, user_id, transaction_type, points, order_header_id, expires_at, created_at)
+	SELECT gen_random_uuid(), OH.user_id, 'Earned', FLOOR((P.amount - COALESCE(R.refunded, 0)) / $1), OH.order_header_id, $2, $3
+	FROM "OrderHeaders" AS OH
+	INNER JOIN "Payments" AS P ON P.payment_id = OH.payment_id
+	LEFT JOIN LATERAL (
+		SELECT SUM(RF.amount) AS refunded
+		FROM "Refunds" AS RF
+		WHERE RF.payment_id = P.payment_id AND RF.refund_status <> 'Rejected'
+	) AS R ON TRUE`+orderStatusCounts+`
+	WHERE OH.deleted_at IS NULL AND P.payment_status = 'Paid'
+		AND (`+derivedOrderStatus+`) = 'Completed'
+		AND FLOOR((P.amount - COALESCE(R.refunded, 0)) / $1) > 0
+		AND NOT EXISTS (
+			SELECT 1 FROM "LoyaltyTransactions" AS LT
+			WHERE LT.order_header_id = OH.order_header_id AND LT.transaction_type = 'Earned'
+		)
+	ON CONFLICT (order_header_id, transaction_type) DO NOTHING;`, model.BahtPerPoint, now.Add(model.PointsLifetime), now)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (u *loyaltyRepository) CreateTransaction(transaction *model.LoyaltyTransaction) error {
+	return u.db.Create(transaction).Error
+}
+
+// RestoreRedeemed gives back the points a canceled order redeemed, at most once
+func (u *loyaltyRepository) RestoreRedeemed(orderHeaderID string) error {
+	return u.db.Exec(`
+	INSERT INTO "LoyaltyTransactions" (transaction_id, user_id, transaction_type, points, order_header_id, created_at)
+	SELECT gen_random_uuid(), user_id, 'Restored', -points, order_header_id, $1
+	FROM "LoyaltyTransactions"
+	WHERE order_header_id = $2 AND transaction_type = 'Redeemed'
+	ON CONFLICT (order_header_id, transaction_type) DO NOTHING;`, time.Now().UTC(), orderHeaderID).Error
+}
+
+func (u *loyaltyRepository) GetTransactions(userID string) (*[]model.LoyaltyTransaction, error) {
+	transactions := new([]model.LoyaltyTransaction)
+
+	result := u.db.Where("user_id = ?", userID).Order("created_at DESC").Find(transactions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type machineCommandRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewMachineCommandRepository(db *platform.Postgres) model.MachineCommandRepository {
+	return &machineCommandRepository{db: db}
+}
+
+func (u *machineCommandRepository) WithTx(tx *platform.Postgres) model.MachineCommandRepository {
+	return &machineCommandRepository{db: tx}
+}
+
+// CreateCommand stores the command once, false means a command with the same
+// idempotency key was issued before
+func (u *machineCommandRepository) CreateCommand(command *model.MachineCommand) (bool, error) {
+	dbTx := u.db.Clauses(clause.OnConflict{DoNothing: true}).Create(command)
+
+	if dbTx.Error != nil {
+		return false, dbTx.Error
+	}
+
+	return dbTx.RowsAffected == 1, nil
+}
+
+// LockCommand holds a row lock on the command until the surrounding transaction ends
+func (u *machineCommandRepository) LockCommand(commandID string) (*model.MachineCommand, error) {
+	command := new(model.MachineCommand)
+	dbTx := u.db.Raw(`
+	SELECT *
+	FROM "MachineCommands"
+	WHERE command_id = $1
+	FOR UPDATE;`, commandID).Scan(command)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return command, nil
+}
+
+// ClaimPending hands the pending commands of the machine to it, oldest first,
+// and counts the attempt
+func (u *machineCommandRepository) ClaimPending(machineSerial string) (*[]model.MachineCommand, error) {
+	commands := new([]model.MachineCommand)
+	now := time.Now().UTC()
+	dbTx := u.db.Raw(`
+	WITH claimed AS (
+		UPDATE "MachineCommands"
+		SET status = 'Sent', attempts = attempts + 1, sent_at = $2, updated_at = $2
+		WHERE machine_serial = $1 AND status = 'Pending' AND expires_at > $2
+		RETURNING *
+	)
+	SELECT * FROM claimed ORDER BY created_at ASC;`, machineSerial, now).Scan(commands)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	return commands, nil
+}
+
+func (u *machineCommandRepository) SaveCommand(command *model.MachineCommand) error {
+	command.UpdatedAt = time.Now().UTC()
+	dbTx := u.db.Save(command)
+	return dbTx.Error
+}
+
+func (u *machineCommandRepository) GetByMachineSerial(machineSerial string) (*[]model.MachineCommand, error) {
+	commands := new([]model.MachineCommand)
+	dbTx := u.db.Where("machine_serial = ?", machineSerial).Order("created_at DESC").Find(commands)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return commands, nil
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"fmt"
+	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type machineReportsRepository struct {
@@ -16,6 +18,10 @@ func CreateNewMachineReportRepository(db *platform.Postgres) model.MachineReport
 	return &machineReportsRepository{db: db}
 }
 
+func (u *machineReportsRepository) WithTx(tx *platform.Postgres) model.MachineReportsRepository {
+	return &machineReportsRepository{db: tx}
+}
+
 func (u *machineReportsRepository) CreateMachineReport(newReport *model.MachineReports) error {
 	dbTx := u.db.Create(newReport)
 	return dbTx.Error
@@ -47,7 +53,7 @@ func (u *machineReportsRepository) FindMachineReportByBranch(branchID string, us
 	var dbTx *gorm.DB
 	if userRole == "SuperAdmin" {
 		dbTx = u.db.DB.Raw(
-			`SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
+			`SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"MachineReports".preventive,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
 			FROM "MachineReports"
 			INNER JOIN "Machines" ON "Machines".machine_serial = "MachineReports".machine_serial
 			WHERE "MachineReports".deleted_at IS NULL AND "Machines".branch_id = ?
@@ -55,7 +61,7 @@ func (u *machineReportsRepository) FindMachineReportByBranch(branchID string, us
 			branchID).Scan(machineReportLists)
 	} else {
 		dbTx = u.db.Raw(
-			`SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
+			`SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"MachineReports".preventive,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
 			FROM "MachineReports"
 			INNER JOIN "Machines" ON "Machines".machine_serial = "MachineReports".machine_serial
 			WHERE "MachineReports".deleted_at IS NULL AND "Machines".branch_id IN (
@@ -82,7 +88,7 @@ func (u *machineReportsRepository) FindMachineReportByBranch(branchID string, us
 func (u *machineReportsRepository) GetAll() (*[]model.MachineReports, error) {
 	machineReportLists := new([]model.MachineReports)
 	dbTx := u.db.Raw(`
-	SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
+	SELECT "MachineReports".report_id,"MachineReports".user_id,"MachineReports".report_desc,"MachineReports".machine_serial,"MachineReports".report_status,"MachineReports".preventive,"Machines".branch_id,"MachineReports".created_at,"MachineReports".deleted_at
 	FROM "MachineReports"
 	INNER JOIN "Machines" ON "Machines".machine_serial = "MachineReports".machine_serial
 	ORDER BY "MachineReports".created_at ASC
@@ -96,8 +102,17 @@ func (u *machineReportsRepository) GetAll() (*[]model.MachineReports, error) {
 	return machineReportLists, nil
 }
 
+// UpdateMachineReportStatus also stamps when the report was accepted or closed
 func (u *machineReportsRepository) UpdateMachineReportStatus(updateReport model.UpdateMachineReportStatusDTO) error {
-	dbTx := u.db.Table("MachineReports").Where("report_id = ?", updateReport.ReportID).Update("report_status", updateReport.ReportStatus)
+	updates := map[string]interface{}{"report_status": updateReport.ReportStatus}
+	switch updateReport.ReportStatus {
+	case model.ReportInProgress:
+		updates["accepted_at"] = time.Now().UTC()
+	case model.ReportFixed, model.ReportCanceled:
+		updates["closed_at"] = time.Now().UTC()
+	}
+
+	dbTx := u.db.Table("MachineReports").Where("report_id = ?", updateReport.ReportID).Updates(updates)
 	if dbTx.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
@@ -112,3 +127,65 @@ func (u *machineReportsRepository) DeleteMachineReport(reportID string) error {
 	}
 	return dbTx.Error
 }
+
+// CreateOpenPreventive opens the preventive report unless the machine has one
+// open already, the unique index of open preventive reports decides it
+// so two sweeps can't open the same maintenance twice.
+func (u *machineReportsRepository) CreateOpenPreventive(newReport *model.MachineReports) (bool, error) {
+	dbTx := u.db.Clauses(clause.OnConflict{DoNothing: true}).Create(newReport)
+	if dbTx.Error != nil {
+		return false, dbTx.Error
+	}
+	return dbTx.RowsAffected == 1, nil
+}
+
+// CountAccepted is how many reports keep the machine out of service
+func (u *machineReportsRepository) CountAccepted(machineSerial string) (int64, error) {
+	var count int64
+	dbTx := u.db.Model(&model.MachineReports{}).
+		Where("machine_serial = ? AND report_status = ?", machineSerial, model.ReportInProgress).
+		Count(&count)
+	return count, dbTx.Error
+}
+
+// MarkDeactivatedMachine records that accepting the report took its machine out of service
+func (u *machineReportsRepository) MarkDeactivatedMachine(reportID string) error {
+	return u.db.Model(&model.MachineReports{}).
+		Where("report_id = ?", reportID).
+		Update("deactivated_machine", true).Error
+}
+
+// HandOverDeactivation passes keeping the machine out of service on to the report
+// accepted first of those still accepted
+func (u *machineReportsRepository) HandOverDeactivation(machineSerial string) error {
+	return u.db.Exec(`
+	UPDATE "MachineReports"
+	SET deactivated_machine = TRUE
+	WHERE report_id = (
+		SELECT report_id
+		FROM "MachineReports"
+		WHERE machine_serial = $1 AND report_status = 'In Progress' AND deleted_at IS NULL
+		ORDER BY accepted_at ASC NULLS FIRST, created_at ASC
+		LIMIT 1
+	);`, machineSerial).Error
+}
+
+// GetDowntime returns the accepted reports of machines of the branch that kept
+// their machine out of service at some time between from and to, a deleted
+// report stopped counting when it was deleted
+func (u *machineReportsRepository) GetDowntime(branchID string, from time.Time, to time.Time) (*[]model.MachineDowntime, error) {
+	downtime := new([]model.MachineDowntime)
+	dbTx := u.db.Raw(`
+	SELECT R.machine_serial, R.report_id, R.accepted_at, COALESCE(R.closed_at, R.deleted_at) AS closed_at
+	FROM "MachineReports" AS R
+	INNER JOIN "Machines" AS M ON M.machine_serial = R.machine_serial
+	WHERE M.branch_id = $1 AND R.accepted_at IS NOT NULL AND R.accepted_at < $3
+		AND (COALESCE(R.closed_at, R.deleted_at) IS NULL OR COALESCE(R.closed_at, R.deleted_at) > $2)
+	ORDER BY R.accepted_at ASC;`, branchID, from, to).Scan(downtime)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	return downtime, nil
+}
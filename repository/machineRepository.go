@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -18,7 +20,10 @@ type MachineRepository interface {
 	GetAvailableMachine(branchID string) (*[]model.MachineInBranch, error)
 	MachineWangMaiWa(machineSerial string) (bool, error)
 	GetWithTime(machineSerial string) (*model.MachineWithTime, error)
-	//GetMachineToAssign(branchID string, machineType string, weight int, numberRequest int) (*[]model.MachineInBranch, error)
+	ReserveMachine(basket model.BasketToAssign, machineType model.MachineType) (*model.Machine, error)
+	LockMachine(machineSerial string) (*model.Machine, error)
+	GetCycles(branchID string, from time.Time, to time.Time) (*[]model.MachineCycle, error)
+	WithTx(tx *platform.Postgres) MachineRepository
 }
 
 type machineRepository struct {
@@ -29,6 +34,10 @@ func CreateMachineRepository(db *platform.Postgres) MachineRepository {
 	return &machineRepository{db: db}
 }
 
+func (u *machineRepository) WithTx(tx *platform.Postgres) MachineRepository {
+	return &machineRepository{db: tx}
+}
+
 func (u *machineRepository) GetAll() (*[]model.Machine, error) {
 	machineList := new([]model.Machine)
 	result := u.db.Find(machineList)
@@ -141,15 +150,27 @@ func (u *machineRepository) GetByBranchID(branchID string) (*[]model.Machine, er
 func (u *machineRepository) GetAvailableMachine(branchID string) (*[]model.MachineInBranch, error) {
 	machines := new([]model.MachineInBranch)
 
+	// a running machine knows better when it's done than the basket in it,
+	// a basket still waiting to go in runs its whole program from now
+	now := time.Now().UTC()
 	result := u.db.Raw(`
-		SELECT m.*, (
+		SELECT m.*, COALESCE(
+			CASE WHEN ms.running THEN ms.reported_at + make_interval(secs => ms.cycle_remaining) END, (
 			SELECT od.finished_at
 			FROM "OrderDetails" od
 			WHERE od.machine_serial = m.machine_serial AND od.order_status = 'Processing'
 			LIMIT 1
-			) AS finished_at
+			), (
+			SELECT $3::timestamptz + make_interval(mins => COALESCE(wp.duration_minutes, $4))
+			FROM "OrderDetails" od
+			LEFT JOIN "WashPrograms" wp ON wp.program_id = od.program_id
+			WHERE od.machine_serial = m.machine_serial AND od.order_status = 'Waiting' AND od.deleted_at IS NULL
+			LIMIT 1
+			)) AS finished_at,
+			ms.door_open, ms.running, ms.error_code, ms.reported_at
 		FROM "Machines" m
-		WHERE branch_id = $1`, branchID).
+		LEFT JOIN "MachineStates" ms ON ms.machine_serial = m.machine_serial AND ms.reported_at > $2
+		WHERE branch_id = $1`, branchID, now.Add(-model.MachineStateStale), now, model.DefaultCycleMinutes).
 		Scan(&machines)
 
 	if result.Error != nil {
@@ -162,15 +183,24 @@ func (u *machineRepository) GetAvailableMachine(branchID string) (*[]model.Machi
 func (u *machineRepository) GetWithTime(machineSerial string) (*model.MachineWithTime, error) {
 	machine := new(model.MachineWithTime)
 
+	now := time.Now().UTC()
 	result := u.db.Raw(`
-		SELECT m.*, (
+		SELECT m.*, COALESCE(
+			CASE WHEN ms.running THEN ms.reported_at + make_interval(secs => ms.cycle_remaining) END, (
 			SELECT od.finished_at
 			FROM "OrderDetails" od
 			WHERE od.machine_serial = m.machine_serial AND od.order_status = 'Processing'
 			LIMIT 1
-			) AS finished_at
+			), (
+			SELECT $3::timestamptz + make_interval(mins => COALESCE(wp.duration_minutes, $4))
+			FROM "OrderDetails" od
+			LEFT JOIN "WashPrograms" wp ON wp.program_id = od.program_id
+			WHERE od.machine_serial = m.machine_serial AND od.order_status = 'Waiting' AND od.deleted_at IS NULL
+			LIMIT 1
+			)) AS finished_at
 		FROM "Machines" m
-		WHERE machine_serial = $1`, machineSerial).
+		LEFT JOIN "MachineStates" ms ON ms.machine_serial = m.machine_serial AND ms.reported_at > $2
+		WHERE m.machine_serial = $1`, machineSerial, now.Add(-model.MachineStateStale), now, model.DefaultCycleMinutes).
 		Scan(&machine)
 
 	if result.Error != nil {
@@ -180,71 +210,159 @@ func (u *machineRepository) GetWithTime(machineSerial string) (*model.MachineWit
 	return machine, nil
 }
 
+// MachineWangMaiWa is true when the machine is in service, no basket has it
+// and it didn't report lately that it's running or broken
 func (u *machineRepository) MachineWangMaiWa(machineSerial string) (bool, error) {
-	machines := new([]model.MachineInBranch)
+	var busy bool
 
 	result := u.db.Raw(`
-	SELECT order_header_id, order_basket_id, created_at, updated_at, finished_at
-	FROM "OrderDetails"
-	WHERE machine_serial = $1 AND order_status = 'Processing'`, machineSerial).Scan(&machines)
+	SELECT EXISTS (
+		SELECT 1
+		FROM "OrderDetails"
+		WHERE machine_serial = $1 AND deleted_at IS NULL AND (order_status = 'Processing' OR order_status = 'Waiting')
+	) OR EXISTS (
+		SELECT 1
+		FROM "MachineStates"
+		WHERE machine_serial = $1 AND reported_at > $2 AND (running OR error_code IS NOT NULL)
+	) OR NOT EXISTS (
+		SELECT 1
+		FROM "Machines"
+		WHERE machine_serial = $1 AND is_active
+	)`, machineSerial, time.Now().UTC().Add(-model.MachineStateStale)).Scan(&busy)
 
 	if result.Error != nil {
 		return false, result.Error
 	}
 
-	if result.RowsAffected > 0 {
-		return false, nil
-	}
-
-	return true, nil
-
-}
-
-// func (u *machineRepository) GetMachineToAssign(branchID string, machineType string, weight int, numberRequest int) (*[]model.MachineInBranch, error) {
-// 	machines := new([]model.MachineInBranch)
-// 	// dbTx := u.db.Raw(`
-// 	// 	SELECT DISTINCT sub.machine_serial
-// 	// 	FROM (SELECT M.machine_serial, OD.finished_at, M.weight, M.machine_label, M.machine_type,
-// 	// 							CASE
-// 	// 											WHEN OD.order_status = 'Processing' THEN FALSE ELSE TRUE
-// 	// 							END AS is_available
-// 	// 	FROM "Machines" AS M LEFT JOIN "OrderDetails" AS OD ON M.machine_serial = OD.machine_serial
-// 	// 	WHERE M.branch_id = ? AND M.is_active = TRUE AND M.deleted_at IS NULL
-// 	// 	ORDER BY OD.created_at DESC) AS sub
-// 	// 	WHERE sub.is_available = TRUE AND sub.machine_type = ? AND sub.weight = ?
-// 	// 	LIMIT ?;
-// 	// `, branchID, machineType, weight, numberRequest).Scan(machines)
-
-// 	dbTx := u.db.Raw(`
-// 	SELECT  DISTINCT sub.machine_serial,sub.weight,sub.is_available
-// 	FROM (
-// 		SELECT DISTINCT M.machine_serial, OD.finished_at, M.weight, M.machine_label, M.machine_type,
-// 		CASE
-// 						WHEN OD.order_status = 'Processing' OR OD.order_status = 'Waiting' THEN FALSE ELSE TRUE
-// 		END AS is_available,OD.created_at
-// 		FROM "Machines" AS M LEFT JOIN "OrderDetails" AS OD ON M.machine_serial = OD.machine_serial
-// 		WHERE M.branch_id = ? AND M.is_active = TRUE AND M.deleted_at IS NULL AND M.machine_type = ? AND M.weight = ?
-// 		ORDER BY OD.created_at DESC
-// 		) AS sub
-// 	WHERE sub.is_available AND sub.machine_serial NOT IN (
-// 		SELECT  DISTINCT sub_two.machine_serial
-// 		FROM (
-// 			SELECT DISTINCT M.machine_serial, OD.finished_at, M.weight, M.machine_label, M.machine_type,
-// 			CASE
-// 							WHEN OD.order_status = 'Processing' OR OD.order_status = 'Waiting' THEN FALSE ELSE TRUE
-// 			END AS is_available,OD.created_at
-// 			FROM "Machines" AS M LEFT JOIN "OrderDetails" AS OD ON M.machine_serial = OD.machine_serial
-// 			WHERE M.branch_id = ? AND M.is_active = TRUE AND M.deleted_at IS NULL AND M.machine_type = ? AND M.weight = ?
-// 			ORDER BY OD.created_at DESC) AS sub_two
-// 		WHERE NOT sub_two.is_available)
-// 		LIMIT ?;
-// 	`, branchID, machineType, weight, branchID, machineType, weight, numberRequest).Scan(machines)
-
-// 	if dbTx.Error != nil {
-// 		return nil, dbTx.Error
-// 	}
-// 	if dbTx.RowsAffected != int64(numberRequest) {
-// 		return nil, errors.New("ERR: not enough machine")
-// 	}
-// 	return machines, nil
-// }
+	return !busy, nil
+}
+
+// ReserveMachine picks a free active machine that fits the basket and writes it
+// onto the basket in one transaction. The machine row stays locked until commit
+// so concurrent callers skip it instead of reserving the same one twice.
+func (u *machineRepository) ReserveMachine(basket model.BasketToAssign, machineType model.MachineType) (*model.Machine, error) {
+	machine := new(model.Machine)
+
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Raw(`
+		SELECT M.*
+		FROM "Machines" AS M
+		WHERE M.branch_id = $1 AND M.machine_type = $2 AND M.weight = $3 AND M.is_active = TRUE AND M.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1
+				FROM "OrderDetails" AS OD
+				WHERE OD.machine_serial = M.machine_serial AND OD.deleted_at IS NULL
+					AND (OD.order_status = 'Waiting' OR OD.order_status = 'Processing'))
+			AND NOT EXISTS (
+				SELECT 1
+				FROM "MachineStates" AS MS
+				WHERE MS.machine_serial = M.machine_serial AND MS.reported_at > $4
+					AND (MS.running OR MS.error_code IS NOT NULL))
+		ORDER BY M.machine_label ASC
+		LIMIT 1
+		FOR UPDATE OF M SKIP LOCKED;`, basket.BranchID, machineType, basket.Weight, time.Now().UTC().Add(-model.MachineStateStale)).Scan(machine)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// basket could be assigned by staff while we were looking for a machine
+		result = tx.Exec(`
+		UPDATE "OrderDetails"
+		SET machine_serial = $1, updated_at = $2
+		WHERE order_basket_id = $3 AND machine_serial IS NULL AND order_status = 'Waiting';`,
+			machine.MachineSerial, time.Now().UTC(), basket.OrderBasketID)
+
+		if isMachineInUseViolation(result.Error) {
+			return &model.MachineBusyError{MachineSerial: machine.MachineSerial}
+		}
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		event := model.OrderEvent{
+			EventID:       uuid.New().String(),
+			OrderHeaderID: basket.OrderHeaderID,
+			OrderBasketID: &basket.OrderBasketID,
+			EventType:     model.MachineAssigned,
+			MachineSerial: &machine.MachineSerial,
+			ActorID:       model.SystemActor,
+			CreatedAt:     time.Now().UTC(),
+		}
+
+		return tx.Create(&event).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return machine, nil
+}
+
+// LockMachine holds a row lock on the machine until the surrounding transaction ends,
+// so only one caller at a time can check and take the machine.
+func (u *machineRepository) LockMachine(machineSerial string) (*model.Machine, error) {
+	machine := new(model.Machine)
+
+	result := u.db.Raw(`
+	SELECT *
+	FROM "Machines"
+	WHERE machine_serial = $1 AND deleted_at IS NULL
+	FOR UPDATE;`, machineSerial).Scan(machine)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return machine, nil
+}
+
+// GetCycles returns the baskets machines of the branch started between from and to,
+// a basket is priced from the line of its order it was charged on
+func (u *machineRepository) GetCycles(branchID string, from time.Time, to time.Time) (*[]model.MachineCycle, error) {
+	cycles := new([]model.MachineCycle)
+
+	result := u.db.Raw(`
+	SELECT * FROM (
+		SELECT OD.machine_serial, OD.order_basket_id, OD.finished_at,
+			COALESCE(OD.started_at, OD.finished_at - make_interval(mins => COALESCE(WP.duration_minutes, $4))) AS started_at,
+			CASE WHEN PM.payment_status = 'Paid' THEN COALESCE(PL.unit_price, 0) ELSE 0 END AS revenue
+		FROM "OrderDetails" AS OD
+		INNER JOIN "Machines" AS M ON M.machine_serial = OD.machine_serial
+		INNER JOIN "OrderHeaders" AS OH ON OH.order_header_id = OD.order_header_id
+		LEFT JOIN "Payments" AS PM ON PM.payment_id = OH.payment_id
+		LEFT JOIN "WashPrograms" AS WP ON WP.program_id = OD.program_id
+		LEFT JOIN LATERAL (
+			SELECT L.unit_price
+			FROM "OrderPriceLines" AS L
+			WHERE L.order_header_id = OD.order_header_id AND L.service_type = OD.service_type AND L.weight = OD.weight
+				AND L.program_id IS NOT DISTINCT FROM OD.program_id AND L.promo_code_id IS NULL
+			LIMIT 1
+		) AS PL ON TRUE
+		WHERE M.branch_id = $1 AND OD.finished_at IS NOT NULL AND OD.deleted_at IS NULL
+			AND (OD.order_status = 'Processing' OR OD.order_status = 'Completed')
+			AND (OD.service_type = 'Washing' OR OD.service_type = 'Drying')
+	) AS C
+	WHERE C.started_at >= $2 AND C.started_at < $3
+	ORDER BY C.started_at ASC;`, branchID, from, to, model.DefaultCycleMinutes).Scan(cycles)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return cycles, nil
+}
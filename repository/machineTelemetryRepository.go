@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type machineTelemetryRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewMachineTelemetryRepository(db *platform.Postgres) model.MachineTelemetryRepository {
+	return &machineTelemetryRepository{db: db}
+}
+
+func (u *machineTelemetryRepository) WithTx(tx *platform.Postgres) model.MachineTelemetryRepository {
+	return &machineTelemetryRepository{db: tx}
+}
+
+// SetDeviceToken replaces the credential of the machine, the old token stops working
+func (u *machineTelemetryRepository) SetDeviceToken(machineSerial string, tokenHash string) error {
+	dbTx := u.db.Model(&model.Machine{}).
+		Where("machine_serial = ?", machineSerial).
+		Update("device_token_hash", tokenHash)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// LockState returns the last report of the machine and holds it until the surrounding
+// transaction ends, so reports of the same machine are handled one at a time.
+// A machine that never reported has no state.
+func (u *machineTelemetryRepository) LockState(machineSerial string) (*model.MachineState, error) {
+	state := new(model.MachineState)
+	dbTx := u.db.Raw(`
+	SELECT *
+	FROM "MachineStates"
+	WHERE machine_serial = $1
+	FOR UPDATE;`, machineSerial).Scan(state)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return state, nil
+}
+
+func (u *machineTelemetryRepository) SaveState(state *model.MachineState) error {
+	dbTx := u.db.Save(state)
+	return dbTx.Error
+}
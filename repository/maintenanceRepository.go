@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type maintenanceRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewMaintenanceRepository(db *platform.Postgres) model.MaintenanceRepository {
+	return &maintenanceRepository{db: db}
+}
+
+func (u *maintenanceRepository) WithTx(tx *platform.Postgres) model.MaintenanceRepository {
+	return &maintenanceRepository{db: tx}
+}
+
+// SavePlan sets the schedule of the machine, a machine that had no plan
+// starts counting from plan.LastServiceAt
+func (u *maintenanceRepository) SavePlan(plan *model.MaintenancePlan) error {
+	dbTx := u.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "machine_serial"}},
+		DoUpdates: clause.AssignmentColumns([]string{"every_cycles", "every_days", "updated_at", "updated_by"}),
+	}).Create(plan)
+
+	return dbTx.Error
+}
+
+// maintenanceStatusQuery counts the baskets every machine completed since it was last serviced
+const maintenanceStatusQuery = `
+	SELECT P.*, M.branch_id, (
+		SELECT COUNT(*)
+		FROM "OrderDetails" AS OD
+		WHERE OD.machine_serial = P.machine_serial AND OD.order_status = 'Completed'
+			AND OD.finished_at > P.last_service_at AND OD.deleted_at IS NULL
+		) AS cycles
+	FROM "MaintenancePlans" AS P
+	INNER JOIN "Machines" AS M ON M.machine_serial = P.machine_serial AND M.deleted_at IS NULL`
+
+func (u *maintenanceRepository) FindPlan(machineSerial string) (*model.MaintenanceStatus, error) {
+	status := new(model.MaintenanceStatus)
+	dbTx := u.db.Raw(maintenanceStatusQuery+`
+	WHERE P.machine_serial = $1;`, machineSerial).Scan(status)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return status, nil
+}
+
+func (u *maintenanceRepository) GetPlans() (*[]model.MaintenanceStatus, error) {
+	plans := new([]model.MaintenanceStatus)
+	dbTx := u.db.Raw(maintenanceStatusQuery + `;`).Scan(plans)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	return plans, nil
+}
+
+// MarkServiced restarts the count of the machine, nothing happens to a machine without a plan
+func (u *maintenanceRepository) MarkServiced(machineSerial string, servicedAt time.Time) error {
+	dbTx := u.db.Model(&model.MaintenancePlan{}).
+		Where("machine_serial = ?", machineSerial).
+		Update("last_service_at", servicedAt)
+
+	return dbTx.Error
+}
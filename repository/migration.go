@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"log"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+// Migrate applies the schema pieces the application relies on.
+// Every statement must be safe to run again on each start up.
+func Migrate(db *platform.Postgres) error {
+	err := db.AutoMigrate(
+		&model.ServicePrice{},
+		&model.OrderPriceLine{},
+		&model.OrderEvent{},
+		&model.Refund{},
+		&model.Notification{},
+		&model.PaymentWebhookEvent{},
+		&model.Wallet{},
+		&model.WalletTransaction{},
+		&model.PromoCode{},
+		&model.PromoRedemption{},
+		&model.LoyaltyTransaction{},
+		&model.PaymentPolicy{},
+		&model.Receipt{},
+		&model.ReceiptSequence{},
+		&model.Job{},
+		&model.MachineState{},
+		&model.MachineCommand{},
+		&model.WashProgram{},
+		&model.MaintenancePlan{},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := releaseDoubleBookedMachines(db); err != nil {
+		return err
+	}
+
+	statements := []string{
+		// a machine can only be reserved by or running one basket at a time
+		`CREATE UNIQUE INDEX IF NOT EXISTS "` + machineInUseIndex + `"
+		ON "OrderDetails" (machine_serial)
+		WHERE machine_serial IS NOT NULL AND deleted_at IS NULL AND order_status IN ('Waiting', 'Processing');`,
+		// money of PromptPay payments goes to the branch the order is placed at
+		`ALTER TABLE "Branches" ADD COLUMN IF NOT EXISTS promptpay_id TEXT;`,
+		// payments remember the policy their due date came from, see PaymentPolicy
+		`ALTER TABLE "Payments" ADD COLUMN IF NOT EXISTS policy_id TEXT;`,
+		`ALTER TABLE "Payments" ADD COLUMN IF NOT EXISTS extension_count INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "PaymentPolicies_branch_type_idx" ON "PaymentPolicies" (COALESCE(branch_id, ''), zuck_onsite);`,
+		// receipts print when a payment was paid, older payments take it from how they were paid
+		`ALTER TABLE "Payments" ADD COLUMN IF NOT EXISTS paid_at TIMESTAMPTZ;`,
+		`UPDATE "Payments" AS PM
+		SET paid_at = COALESCE(
+			(SELECT MIN(E.paid_at) FROM "PaymentWebhookEvents" AS E WHERE E.payment_id = PM.payment_id),
+			(SELECT MIN(WT.created_at) FROM "WalletTransactions" AS WT WHERE WT.payment_id = PM.payment_id AND WT.transaction_type = 'Debit'),
+			PM.created_at)
+		WHERE PM.payment_status = 'Paid' AND PM.paid_at IS NULL;`,
+		// receipts print the tax id of the branch when it has one
+		`ALTER TABLE "Branches" ADD COLUMN IF NOT EXISTS tax_id TEXT;`,
+		// machines report their state with their own token, see MachineCredential
+		`ALTER TABLE "Machines" ADD COLUMN IF NOT EXISTS device_token_hash TEXT;`,
+		// the program the customer picked for the basket, see WashProgram
+		`ALTER TABLE "OrderDetails" ADD COLUMN IF NOT EXISTS program_id TEXT;`,
+		// when the machine of the basket was started, see MachineCycle
+		`ALTER TABLE "OrderDetails" ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ;`,
+		// reports opened by the maintenance plan of the machine, see MaintenancePlan
+		`ALTER TABLE "MachineReports" ADD COLUMN IF NOT EXISTS preventive BOOLEAN NOT NULL DEFAULT FALSE;`,
+		// a machine has at most one open preventive report, see MachineReportsRepository.CreateOpenPreventive
+		`CREATE UNIQUE INDEX IF NOT EXISTS "MachineReports_open_preventive_idx"
+		ON "MachineReports" (machine_serial)
+		WHERE preventive AND deleted_at IS NULL AND report_status IN ('Pending', 'In Progress');`,
+		// when a report took its machine out of service and when it gave it back, see MachineDowntime
+		`ALTER TABLE "MachineReports" ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMPTZ;`,
+		`ALTER TABLE "MachineReports" ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;`,
+		// whether accepting the report took its machine out of service, see MachineReports.DeactivatedMachine
+		`ALTER TABLE "MachineReports" ADD COLUMN IF NOT EXISTS deactivated_machine BOOLEAN NOT NULL DEFAULT FALSE;`,
+		// order listings page through these, see OrderHeaderRepository.GetPage
+		`CREATE INDEX IF NOT EXISTS "OrderHeaders_branch_created_idx" ON "OrderHeaders" (branch_id, created_at, order_header_id);`,
+		`CREATE INDEX IF NOT EXISTS "OrderHeaders_user_created_idx" ON "OrderHeaders" (user_id, created_at, order_header_id);`,
+		`CREATE INDEX IF NOT EXISTS "OrderDetails_order_header_idx" ON "OrderDetails" (order_header_id);`,
+		// the wallet ledger is append only, balances are corrected with a new transaction
+		`CREATE OR REPLACE FUNCTION wallet_transactions_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'WalletTransactions rows can not be changed';
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`DROP TRIGGER IF EXISTS "WalletTransactions_append_only" ON "WalletTransactions";`,
+		`CREATE TRIGGER "WalletTransactions_append_only"
+		BEFORE UPDATE OR DELETE ON "WalletTransactions"
+		FOR EACH ROW EXECUTE FUNCTION wallet_transactions_append_only();`,
+		// default catalog, same prices the service used to have as constants
+		`INSERT INTO "ServicePrices" (price_id, branch_id, service_type, weight, price, effective_from, created_at, created_by, updated_at, updated_by)
+		SELECT gen_random_uuid(), NULL, v.service_type, v.weight, v.price, 'epoch', NOW(), 'system', NOW(), 'system'
+		FROM (VALUES
+			('Washing', 7, 50), ('Washing', 14, 100), ('Washing', 21, 150),
+			('Drying', 7, 50), ('Drying', 14, 100), ('Drying', 21, 150),
+			('Pickup', 0, 20), ('Delivery', 0, 20), ('Agents', 0, 20)
+		) AS v(service_type, weight, price)
+		WHERE NOT EXISTS (SELECT 1 FROM "ServicePrices");`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseDoubleBookedMachines makes room for the machine in use index on databases
+// from before it existed. When a machine holds more than one live basket, the running
+// one keeps it, then the oldest, and the others go back to the assignment sweep.
+func releaseDoubleBookedMachines(db *platform.Postgres) error {
+	released := []struct {
+		OrderBasketID string
+		MachineSerial string
+		OrderStatus   model.OrderStatus
+	}{}
+
+	dbTx := db.Raw(`
+	WITH ranked AS (
+		SELECT order_basket_id, machine_serial, ROW_NUMBER() OVER (
+			PARTITION BY machine_serial
+			ORDER BY order_status = 'Processing' DESC, created_at ASC, order_basket_id ASC
+		) AS rank
+		FROM "OrderDetails"
+		WHERE machine_serial IS NOT NULL AND deleted_at IS NULL AND order_status IN ('Waiting', 'Processing')
+	)
+	UPDATE "OrderDetails" AS OD
+	SET machine_serial = NULL
+	FROM ranked
+	WHERE OD.order_basket_id = ranked.order_basket_id AND ranked.rank > 1
+	RETURNING OD.order_basket_id, ranked.machine_serial, OD.order_status;`).Scan(&released)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	for _, basket := range released {
+		log.Printf("migration: released machine %s from %s basket %s, it was booked for another basket", basket.MachineSerial, basket.OrderStatus, basket.OrderBasketID)
+	}
+
+	return nil
+}
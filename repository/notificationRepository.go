@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type notificationRepository struct {
+	db *platform.Postgres
+}
+
+type NotificationRepository interface {
+	CreateNotification(notification *model.Notification) error
+	GetByBranchID(branchID string, unreadOnly bool) (*[]model.Notification, error)
+	GetByNotificationID(notificationID string) (*model.Notification, error)
+	MarkRead(notificationID string, readBy string) (*model.Notification, error)
+	WithTx(tx *platform.Postgres) NotificationRepository
+}
+
+func CreateNotificationRepository(db *platform.Postgres) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (u *notificationRepository) WithTx(tx *platform.Postgres) NotificationRepository {
+	return &notificationRepository{db: tx}
+}
+
+func (u *notificationRepository) CreateNotification(notification *model.Notification) error {
+	return u.db.Create(notification).Error
+}
+
+func (u *notificationRepository) GetByBranchID(branchID string, unreadOnly bool) (*[]model.Notification, error) {
+	notifications := new([]model.Notification)
+
+	query := u.db.Where("branch_id = ?", branchID)
+	if unreadOnly {
+		query = query.Where("is_read = FALSE")
+	}
+
+	result := query.Order("created_at DESC").Find(notifications)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notifications, nil
+}
+
+func (u *notificationRepository) GetByNotificationID(notificationID string) (*model.Notification, error) {
+	notification := new(model.Notification)
+
+	result := u.db.First(notification, "notification_id = ?", notificationID)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notification, nil
+}
+
+func (u *notificationRepository) MarkRead(notificationID string, readBy string) (*model.Notification, error) {
+	result := u.db.Model(&model.Notification{}).
+		Where("notification_id = ? AND is_read = FALSE", notificationID).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now().UTC(), "read_by": readBy})
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return u.GetByNotificationID(notificationID)
+}
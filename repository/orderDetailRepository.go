@@ -1,48 +1,69 @@
 package repository
 
 import (
+	"errors"
 	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+// machineInUseIndex only allows one waiting or processing basket per machine, see Migrate
+const machineInUseIndex = "OrderDetails_machine_in_use_idx"
+
+func isMachineInUseViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == machineInUseIndex
+}
+
+func machineBusyError(machineSerial *string) error {
+	busyErr := &model.MachineBusyError{}
+	if machineSerial != nil {
+		busyErr.MachineSerial = *machineSerial
+	}
+	return busyErr
+}
+
 type orderDetailRepository struct {
 	db *platform.Postgres
 }
 
 type OrderDetailRepository interface {
-	GetAll() (*[]model.OrderDetail, error)
 	CreateOrderDetails(orderDetails *[]model.OrderDetail) (*[]model.OrderDetail, error)
 	GetByHeaderID(orderHeaderID string, isAdminView bool) (*[]model.OrderDetail, error)
 	UpdateStatus(order model.OrderDetail) (*model.OrderDetail, error)
-	GetByUserID(userID string) (*[]model.OrderDetail, error)
+	GetByHeaderIDs(orderHeaderIDs []string) (*[]model.OrderDetail, error)
 	GetDetail(orderBasketID string) (*model.OrderDetail, error)
 	DeleteByHeaderID(orderHeaderID string, deletedBy string) (*[]model.OrderDetail, error)
-	CleanUpExpiredOrder() error
-	CompleteZuckProcess() error
+	ExpireByPaymentID(paymentID string) error
+	CompleteBasket(orderBasketID string) (bool, error)
+	GetProcessingByMachine(machineSerial string) (*model.OrderDetail, error)
+	UpdateFinishedAt(orderBasketID string, finishedAt time.Time) error
+	StartCycle(orderBasketID string, startedAt time.Time, finishedAt time.Time) error
+	GetWaitingForMachine(paymentID string) (*[]model.BasketToAssign, error)
+	LockByHeaderID(orderHeaderID string) (*[]model.OrderDetail, error)
+	CancelByHeaderID(orderHeaderID string, updatedBy string) (*[]model.OrderDetail, error)
+	ReleaseMachine(machineSerial string) (int64, error)
+	WithTx(tx *platform.Postgres) OrderDetailRepository
 }
 
 func CreateOrderDetailRepository(db *platform.Postgres) OrderDetailRepository {
 	return &orderDetailRepository{db: db}
 }
 
-func (u *orderDetailRepository) GetAll() (*[]model.OrderDetail, error) {
-	orderDetails := new([]model.OrderDetail)
-
-	result := u.db.Find(orderDetails)
-
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	return orderDetails, result.Error
+func (u *orderDetailRepository) WithTx(tx *platform.Postgres) OrderDetailRepository {
+	return &orderDetailRepository{db: tx}
 }
 
 func (u *orderDetailRepository) CreateOrderDetails(orderDetails *[]model.OrderDetail) (*[]model.OrderDetail, error) {
 	result := u.db.CreateInBatches(orderDetails, len(*orderDetails))
 
+	if isMachineInUseViolation(result.Error) {
+		return nil, machineBusyError((*orderDetails)[0].MachineSerial)
+	}
+
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -74,6 +95,23 @@ func (u *orderDetailRepository) GetByHeaderID(orderHeaderID string, isAdminView
 	return orders, result.Error
 }
 
+// GetByHeaderIDs returns the baskets of many orders in one query
+func (u *orderDetailRepository) GetByHeaderIDs(orderHeaderIDs []string) (*[]model.OrderDetail, error) {
+	orders := new([]model.OrderDetail)
+
+	if len(orderHeaderIDs) == 0 {
+		return orders, nil
+	}
+
+	result := u.db.Where("order_header_id IN ?", orderHeaderIDs).Order("created_at ASC, order_basket_id ASC").Find(orders)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return orders, nil
+}
+
 func (u *orderDetailRepository) GetDetail(orderBasketID string) (*model.OrderDetail, error) {
 	orderDetail := new(model.OrderDetail)
 
@@ -93,6 +131,10 @@ func (u *orderDetailRepository) UpdateStatus(order model.OrderDetail) (*model.Or
 		Updates(order).
 		Find(&updatedOrder)
 
+	if isMachineInUseViolation(result.Error) {
+		return nil, machineBusyError(order.MachineSerial)
+	}
+
 	if result.RowsAffected == 0 {
 		return nil, gorm.ErrRecordNotFound
 	}
@@ -137,47 +179,167 @@ func (u *orderDetailRepository) DeleteByHeaderID(orderHeaderID string, deletedBy
 	return cascading, result.Error
 }
 
-func (u *orderDetailRepository) CleanUpExpiredOrder() error {
-	orderDetailDummy := new(model.OrderDetail)
-	dbTx := u.db.Raw(`
-	UPDATE "OrderDetails"
-	SET order_status = 'Expired'
-	WHERE order_basket_id IN (
-		SELECT OD.order_basket_id
-		FROM "OrderDetails" AS OD INNER JOIN "OrderHeaders" AS OH ON OD.order_header_id = OH.order_header_id
-		INNER JOIN "Payments" AS PM ON PM.payment_id = OH.payment_id
-		WHERE OD.order_status = 'Waiting' AND PM.payment_status = 'Expired' AND PM.due_date < $1);
-	`, time.Now().UTC()).Scan(orderDetailDummy)
+// ExpireByPaymentID and CompleteBasket write the order event of every basket
+// they touch in the same statement, so the timeline can't miss a job update.
+func (u *orderDetailRepository) ExpireByPaymentID(paymentID string) error {
+	now := time.Now().UTC()
+	dbTx := u.db.Exec(`
+	WITH updated AS (
+		UPDATE "OrderDetails"
+		SET order_status = 'Expired'
+		WHERE order_basket_id IN (
+			SELECT OD.order_basket_id
+			FROM "OrderDetails" AS OD INNER JOIN "OrderHeaders" AS OH ON OD.order_header_id = OH.order_header_id
+			WHERE OD.order_status = 'Waiting' AND OH.payment_id = $1)
+		RETURNING order_basket_id, order_header_id, machine_serial
+	)
+	INSERT INTO "OrderEvents" (event_id, order_header_id, order_basket_id, event_type, from_status, to_status, machine_serial, actor_id, created_at)
+	SELECT gen_random_uuid(), order_header_id, order_basket_id, $2, 'Waiting', 'Expired', machine_serial, $3, $4
+	FROM updated;
+	`, paymentID, model.OrderStatusChanged, model.SystemActor, now)
 	return dbTx.Error
 }
 
-func (u *orderDetailRepository) CompleteZuckProcess() error {
-	orderDetailDummy := new(model.OrderDetail)
-	dbTx := u.db.Raw(`
-	UPDATE "OrderDetails"
-	SET order_status = 'Completed'
-	WHERE finished_at < $1 AND order_status = 'Processing' AND (service_type = 'Washing' OR service_type = 'Drying');
-	`, time.Now().UTC()).Scan(orderDetailDummy)
-	return dbTx.Error
+// CompleteBasket completes a washing or drying basket whose machine is done,
+// false when the basket isn't processing or isn't done yet. A machine that
+// reports it's still running keeps its basket, whatever the finish time says,
+// and so does a machine taken out of service until it's fixed.
+func (u *orderDetailRepository) CompleteBasket(orderBasketID string) (bool, error) {
+	now := time.Now().UTC()
+	dbTx := u.db.Exec(`
+	WITH updated AS (
+		UPDATE "OrderDetails" AS OD
+		SET order_status = 'Completed'
+		WHERE OD.order_basket_id = $1 AND OD.finished_at <= $2 AND OD.order_status = 'Processing' AND (OD.service_type = 'Washing' OR OD.service_type = 'Drying')
+			AND NOT EXISTS (
+				SELECT 1
+				FROM "MachineStates" AS MS
+				WHERE MS.machine_serial = OD.machine_serial AND MS.running AND MS.reported_at > $5)
+			AND EXISTS (
+				SELECT 1
+				FROM "Machines" AS M
+				WHERE M.machine_serial = OD.machine_serial AND M.is_active)
+		RETURNING OD.order_basket_id, OD.order_header_id, OD.machine_serial
+	)
+	INSERT INTO "OrderEvents" (event_id, order_header_id, order_basket_id, event_type, from_status, to_status, machine_serial, actor_id, created_at)
+	SELECT gen_random_uuid(), order_header_id, order_basket_id, $3, 'Processing', 'Completed', machine_serial, $4, $2
+	FROM updated;
+	`, orderBasketID, now, model.OrderStatusChanged, model.SystemActor, now.Add(-model.MachineStateStale))
+
+	if dbTx.Error != nil {
+		return false, dbTx.Error
+	}
+
+	return dbTx.RowsAffected == 1, nil
 }
 
-func (u *orderDetailRepository) GetByUserID(userID string) (*[]model.OrderDetail, error) {
-	order := new([]model.OrderDetail)
+// GetProcessingByMachine returns the basket the machine is running
+func (u *orderDetailRepository) GetProcessingByMachine(machineSerial string) (*model.OrderDetail, error) {
+	orderDetail := new(model.OrderDetail)
+
+	result := u.db.
+		Where("machine_serial = ? AND order_status = ?", machineSerial, model.Processing).
+		First(orderDetail)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return orderDetail, nil
+}
+
+// UpdateFinishedAt moves the time the machine of the basket is done
+func (u *orderDetailRepository) UpdateFinishedAt(orderBasketID string, finishedAt time.Time) error {
+	result := u.db.Model(&model.OrderDetail{}).
+		Where("order_basket_id = ?", orderBasketID).
+		Update("finished_at", finishedAt)
+
+	return result.Error
+}
+
+// StartCycle records when the machine of the basket was started and when it'll be done
+func (u *orderDetailRepository) StartCycle(orderBasketID string, startedAt time.Time, finishedAt time.Time) error {
+	result := u.db.Model(&model.OrderDetail{}).
+		Where("order_basket_id = ?", orderBasketID).
+		Updates(map[string]interface{}{"started_at": startedAt, "finished_at": finishedAt})
+
+	return result.Error
+}
+
+func (u *orderDetailRepository) GetWaitingForMachine(paymentID string) (*[]model.BasketToAssign, error) {
+	baskets := new([]model.BasketToAssign)
+
+	var result *gorm.DB
+
+	if paymentID == "" {
+		result = u.db.Raw(`
+		SELECT OD.order_basket_id, OD.order_header_id, OH.branch_id, OD.service_type, OD.weight
+		FROM "OrderDetails" AS OD INNER JOIN "OrderHeaders" AS OH ON OD.order_header_id = OH.order_header_id
+		INNER JOIN "Payments" AS PM ON PM.payment_id = OH.payment_id
+		WHERE OD.order_status = 'Waiting' AND OD.machine_serial IS NULL AND (OD.service_type = 'Washing' OR OD.service_type = 'Drying')
+			AND OD.deleted_at IS NULL AND OH.deleted_at IS NULL AND PM.payment_status = 'Paid'
+		ORDER BY OD.created_at ASC;`).Scan(baskets)
+	} else {
+		result = u.db.Raw(`
+		SELECT OD.order_basket_id, OD.order_header_id, OH.branch_id, OD.service_type, OD.weight
+		FROM "OrderDetails" AS OD INNER JOIN "OrderHeaders" AS OH ON OD.order_header_id = OH.order_header_id
+		INNER JOIN "Payments" AS PM ON PM.payment_id = OH.payment_id
+		WHERE OD.order_status = 'Waiting' AND OD.machine_serial IS NULL AND (OD.service_type = 'Washing' OR OD.service_type = 'Drying')
+			AND OD.deleted_at IS NULL AND OH.deleted_at IS NULL AND PM.payment_status = 'Paid' AND PM.payment_id = $1
+		ORDER BY OD.created_at ASC;`, paymentID).Scan(baskets)
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return baskets, nil
+}
+
+// LockByHeaderID holds row locks on every basket of the order until the surrounding transaction ends
+func (u *orderDetailRepository) LockByHeaderID(orderHeaderID string) (*[]model.OrderDetail, error) {
+	orderDetails := new([]model.OrderDetail)
 
 	result := u.db.Raw(`
-	SELECT order_basket_id, od.order_header_id, machine_serial, order_status, service_type, finished_at, 
-		od.created_at, od.created_at,od.created_by, od.updated_at,od.updated_by, od.deleted_at, od.deleted_by, weight
-	FROM "OrderDetails" od
-	JOIN public."OrderHeaders" oh on od.order_header_id = oh.order_header_id
-	WHERE oh.user_id = $1`, userID).Scan(&order)
+	SELECT *
+	FROM "OrderDetails"
+	WHERE order_header_id = $1 AND deleted_at IS NULL
+	FOR UPDATE;`, orderHeaderID).Scan(orderDetails)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return orderDetails, nil
+}
+
+// CancelByHeaderID cancels every waiting basket of the order, a canceled basket
+// no longer holds its machine so the machine is free for the next basket.
+func (u *orderDetailRepository) CancelByHeaderID(orderHeaderID string, updatedBy string) (*[]model.OrderDetail, error) {
+	result := u.db.Exec(`
+	UPDATE "OrderDetails"
+	SET order_status = 'Canceled', updated_at = $1, updated_by = $2
+	WHERE order_header_id = $3 AND order_status = 'Waiting' AND deleted_at IS NULL;`,
+		time.Now().UTC(), updatedBy, orderHeaderID)
 
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
 	if result.RowsAffected == 0 {
-		return new([]model.OrderDetail), nil
+		return nil, gorm.ErrRecordNotFound
 	}
 
-	return order, result.Error
+	return u.GetByHeaderID(orderHeaderID, true)
+}
+
+// ReleaseMachine takes the machine back from the waiting baskets it was reserved for,
+// they're assigned another machine on the next assignment sweep.
+func (u *orderDetailRepository) ReleaseMachine(machineSerial string) (int64, error) {
+	result := u.db.Exec(`
+	UPDATE "OrderDetails"
+	SET machine_serial = NULL
+	WHERE machine_serial = $1 AND order_status = 'Waiting' AND deleted_at IS NULL;`, machineSerial)
+
+	return result.RowsAffected, result.Error
 }
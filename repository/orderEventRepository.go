@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type orderEventRepository struct {
+	db *platform.Postgres
+}
+
+type OrderEventRepository interface {
+	CreateEvents(events *[]model.OrderEvent) error
+	GetByHeaderID(orderHeaderID string) (*[]model.OrderEvent, error)
+	WithTx(tx *platform.Postgres) OrderEventRepository
+}
+
+func CreateOrderEventRepository(db *platform.Postgres) OrderEventRepository {
+	return &orderEventRepository{db: db}
+}
+
+func (u *orderEventRepository) WithTx(tx *platform.Postgres) OrderEventRepository {
+	return &orderEventRepository{db: tx}
+}
+
+func (u *orderEventRepository) CreateEvents(events *[]model.OrderEvent) error {
+	if len(*events) == 0 {
+		return nil
+	}
+
+	result := u.db.CreateInBatches(events, len(*events))
+	return result.Error
+}
+
+func (u *orderEventRepository) GetByHeaderID(orderHeaderID string) (*[]model.OrderEvent, error) {
+	events := new([]model.OrderEvent)
+
+	result := u.db.Where("order_header_id = ?", orderHeaderID).Order("created_at ASC").Find(events)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return events, nil
+}
@@ -1,41 +1,55 @@
 package repository
 
 import (
-	"errors"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 
 	"gorm.io/gorm"
 )
 
+// derivedOrderStatus sums up the baskets of an order into one status in SQL,
+// it must stay in line with usecases.DeriveOrderStatus
+const derivedOrderStatus = `
+	CASE
+		WHEN OS.total = 0 THEN 'Waiting'
+		WHEN OS.expired > 0 AND OS.expired + OS.canceled = OS.total THEN 'Expired'
+		WHEN OS.canceled = OS.total THEN 'Canceled'
+		WHEN OS.completed + OS.canceled = OS.total THEN 'Completed'
+		WHEN OS.waiting + OS.canceled = OS.total THEN 'Waiting'
+		ELSE 'Processing'
+	END`
+
+const orderStatusCounts = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE OD.order_status = 'Waiting') AS waiting,
+			COUNT(*) FILTER (WHERE OD.order_status = 'Completed') AS completed,
+			COUNT(*) FILTER (WHERE OD.order_status = 'Canceled') AS canceled,
+			COUNT(*) FILTER (WHERE OD.order_status = 'Expired') AS expired
+		FROM "OrderDetails" AS OD
+		WHERE OD.order_header_id = OH.order_header_id AND OD.deleted_at IS NULL
+	) AS OS ON TRUE`
+
 type orderHeaderRepository struct {
 	db *platform.Postgres
 }
 
 type OrderHeaderRepository interface {
 	CreateOrderHeader(orderHeader *model.OrderHeader) (*model.OrderHeader, error)
-	GetAll() (*[]model.OrderHeader, error)
 	GetByID(orderHeaderID string, isAdminView bool) (*model.OrderHeader, error)
-	GetByBranchID(branchID string, status string) (*[]model.OrderHeader, error)
-	GetByUserID(userID string) (*[]model.OrderHeader, error)
+	GetByPaymentID(paymentID string) (*model.OrderHeader, error)
+	GetPage(filter model.OrderListFilter, cursor *model.OrderCursor) (*[]model.OrderHeader, error)
 	UpdateReview(order model.OrderHeader) (*model.OrderHeader, error)
 	SoftDelete(orderHeaderID string, deletedBy string) (*model.OrderHeader, error)
+	WithTx(tx *platform.Postgres) OrderHeaderRepository
 }
 
 func CreateOrderHeaderRepository(db *platform.Postgres) OrderHeaderRepository {
 	return &orderHeaderRepository{db: db}
 }
 
-func (u *orderHeaderRepository) GetAll() (*[]model.OrderHeader, error) {
-	orderHeaders := new([]model.OrderHeader)
-
-	result := u.db.Find(orderHeaders)
-
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	return orderHeaders, result.Error
+func (u *orderHeaderRepository) WithTx(tx *platform.Postgres) OrderHeaderRepository {
+	return &orderHeaderRepository{db: tx}
 }
 
 func (u *orderHeaderRepository) CreateOrderHeader(orderHeader *model.OrderHeader) (*model.OrderHeader, error) {
@@ -76,50 +90,16 @@ func (u *orderHeaderRepository) GetByID(orderHeaderID string, isAdminView bool)
 	return order, result.Error
 }
 
-func (u *orderHeaderRepository) GetByBranchID(branchID string, status string) (*[]model.OrderHeader, error) {
-	order := new([]model.OrderHeader)
-
-	var result *gorm.DB
-
-	if status == "" {
-		result = u.db.Raw(`
-		SELECT OH.order_header_id, OH.user_id, OH.branch_id, OH.order_note, OH.payment_id, OH.zuck_onsite, OH.delivery_address, OH.delivery_lat,
-		OH.delivery_long, OH.star_rating, OH.review_comment, OH.created_at, OH.created_by, OH.updated_at, OH.updated_by, OH.deleted_at, OH.deleted_by
-		FROM "OrderHeaders" AS OH INNER JOIN "Payments" AS PM ON OH.payment_id = PM.payment_id
-		WHERE OH.branch_id = $1;`, branchID).Scan(&order)
-	} else {
-		result = u.db.Raw(`
-		SELECT OH.order_header_id, OH.user_id, OH.branch_id, OH.order_note, OH.payment_id, OH.zuck_onsite, OH.delivery_address, OH.delivery_lat,
-		OH.delivery_long, OH.star_rating, OH.review_comment, OH.created_at, OH.created_by, OH.updated_at, OH.updated_by, OH.deleted_at, OH.deleted_by
-		FROM "OrderHeaders" AS OH INNER JOIN "Payments" AS PM ON OH.payment_id = PM.payment_id
-		WHERE OH.branch_id = $1 AND PM.payment_status = $2;`, branchID, status).Scan(&order)
-	}
-
-	if result == nil {
-		return nil, errors.New("ERR: unable to initialize query")
-	}
-
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	return order, result.Error
-}
-
-func (u *orderHeaderRepository) GetByUserID(userID string) (*[]model.OrderHeader, error) {
-	order := new([]model.OrderHeader)
+func (u *orderHeaderRepository) GetByPaymentID(paymentID string) (*model.OrderHeader, error) {
+	order := new(model.OrderHeader)
 
-	result := u.db.Where("user_id = ?", userID).Find(&order)
+	result := u.db.First(order, "payment_id = ?", paymentID)
 
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	if result.RowsAffected == 0 {
-		return new([]model.OrderHeader), nil
-	}
-
-	return order, result.Error
+	return order, nil
 }
 
 func (u *orderHeaderRepository) UpdateReview(order model.OrderHeader) (*model.OrderHeader, error) {
@@ -167,3 +147,75 @@ func (u *orderHeaderRepository) SoftDelete(orderHeaderID string, deletedBy strin
 
 	return order, result.Error
 }
+
+// GetPage returns the orders matching the filter that come after the cursor,
+// one more row than filter.Limit is fetched so the caller can tell if there is a next page
+func (u *orderHeaderRepository) GetPage(filter model.OrderListFilter, cursor *model.OrderCursor) (*[]model.OrderHeader, error) {
+	orders := new([]model.OrderHeader)
+
+	query := u.db.Table(`"OrderHeaders" AS OH`).
+		Select("OH.*").
+		Where("OH.deleted_at IS NULL")
+
+	if filter.BranchID != "" {
+		query = query.Where("OH.branch_id = ?", filter.BranchID)
+	}
+
+	if filter.UserID != "" {
+		query = query.Where("OH.user_id = ?", filter.UserID)
+	}
+
+	if filter.ZuckOnsite != nil {
+		query = query.Where("OH.zuck_onsite = ?", *filter.ZuckOnsite)
+	}
+
+	if filter.CreatedFrom != nil {
+		query = query.Where("OH.created_at >= ?", filter.CreatedFrom.UTC())
+	}
+
+	if filter.CreatedTo != nil {
+		query = query.Where("OH.created_at < ?", filter.CreatedTo.UTC())
+	}
+
+	if filter.PaymentStatus != "" {
+		query = query.
+			Joins(`INNER JOIN "Payments" AS PM ON PM.payment_id = OH.payment_id`).
+			Where("PM.payment_status = ?", filter.PaymentStatus)
+	}
+
+	if filter.ServiceType != "" {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM "OrderDetails" AS SD
+			WHERE SD.order_header_id = OH.order_header_id AND SD.service_type = ? AND SD.deleted_at IS NULL)`, filter.ServiceType)
+	}
+
+	if filter.OrderStatus != "" {
+		query = query.Joins(orderStatusCounts).Where(derivedOrderStatus+" = ?", filter.OrderStatus)
+	}
+
+	// sort column is never taken from the request as is
+	sortColumn := "OH.created_at"
+	if filter.SortBy == model.SortByUpdatedAt {
+		sortColumn = "OH.updated_at"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if cursor != nil {
+		query = query.Where("("+sortColumn+", OH.order_header_id) "+comparison+" (?, ?)", cursor.SortValue, cursor.OrderHeaderID)
+	}
+
+	result := query.
+		Order(sortColumn + " " + direction + ", OH.order_header_id " + direction).
+		Limit(filter.Limit + 1).
+		Scan(orders)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type orderPriceLineRepository struct {
+	db *platform.Postgres
+}
+
+type OrderPriceLineRepository interface {
+	CreatePriceLines(priceLines *[]model.OrderPriceLine) error
+	GetByHeaderID(orderHeaderID string) (*[]model.OrderPriceLine, error)
+	WithTx(tx *platform.Postgres) OrderPriceLineRepository
+}
+
+func CreateOrderPriceLineRepository(db *platform.Postgres) OrderPriceLineRepository {
+	return &orderPriceLineRepository{db: db}
+}
+
+func (u *orderPriceLineRepository) WithTx(tx *platform.Postgres) OrderPriceLineRepository {
+	return &orderPriceLineRepository{db: tx}
+}
+
+func (u *orderPriceLineRepository) CreatePriceLines(priceLines *[]model.OrderPriceLine) error {
+	result := u.db.CreateInBatches(priceLines, len(*priceLines))
+	return result.Error
+}
+
+func (u *orderPriceLineRepository) GetByHeaderID(orderHeaderID string) (*[]model.OrderPriceLine, error) {
+	priceLines := new([]model.OrderPriceLine)
+
+	result := u.db.Where("order_header_id = ?", orderHeaderID).Order("line_no ASC").Find(priceLines)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return priceLines, nil
+}
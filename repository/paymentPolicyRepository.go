@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type paymentPolicyRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewPaymentPolicyRepository(db *platform.Postgres) model.PaymentPolicyRepository {
+	return &paymentPolicyRepository{db: db}
+}
+
+// SavePolicy creates the policy or updates every setting of it
+func (u *paymentPolicyRepository) SavePolicy(policy *model.PaymentPolicy) error {
+	dbTx := u.db.Save(policy)
+	return dbTx.Error
+}
+
+func (u *paymentPolicyRepository) FindByPolicyID(policyID string) (*model.PaymentPolicy, error) {
+	policy := new(model.PaymentPolicy)
+	dbTx := u.db.First(policy, "policy_id = ?", policyID)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return policy, nil
+}
+
+// FindPolicy returns the policy set for exactly this branch and order type,
+// nil branchID for the default one
+func (u *paymentPolicyRepository) FindPolicy(branchID *string, zuckOnsite bool) (*model.PaymentPolicy, error) {
+	policy := new(model.PaymentPolicy)
+	query := u.db.Where("zuck_onsite = ?", zuckOnsite)
+	if branchID == nil {
+		query = query.Where("branch_id IS NULL")
+	} else {
+		query = query.Where("branch_id = ?", *branchID)
+	}
+
+	if dbTx := query.First(policy); dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return policy, nil
+}
+
+// FindByBranchID returns the policies of the branch together with the default policies
+func (u *paymentPolicyRepository) FindByBranchID(branchID string) (*[]model.PaymentPolicy, error) {
+	policies := new([]model.PaymentPolicy)
+	dbTx := u.db.
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Order("zuck_onsite ASC, branch_id IS NULL ASC").
+		Find(policies)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return policies, nil
+}
+
+// FindEffectivePolicy returns the policy an order of the branch is paid under,
+// a policy set for the branch wins over the default one
+func (u *paymentPolicyRepository) FindEffectivePolicy(branchID string, zuckOnsite bool) (*model.PaymentPolicy, error) {
+	policy := new(model.PaymentPolicy)
+	dbTx := u.db.Raw(`
+	SELECT *
+	FROM "PaymentPolicies"
+	WHERE (branch_id = $1 OR branch_id IS NULL) AND zuck_onsite = $2
+	ORDER BY branch_id IS NULL ASC
+	LIMIT 1;`, branchID, zuckOnsite).Scan(policy)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return policy, nil
+}
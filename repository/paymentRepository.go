@@ -4,6 +4,9 @@ import (
 	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
 	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type paymentReopository struct {
@@ -14,6 +17,10 @@ func CreateNewPaymentRepository(db *platform.Postgres) model.PaymentRepository {
 	return &paymentReopository{db: db}
 }
 
+func (u *paymentReopository) WithTx(tx *platform.Postgres) model.PaymentRepository {
+	return &paymentReopository{db: tx}
+}
+
 func (u *paymentReopository) CreatePayment(newPayment model.Payments) (*model.Payments, error) {
 	createdPayment := new(model.Payments)
 	dbTx := u.db.Create(newPayment)
@@ -44,11 +51,154 @@ func (u *paymentReopository) UpdatePaymentStatus(paymentID string, status model.
 	return dbTx.Error
 }
 
-func (u *paymentReopository) CleanupExpiredPayment() error {
-	var list []model.Payments
-	dbTx := u.db.Raw(`
+// ExpirePayment expires a payment that is still pending
+func (u *paymentReopository) ExpirePayment(paymentID string) error {
+	dbTx := u.db.Exec(`
 	UPDATE "Payments"
 	SET payment_status = 'Expired'
-	WHERE due_date < $1 AND payment_status = 'Pending'`, time.Now().UTC()).Scan(&list)
-	return dbTx.Error
+	WHERE payment_id = $1 AND payment_status = 'Pending';`, paymentID)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// LockPayment holds a row lock on the payment until the surrounding transaction ends
+func (u *paymentReopository) LockPayment(paymentID string) (*model.Payments, error) {
+	payment := new(model.Payments)
+	dbTx := u.db.Raw(`
+	SELECT *
+	FROM "Payments"
+	WHERE payment_id = $1 AND deleted_at IS NULL
+	FOR UPDATE;`, paymentID).Scan(payment)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return payment, nil
+}
+
+func (u *paymentReopository) CancelPayment(paymentID string) error {
+	dbTx := u.db.Exec(`
+	UPDATE "Payments"
+	SET payment_status = 'Cancel'
+	WHERE payment_id = $1 AND payment_status = 'Pending';`, paymentID)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// MarkPaid settles a pending payment, only the payment webhook calls this
+func (u *paymentReopository) MarkPaid(paymentID string) error {
+	dbTx := u.db.Exec(`
+	UPDATE "Payments"
+	SET payment_status = 'Paid', paid_at = $2
+	WHERE payment_id = $1 AND payment_status = 'Pending';`, paymentID, time.Now().UTC())
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RecordWebhookEvent stores the event once, false means the event was seen before
+func (u *paymentReopository) RecordWebhookEvent(event *model.PaymentWebhookEvent) (bool, error) {
+	dbTx := u.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+
+	if dbTx.Error != nil {
+		return false, dbTx.Error
+	}
+
+	return dbTx.RowsAffected == 1, nil
+}
+
+// FindMerchantID returns the PromptPay id of the branch the payment belongs to,
+// empty when the branch has none
+func (u *paymentReopository) FindMerchantID(paymentID string) (string, error) {
+	var merchantID *string
+	dbTx := u.db.Raw(`
+	SELECT BR.promptpay_id
+	FROM "OrderHeaders" AS OH INNER JOIN "Branches" AS BR ON BR.branch_id = OH.branch_id
+	WHERE OH.payment_id = $1
+	LIMIT 1;`, paymentID).Scan(&merchantID)
+
+	if dbTx.Error != nil {
+		return "", dbTx.Error
+	}
+
+	if merchantID == nil {
+		return "", nil
+	}
+
+	return *merchantID, nil
+}
+
+// ExtendPayment moves the due date of a pending payment and counts the extension
+func (u *paymentReopository) ExtendPayment(paymentID string, dueDate time.Time) error {
+	dbTx := u.db.Exec(`
+	UPDATE "Payments"
+	SET due_date = $1, extension_count = extension_count + 1
+	WHERE payment_id = $2 AND payment_status = 'Pending';`, dueDate, paymentID)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetReportLines returns payments created between from and to for orders of the branch,
+// with where the money came from
+func (u *paymentReopository) GetReportLines(branchID string, from time.Time, to time.Time) (*[]model.PaymentReportLine, error) {
+	lines := new([]model.PaymentReportLine)
+	dbTx := u.db.Raw(`
+	SELECT PM.payment_id, OH.order_header_id, PM.amount, PM.payment_status, PM.due_date, PM.created_at,
+		CASE WHEN WT.transaction_id IS NOT NULL THEN $4 ELSE COALESCE(EV.provider, '') END AS method,
+		CASE WHEN WT.transaction_id IS NOT NULL THEN WT.amount ELSE EV.amount END AS received_amount
+	FROM "Payments" AS PM
+		INNER JOIN "OrderHeaders" AS OH ON OH.payment_id = PM.payment_id
+		LEFT JOIN "WalletTransactions" AS WT ON WT.payment_id = PM.payment_id AND WT.transaction_type = $5
+		LEFT JOIN LATERAL (
+			SELECT E.provider, E.amount
+			FROM "PaymentWebhookEvents" AS E
+			WHERE E.payment_id = PM.payment_id AND E.status = $6
+			ORDER BY E.received_at ASC
+			LIMIT 1
+		) AS EV ON TRUE
+	WHERE OH.branch_id = $1 AND PM.created_at >= $2 AND PM.created_at < $3 AND PM.deleted_at IS NULL
+	ORDER BY PM.created_at ASC, PM.payment_id ASC;`,
+		branchID, from, to, model.WalletMethod, model.WalletDebit, model.Paid).Scan(lines)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	return lines, nil
 }
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type promoCodeRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewPromoCodeRepository(db *platform.Postgres) model.PromoCodeRepository {
+	return &promoCodeRepository{db: db}
+}
+
+func (u *promoCodeRepository) WithTx(tx *platform.Postgres) model.PromoCodeRepository {
+	return &promoCodeRepository{db: tx}
+}
+
+func (u *promoCodeRepository) CreatePromoCode(promoCode *model.PromoCode) error {
+	return u.db.Create(promoCode).Error
+}
+
+func (u *promoCodeRepository) FindByPromoCodeID(promoCodeID string) (*model.PromoCode, error) {
+	promoCode := new(model.PromoCode)
+
+	result := u.db.First(promoCode, "promo_code_id = ?", promoCodeID)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return promoCode, nil
+}
+
+func (u *promoCodeRepository) FindByCode(code string) (*model.PromoCode, error) {
+	promoCode := new(model.PromoCode)
+
+	result := u.db.First(promoCode, "code = ?", code)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return promoCode, nil
+}
+
+func (u *promoCodeRepository) GetAll() (*[]model.PromoCode, error) {
+	promoCodes := new([]model.PromoCode)
+
+	result := u.db.Order("created_at DESC").Find(promoCodes)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return promoCodes, nil
+}
+
+// LockPromoCode holds a row lock on the code so redemptions are counted and
+// added one order at a time
+func (u *promoCodeRepository) LockPromoCode(promoCodeID string) (*model.PromoCode, error) {
+	promoCode := new(model.PromoCode)
+
+	result := u.db.Raw(`
+	SELECT *
+	FROM "PromoCodes"
+	WHERE promo_code_id = $1
+	FOR UPDATE;`, promoCodeID).Scan(promoCode)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return promoCode, nil
+}
+
+func (u *promoCodeRepository) CountRedemptions(promoCodeID string, userID string) (*model.PromoUsage, error) {
+	usage := new(model.PromoUsage)
+
+	result := u.db.Raw(`
+	SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE user_id = $2) AS by_user
+	FROM "PromoRedemptions"
+	WHERE promo_code_id = $1;`, promoCodeID, userID).Scan(usage)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return usage, nil
+}
+
+func (u *promoCodeRepository) CreateRedemption(redemption *model.PromoRedemption) error {
+	return u.db.Create(redemption).Error
+}
+
+func (u *promoCodeRepository) GetRedemptions(promoCodeID string) (*[]model.PromoRedemption, error) {
+	redemptions := new([]model.PromoRedemption)
+
+	result := u.db.Where("promo_code_id = ?", promoCodeID).Order("created_at ASC").Find(redemptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return redemptions, nil
+}
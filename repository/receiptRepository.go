@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type receiptRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewReceiptRepository(db *platform.Postgres) model.ReceiptRepository {
+	return &receiptRepository{db: db}
+}
+
+func (u *receiptRepository) WithTx(tx *platform.Postgres) model.ReceiptRepository {
+	return &receiptRepository{db: tx}
+}
+
+// NextNumber takes the next receipt number of the branch. The sequence row stays
+// locked until the surrounding transaction ends, so receipts of a branch are issued
+// one at a time and a rolled back receipt gives its number back.
+func (u *receiptRepository) NextNumber(branchID string) (int64, error) {
+	var number int64
+	dbTx := u.db.Raw(`
+	INSERT INTO "ReceiptSequences" (branch_id, last_number)
+	VALUES ($1, 1)
+	ON CONFLICT (branch_id) DO UPDATE SET last_number = "ReceiptSequences".last_number + 1
+	RETURNING last_number;`, branchID).Scan(&number)
+
+	if dbTx.Error != nil {
+		return 0, dbTx.Error
+	}
+
+	return number, nil
+}
+
+func (u *receiptRepository) CreateReceipt(receipt *model.Receipt) error {
+	dbTx := u.db.Create(receipt)
+	return dbTx.Error
+}
+
+func (u *receiptRepository) FindByOrderHeaderID(orderHeaderID string) (*model.Receipt, error) {
+	receipt := new(model.Receipt)
+	dbTx := u.db.First(receipt, "order_header_id = ?", orderHeaderID)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return receipt, nil
+}
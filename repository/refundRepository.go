@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type refundRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewRefundRepository(db *platform.Postgres) model.RefundRepository {
+	return &refundRepository{db: db}
+}
+
+func (u *refundRepository) WithTx(tx *platform.Postgres) model.RefundRepository {
+	return &refundRepository{db: tx}
+}
+
+func (u *refundRepository) CreateRefund(refund *model.Refund) error {
+	return u.db.Create(refund).Error
+}
+
+func (u *refundRepository) FindByRefundID(refundID string) (*model.Refund, error) {
+	refund := new(model.Refund)
+
+	result := u.db.First(refund, "refund_id = ?", refundID)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return refund, nil
+}
+
+func (u *refundRepository) FindByPaymentID(paymentID string) (*[]model.Refund, error) {
+	refunds := new([]model.Refund)
+
+	result := u.db.Where("payment_id = ?", paymentID).Order("created_at ASC").Find(refunds)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return refunds, nil
+}
+
+// SumByPaymentID is how much of the payment is refunded or waiting to be, rejected refunds don't count
+func (u *refundRepository) SumByPaymentID(paymentID string) (float64, error) {
+	var total float64
+
+	result := u.db.Raw(`
+	SELECT COALESCE(SUM(amount), 0)
+	FROM "Refunds"
+	WHERE payment_id = $1 AND refund_status <> $2;`, paymentID, model.RefundRejected).Scan(&total)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return total, nil
+}
+
+// GetByBranchID returns refunds requested between from and to of payments made at the branch
+func (u *refundRepository) GetByBranchID(branchID string, from time.Time, to time.Time) (*[]model.Refund, error) {
+	refunds := new([]model.Refund)
+
+	result := u.db.Raw(`
+	SELECT RF.*
+	FROM "Refunds" AS RF INNER JOIN "OrderHeaders" AS OH ON OH.payment_id = RF.payment_id
+	WHERE OH.branch_id = $1 AND RF.created_at >= $2 AND RF.created_at < $3
+	ORDER BY RF.created_at ASC, RF.refund_id ASC;`, branchID, from, to).Scan(refunds)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return refunds, nil
+}
+
+// UpdateRefundStatus settles a requested refund, a refund that was settled already is not found
+func (u *refundRepository) UpdateRefundStatus(refundID string, status model.RefundStatus, processedBy string) error {
+	result := u.db.Exec(`
+	UPDATE "Refunds"
+	SET refund_status = $1, processed_at = $2, processed_by = $3
+	WHERE refund_id = $4 AND refund_status = $5;`, status, time.Now().UTC(), processedBy, refundID, model.RefundRequested)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type servicePriceRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewServicePriceRepository(db *platform.Postgres) model.ServicePriceRepository {
+	return &servicePriceRepository{db: db}
+}
+
+func (u *servicePriceRepository) CreateServicePrice(newPrice *model.ServicePrice) error {
+	dbTx := u.db.Create(newPrice)
+	return dbTx.Error
+}
+
+func (u *servicePriceRepository) FindByPriceID(priceID string) (*model.ServicePrice, error) {
+	price := new(model.ServicePrice)
+	dbTx := u.db.First(price, "price_id = ?", priceID)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return price, nil
+}
+
+// FindByBranchID returns the prices of the branch together with the default prices
+func (u *servicePriceRepository) FindByBranchID(branchID string) (*[]model.ServicePrice, error) {
+	prices := new([]model.ServicePrice)
+	dbTx := u.db.
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Order("service_type ASC, weight ASC, effective_from DESC").
+		Find(prices)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return prices, nil
+}
+
+// FindEffectivePrice returns the latest price that is in effect at the given time,
+// a price set for the branch wins over the default one.
+func (u *servicePriceRepository) FindEffectivePrice(branchID string, serviceType model.ServiceType, weight int16, at time.Time) (*model.ServicePrice, error) {
+	price := new(model.ServicePrice)
+	dbTx := u.db.Raw(`
+	SELECT *
+	FROM "ServicePrices"
+	WHERE (branch_id = $1 OR branch_id IS NULL) AND service_type = $2 AND weight = $3
+		AND effective_from <= $4 AND deleted_at IS NULL
+	ORDER BY branch_id IS NULL ASC, effective_from DESC
+	LIMIT 1;`, branchID, serviceType, weight, at).Scan(price)
+
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+
+	if dbTx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return price, nil
+}
+
+func (u *servicePriceRepository) DeleteServicePrice(priceID string, deletedBy string) error {
+	dbTx := u.db.Model(&model.ServicePrice{}).
+		Where("price_id = ?", priceID).
+		Update("deleted_by", deletedBy)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	dbTx = u.db.Where("price_id = ?", priceID).Delete(&model.ServicePrice{})
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return dbTx.Error
+}
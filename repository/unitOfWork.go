@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs a set of repository calls in one database transaction.
+// Repositories join the transaction through their WithTx method, everything
+// is committed when work returns nil and rolled back otherwise.
+type UnitOfWork interface {
+	Do(work func(tx *platform.Postgres) error) error
+}
+
+type unitOfWork struct {
+	db *platform.Postgres
+}
+
+func CreateNewUnitOfWork(db *platform.Postgres) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(work func(tx *platform.Postgres) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return work(&platform.Postgres{DB: tx})
+	})
+}
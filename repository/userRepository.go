@@ -12,6 +12,7 @@ import (
 type UserRepository interface {
 	CreateUser(newUser model.Users) (*model.Users, error)
 	FindUserByUserID(userID string) (*model.Users, error)
+	FindUsersByUserIDs(userIDs []string) ([]model.Users, error)
 	FindUserByEmail(email string) (*model.Users, error)
 	FindUserByGoogleID(googleID string) (*model.Users, error)
 	GetAll() ([]model.Users, error)
@@ -46,6 +47,20 @@ func (repo *userRepository) FindUserByUserID(userID string) (*model.Users, error
 	return result, nil
 }
 
+// FindUsersByUserIDs looks up many users in one query, missing users are left out
+func (repo *userRepository) FindUsersByUserIDs(userIDs []string) ([]model.Users, error) {
+	var users []model.Users
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	dbTx := repo.db.Where("user_id IN ?", userIDs).Find(&users)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return users, nil
+}
+
 func (repo *userRepository) FindUserByEmail(email string) (*model.Users, error) {
 	result := new(model.Users)
 	dbTx := repo.db.First(result, "email = ?", email)
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type walletRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewWalletRepository(db *platform.Postgres) model.WalletRepository {
+	return &walletRepository{db: db}
+}
+
+func (u *walletRepository) WithTx(tx *platform.Postgres) model.WalletRepository {
+	return &walletRepository{db: tx}
+}
+
+// GetWallet returns an empty wallet for users that never topped up
+func (u *walletRepository) GetWallet(userID string) (*model.Wallet, error) {
+	wallet := new(model.Wallet)
+
+	result := u.db.Where("user_id = ?", userID).Find(wallet)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return &model.Wallet{UserID: userID}, nil
+	}
+
+	return wallet, nil
+}
+
+// Credit adds amount to the wallet, creating it on the first top up, and returns the new balance
+func (u *walletRepository) Credit(userID string, amount float64) (float64, error) {
+	var balance float64
+
+	result := u.db.Raw(`
+	INSERT INTO "Wallets" (user_id, balance, updated_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE
+	SET balance = "Wallets".balance + EXCLUDED.balance, updated_at = EXCLUDED.updated_at
+	RETURNING balance;`, userID, amount, time.Now().UTC()).Scan(&balance)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return balance, nil
+}
+
+// Debit takes amount out of the wallet and returns the new balance. The balance is
+// checked in the same statement that changes it, so concurrent debits can never
+// spend the same money twice.
+func (u *walletRepository) Debit(userID string, amount float64) (float64, error) {
+	var balance float64
+
+	result := u.db.Raw(`
+	UPDATE "Wallets"
+	SET balance = balance - $1, updated_at = $2
+	WHERE user_id = $3 AND balance >= $1
+	RETURNING balance;`, amount, time.Now().UTC(), userID).Scan(&balance)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return 0, errors.New("ERR 400: not enough wallet balance")
+	}
+
+	return balance, nil
+}
+
+func (u *walletRepository) CreateTransaction(transaction *model.WalletTransaction) error {
+	return u.db.Create(transaction).Error
+}
+
+// FindDebitByPaymentID returns nil when the payment was not paid from a wallet
+func (u *walletRepository) FindDebitByPaymentID(paymentID string) (*model.WalletTransaction, error) {
+	transaction := new(model.WalletTransaction)
+
+	result := u.db.Where("payment_id = ? AND transaction_type = ?", paymentID, model.WalletDebit).Find(transaction)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return transaction, nil
+}
+
+func (u *walletRepository) GetTransactions(userID string, from *time.Time, to *time.Time) (*[]model.WalletTransaction, error) {
+	transactions := new([]model.WalletTransaction)
+
+	query := u.db.Where("user_id = ?", userID)
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	result := query.Order("created_at ASC").Order("transaction_id ASC").Find(transactions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type washProgramRepository struct {
+	db *platform.Postgres
+}
+
+func CreateNewWashProgramRepository(db *platform.Postgres) model.WashProgramRepository {
+	return &washProgramRepository{db: db}
+}
+
+func (u *washProgramRepository) CreateProgram(newProgram *model.WashProgram) error {
+	dbTx := u.db.Create(newProgram)
+	return dbTx.Error
+}
+
+func (u *washProgramRepository) FindByProgramID(programID string) (*model.WashProgram, error) {
+	program := new(model.WashProgram)
+	dbTx := u.db.First(program, "program_id = ?", programID)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return program, nil
+}
+
+// FindByMachineType returns the programs machines of the type can run, shortest first
+func (u *washProgramRepository) FindByMachineType(machineType model.MachineType) (*[]model.WashProgram, error) {
+	programs := new([]model.WashProgram)
+	dbTx := u.db.
+		Where("machine_type = ?", machineType).
+		Order("duration_minutes ASC, name ASC").
+		Find(programs)
+	if dbTx.Error != nil {
+		return nil, dbTx.Error
+	}
+	return programs, nil
+}
+
+func (u *washProgramRepository) UpdateProgram(program *model.WashProgram) error {
+	dbTx := u.db.Model(&model.WashProgram{}).
+		Where("program_id = ?", program.ProgramID).
+		Updates(map[string]interface{}{
+			"name":             program.Name,
+			"duration_minutes": program.DurationMinutes,
+			"temperature":      program.Temperature,
+			"price_modifier":   program.PriceModifier,
+			"updated_at":       program.UpdatedAt,
+			"updated_by":       program.UpdatedBy,
+		})
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return dbTx.Error
+}
+
+func (u *washProgramRepository) DeleteProgram(programID string, deletedBy string) error {
+	dbTx := u.db.Model(&model.WashProgram{}).
+		Where("program_id = ?", programID).
+		Update("deleted_by", deletedBy)
+
+	if dbTx.Error != nil {
+		return dbTx.Error
+	}
+
+	dbTx = u.db.Where("program_id = ?", programID).Delete(&model.WashProgram{})
+
+	if dbTx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return dbTx.Error
+}
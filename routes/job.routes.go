@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func JobRoutes(routeRegister *config.RoutesRegister) {
+	jobRepo := repository.CreateNewJobRepository(routeRegister.DbConnection)
+	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
+	paymentPolicyRepo := repository.CreateNewPaymentPolicyRepository(routeRegister.DbConnection)
+	orderDetailRepo := repository.CreateOrderDetailRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	jobUsecase := usecases.CreateNewJobUsecase(jobRepo, paymentRepo, paymentPolicyRepo, orderDetailRepo, unitOfWork)
+	jobController := controller.CreateNewJobController(jobUsecase)
+
+	application := routeRegister.Application
+
+	jobGroup := application.Group("/job", middleware.AuthRequire, middleware.IsSuperAdmin)
+	jobGroup.Get("/dead", jobController.GetDeadJobs)
+	jobGroup.Post("/:job_id/retry", jobController.RetryDeadJob)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func LoyaltyRoutes(routeRegister *config.RoutesRegister) {
+	loyaltyRepo := repository.CreateNewLoyaltyRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	loyaltyUsecase := usecases.CreateNewLoyaltyUsecase(loyaltyRepo, unitOfWork)
+	loyaltyController := controller.CreateNewLoyaltyController(loyaltyUsecase)
+
+	application := routeRegister.Application
+	application.Get("/users/:id/points", middleware.AuthRequire, loyaltyController.GetHistory)
+}
@@ -13,12 +13,48 @@ func MachineRoutes(routeRegister *config.RoutesRegister) {
 	machineUsecase := usecases.CreateMachineUsecase(machineRepo)
 	machineController := controller.CreateMachineController(machineUsecase)
 
+	telemetryRepo := repository.CreateNewMachineTelemetryRepository(routeRegister.DbConnection)
+	orderDetailRepo := repository.CreateOrderDetailRepository(routeRegister.DbConnection)
+	jobRepo := repository.CreateNewJobRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	telemetryUsecase := usecases.CreateNewMachineTelemetryUsecase(telemetryRepo, machineRepo, orderDetailRepo, jobRepo, branchRepo, unitOfWork)
+	telemetryController := controller.CreateNewMachineTelemetryController(telemetryUsecase)
+
+	commandRepo := repository.CreateNewMachineCommandRepository(routeRegister.DbConnection)
+	orderHeaderRepo := repository.CreateOrderHeaderRepository(routeRegister.DbConnection)
+	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
+	contractRepo := repository.CreateNewEmployeeContractRepository(routeRegister.DbConnection)
+	washProgramRepo := repository.CreateNewWashProgramRepository(routeRegister.DbConnection)
+	commandUsecase := usecases.CreateNewMachineCommandUsecase(commandRepo, machineRepo, orderHeaderRepo, orderDetailRepo, paymentRepo, branchRepo, contractRepo, jobRepo, washProgramRepo, unitOfWork)
+	commandController := controller.CreateNewMachineCommandController(commandUsecase)
+
+	maintenanceRepo := repository.CreateNewMaintenanceRepository(routeRegister.DbConnection)
+	machineReportRepo := repository.CreateNewMachineReportRepository(routeRegister.DbConnection)
+	notificationRepo := repository.CreateNotificationRepository(routeRegister.DbConnection)
+	maintenanceUsecase := usecases.CreateNewMaintenanceUsecase(maintenanceRepo, machineReportRepo, machineRepo, branchRepo, notificationRepo, unitOfWork)
+	maintenanceController := controller.CreateNewMaintenanceController(maintenanceUsecase)
+
+	analyticsUsecase := usecases.CreateNewMachineAnalyticsUsecase(machineRepo, machineReportRepo, branchRepo)
+	analyticsController := controller.CreateNewMachineAnalyticsController(analyticsUsecase)
+
 	application := routeRegister.Application
 
+	// called by the machines, trusted by their device token instead of a user token
+	application.Post("/device/machine/:serial_id/telemetry", telemetryController.Report)
+	application.Get("/device/machine/:serial_id/commands", commandController.PollCommands)
+	application.Post("/device/machine/:serial_id/commands/:command_id/ack", commandController.AckCommand)
+
 	machineGroup := application.Group("/machine", middleware.AuthRequire)
 
 	machineGroup.Post("/add", middleware.IsBranchManager, machineController.AddMachine)
+	machineGroup.Post("/:serial_id/credential", middleware.IsBranchManager, telemetryController.IssueCredential)
+	machineGroup.Post("/:serial_id/command", middleware.IsEmployee, commandController.IssueCommand)
+	machineGroup.Get("/:serial_id/command", middleware.IsEmployee, commandController.GetCommands)
+	machineGroup.Put("/:serial_id/maintenance", middleware.IsBranchManager, maintenanceController.SetPlan)
+	machineGroup.Get("/:serial_id/maintenance", middleware.IsBranchManager, maintenanceController.GetPlan)
 	machineGroup.Get("/all", middleware.IsSuperAdmin, machineController.GetAll)
+	machineGroup.Get("/analytics", middleware.IsBranchManager, analyticsController.GetAnalytics)
 	machineGroup.Get("/detail/:serial_id", machineController.GetByMachineSerial)
 	machineGroup.Get("/available/branch/:branch_id", machineController.GetAvailableMachineInBranch)
 	machineGroup.Get("/branch/:branch_id", machineController.GetByBranchID)
@@ -13,7 +13,12 @@ func MachineReportRoutes(routeRegister *config.RoutesRegister) {
 	contractRepo := repository.CreateNewEmployeeContractRepository(routeRegister.DbConnection)
 	machineReportRepo := repository.CreateNewMachineReportRepository(routeRegister.DbConnection)
 	machineRepo := repository.CreateMachineRepository(routeRegister.DbConnection)
-	machineReportUsecase := usecases.CreateNewMachineReportUsecase(machineReportRepo, machineRepo, brachRepo, contractRepo)
+	orderDetailRepo := repository.CreateOrderDetailRepository(routeRegister.DbConnection)
+	orderEventRepo := repository.CreateOrderEventRepository(routeRegister.DbConnection)
+	notificationRepo := repository.CreateNotificationRepository(routeRegister.DbConnection)
+	maintenanceRepo := repository.CreateNewMaintenanceRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	machineReportUsecase := usecases.CreateNewMachineReportUsecase(machineReportRepo, machineRepo, brachRepo, contractRepo, orderDetailRepo, orderEventRepo, notificationRepo, maintenanceRepo, unitOfWork)
 	machineReportController := controller.CreateNewMachineReportController(machineReportUsecase)
 
 	application := routeRegister.Application
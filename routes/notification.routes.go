@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func NotificationRoutes(routeRegister *config.RoutesRegister) {
+	notificationRepo := repository.CreateNotificationRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	contractRepo := repository.CreateNewEmployeeContractRepository(routeRegister.DbConnection)
+	notificationUsecase := usecases.CreateNewNotificationUsecase(notificationRepo, branchRepo, contractRepo)
+	notificationController := controller.CreateNewNotificationController(notificationUsecase)
+
+	application := routeRegister.Application
+	notificationGroup := application.Group("/notification", middleware.AuthRequire, middleware.IsEmployee)
+	notificationGroup.Get("/branch/:branch_id", notificationController.GetByBranchID)
+	notificationGroup.Put("/read/:notification_id", notificationController.MarkRead)
+}
@@ -4,6 +4,7 @@ import (
 	"zuck-my-clothe/zuck-my-clothe-backend/config"
 	"zuck-my-clothe/zuck-my-clothe-backend/controller"
 	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/paymentgateway"
 	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
 )
@@ -13,22 +14,46 @@ func OrderRoutes(routeRegister *config.RoutesRegister) {
 	orderDetailRepo := repository.CreateOrderDetailRepository(routeRegister.DbConnection)
 	userRepo := repository.CreatenewUserRepository(routeRegister.DbConnection)
 
-	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
-	paymentUsecase := usecases.CreateNewPaymentUsecase(paymentRepo)
-
 	machineRepo := repository.CreateMachineRepository(routeRegister.DbConnection)
+	machineAssignment := usecases.CreateNewMachineAssignmentUsecase(orderDetailRepo, machineRepo)
+
+	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
+	refundRepo := repository.CreateNewRefundRepository(routeRegister.DbConnection)
+	walletRepo := repository.CreateNewWalletRepository(routeRegister.DbConnection)
+	paymentPolicyRepo := repository.CreateNewPaymentPolicyRepository(routeRegister.DbConnection)
+	paymentProvider := paymentgateway.CreateProvider(routeRegister.Config)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	jobRepo := repository.CreateNewJobRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
 	contractRepo := repository.CreateNewEmployeeContractRepository(routeRegister.DbConnection)
+	paymentUsecase := usecases.CreateNewPaymentUsecase(paymentRepo, refundRepo, walletRepo, paymentPolicyRepo, paymentProvider, unitOfWork, machineAssignment, jobRepo, orderHeaderRepo, branchRepo, contractRepo)
+
+	servicePriceRepo := repository.CreateNewServicePriceRepository(routeRegister.DbConnection)
+	priceLineRepo := repository.CreateOrderPriceLineRepository(routeRegister.DbConnection)
+	orderEventRepo := repository.CreateOrderEventRepository(routeRegister.DbConnection)
+	notificationRepo := repository.CreateNotificationRepository(routeRegister.DbConnection)
+	promoCodeRepo := repository.CreateNewPromoCodeRepository(routeRegister.DbConnection)
+	loyaltyRepo := repository.CreateNewLoyaltyRepository(routeRegister.DbConnection)
+	washProgramRepo := repository.CreateNewWashProgramRepository(routeRegister.DbConnection)
 
-	orderUsecase := usecases.CreateOrderUsecase(orderHeaderRepo, orderDetailRepo, userRepo, machineRepo, paymentUsecase, contractRepo)
+	orderUsecase := usecases.CreateOrderUsecase(orderHeaderRepo, orderDetailRepo, userRepo, machineRepo, paymentUsecase, contractRepo, unitOfWork, servicePriceRepo, priceLineRepo, orderEventRepo, notificationRepo, refundRepo, promoCodeRepo, loyaltyRepo, jobRepo, washProgramRepo, branchRepo)
 	orderController := controller.CreateOrderController(orderUsecase)
 
+	receiptRepo := repository.CreateNewReceiptRepository(routeRegister.DbConnection)
+	receiptUsecase := usecases.CreateNewReceiptUsecase(receiptRepo, paymentRepo, walletRepo, branchRepo, contractRepo, orderUsecase, unitOfWork)
+	receiptController := controller.CreateNewReceiptController(receiptUsecase, routeRegister.Config.RECEIPT_FONT_PATH)
+
 	application := routeRegister.Application
 
 	orderGroup := application.Group("/order", middleware.AuthRequire)
 
 	orderGroup.Post("/new", orderController.CreateNewOrder)
+	orderGroup.Post("/quote", orderController.QuoteOrder)
+	orderGroup.Post("/:order_header_id/cancel", orderController.CancelOrder)
 	orderGroup.Get("/all", middleware.IsSuperAdmin, orderController.GetAll)
 	orderGroup.Get("/branch/:branch_id", middleware.IsEmployee, orderController.GetByBranchID)
+	orderGroup.Get("/:order_header_id/timeline", orderController.GetTimeline)
+	orderGroup.Get("/:order_header_id/receipt", receiptController.GetReceipt)
 	orderGroup.Get("/:order_header_id/:option", orderController.GetByHeaderID)
 	orderGroup.Get("/me", orderController.GetByUserID)
 
@@ -4,18 +4,46 @@ import (
 	"zuck-my-clothe/zuck-my-clothe-backend/config"
 	"zuck-my-clothe/zuck-my-clothe-backend/controller"
 	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/paymentgateway"
 	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
 )
 
 func PaymentRoutes(routeRegister *config.RoutesRegister) {
 	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
-	paymentUsecase := usecases.CreateNewPaymentUsecase(paymentRepo)
+	orderHeaderRepo := repository.CreateOrderHeaderRepository(routeRegister.DbConnection)
+	orderDetailRepo := repository.CreateOrderDetailRepository(routeRegister.DbConnection)
+	machineRepo := repository.CreateMachineRepository(routeRegister.DbConnection)
+	machineAssignment := usecases.CreateNewMachineAssignmentUsecase(orderDetailRepo, machineRepo)
+	refundRepo := repository.CreateNewRefundRepository(routeRegister.DbConnection)
+	walletRepo := repository.CreateNewWalletRepository(routeRegister.DbConnection)
+	paymentPolicyRepo := repository.CreateNewPaymentPolicyRepository(routeRegister.DbConnection)
+	paymentProvider := paymentgateway.CreateProvider(routeRegister.Config)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	jobRepo := repository.CreateNewJobRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	contractRepo := repository.CreateNewEmployeeContractRepository(routeRegister.DbConnection)
+	paymentUsecase := usecases.CreateNewPaymentUsecase(paymentRepo, refundRepo, walletRepo, paymentPolicyRepo, paymentProvider, unitOfWork, machineAssignment, jobRepo, orderHeaderRepo, branchRepo, contractRepo)
 	paymentController := controller.CreateNewPaymentController(paymentUsecase)
 
+	paymentPolicyUsecase := usecases.CreateNewPaymentPolicyUsecase(paymentPolicyRepo, paymentRepo, orderHeaderRepo, branchRepo, contractRepo, unitOfWork)
+	paymentPolicyController := controller.CreateNewPaymentPolicyController(paymentPolicyUsecase)
+	paymentReportUsecase := usecases.CreateNewPaymentReportUsecase(paymentRepo, refundRepo, branchRepo)
+	paymentReportController := controller.CreateNewPaymentReportController(paymentReportUsecase)
+
 	application := routeRegister.Application
+
+	// called by the payment provider, trusted by its signature instead of a user token
+	application.Post("/webhook/payment", paymentController.HandleWebhook)
+
 	paymentGroup := application.Group("/payment", middleware.AuthRequire)
+	paymentGroup.Get("/report", middleware.IsBranchManager, paymentReportController.GetDailyReport)
 	paymentGroup.Post("/add", paymentController.CreatePayment)
 	paymentGroup.Get("/detail/:paymentID", paymentController.FindByPaymentID)
-	paymentGroup.Put("/update/:paymentID/setstatus/:status", paymentController.UpdatePaymenstatus)
+	paymentGroup.Post("/:paymentID/charge", paymentController.CreateCharge)
+	paymentGroup.Get("/:paymentID/qr", paymentController.GetQR)
+	paymentGroup.Post("/:paymentID/extend", middleware.IsEmployee, paymentPolicyController.ExtendPayment)
+	paymentGroup.Put("/policy", middleware.IsBranchManager, paymentPolicyController.SetPolicy)
+	paymentGroup.Get("/policy/branch/:branch_id", paymentPolicyController.FindByBranchID)
+	paymentGroup.Put("/update/:paymentID/setstatus/:status", middleware.IsEmployee, paymentController.UpdatePaymenstatus)
 }
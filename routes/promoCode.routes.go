@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func PromoCodeRoutes(routeRegister *config.RoutesRegister) {
+	promoCodeRepo := repository.CreateNewPromoCodeRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	promoCodeUsecase := usecases.CreateNewPromoCodeUsecase(promoCodeRepo, branchRepo)
+	promoCodeController := controller.CreateNewPromoCodeController(promoCodeUsecase)
+
+	application := routeRegister.Application
+	promoCodeGroup := application.Group("/promo", middleware.AuthRequire, middleware.IsSuperAdmin)
+	promoCodeGroup.Post("/add", promoCodeController.CreatePromoCode)
+	promoCodeGroup.Get("/all", promoCodeController.GetAll)
+	promoCodeGroup.Get("/:promo_code_id/report", promoCodeController.GetReport)
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func RefundRoutes(routeRegister *config.RoutesRegister) {
+	refundRepo := repository.CreateNewRefundRepository(routeRegister.DbConnection)
+	walletRepo := repository.CreateNewWalletRepository(routeRegister.DbConnection)
+	paymentRepo := repository.CreateNewPaymentRepository(routeRegister.DbConnection)
+	orderHeaderRepo := repository.CreateOrderHeaderRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	refundUsecase := usecases.CreateNewRefundUsecase(refundRepo, walletRepo, paymentRepo, orderHeaderRepo, branchRepo, unitOfWork)
+	refundController := controller.CreateNewRefundController(refundUsecase)
+
+	application := routeRegister.Application
+	refundGroup := application.Group("/refund", middleware.AuthRequire, middleware.IsBranchManager)
+	refundGroup.Post("/payment/:paymentID", refundController.CreateRefund)
+	refundGroup.Get("/payment/:paymentID", refundController.GetByPaymentID)
+	refundGroup.Put("/:refund_id/complete", refundController.CompleteRefund)
+	refundGroup.Put("/:refund_id/reject", refundController.RejectRefund)
+}
@@ -12,4 +12,12 @@ func RoutesRegister(routeRegister *config.RoutesRegister) {
 	UserAddressesRoutes(routeRegister)
 	EmployeeContractRoutes(routeRegister)
 	MachineReportRoutes(routeRegister)
+	ServicePriceRoutes(routeRegister)
+	WashProgramRoutes(routeRegister)
+	NotificationRoutes(routeRegister)
+	RefundRoutes(routeRegister)
+	WalletRoutes(routeRegister)
+	PromoCodeRoutes(routeRegister)
+	LoyaltyRoutes(routeRegister)
+	JobRoutes(routeRegister)
 }
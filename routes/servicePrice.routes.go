@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func ServicePriceRoutes(routeRegister *config.RoutesRegister) {
+	servicePriceRepo := repository.CreateNewServicePriceRepository(routeRegister.DbConnection)
+	branchRepo := repository.CreateNewBranchRepository(routeRegister.DbConnection)
+	servicePriceUsecase := usecases.CreateNewServicePriceUsecase(servicePriceRepo, branchRepo)
+	servicePriceController := controller.CreateNewServicePriceController(servicePriceUsecase)
+
+	application := routeRegister.Application
+	servicePriceGroup := application.Group("/price", middleware.AuthRequire)
+	servicePriceGroup.Post("/add", middleware.IsBranchManager, servicePriceController.CreateServicePrice)
+	servicePriceGroup.Get("/branch/:branch_id", servicePriceController.FindByBranchID)
+	servicePriceGroup.Put("/update", middleware.IsBranchManager, servicePriceController.UpdateServicePrice)
+	servicePriceGroup.Delete("/delete/:price_id", middleware.IsBranchManager, servicePriceController.DeleteServicePrice)
+}
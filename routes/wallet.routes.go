@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func WalletRoutes(routeRegister *config.RoutesRegister) {
+	walletRepo := repository.CreateNewWalletRepository(routeRegister.DbConnection)
+	userRepo := repository.CreatenewUserRepository(routeRegister.DbConnection)
+	unitOfWork := repository.CreateNewUnitOfWork(routeRegister.DbConnection)
+	walletUsecase := usecases.CreateNewWalletUsecase(walletRepo, userRepo, unitOfWork)
+	walletController := controller.CreateNewWalletController(walletUsecase)
+
+	application := routeRegister.Application
+	walletGroup := application.Group("/wallet", middleware.AuthRequire)
+	walletGroup.Get("/me", walletController.GetMyStatement)
+	walletGroup.Get("/statement/:user_id", middleware.IsEmployee, walletController.GetStatement)
+	walletGroup.Post("/topup", middleware.IsBranchManager, walletController.TopUp)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/config"
+	"zuck-my-clothe/zuck-my-clothe-backend/controller"
+	"zuck-my-clothe/zuck-my-clothe-backend/middleware"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+	"zuck-my-clothe/zuck-my-clothe-backend/usecases"
+)
+
+func WashProgramRoutes(routeRegister *config.RoutesRegister) {
+	washProgramRepo := repository.CreateNewWashProgramRepository(routeRegister.DbConnection)
+	washProgramUsecase := usecases.CreateNewWashProgramUsecase(washProgramRepo)
+	washProgramController := controller.CreateNewWashProgramController(washProgramUsecase)
+
+	application := routeRegister.Application
+	washProgramGroup := application.Group("/program", middleware.AuthRequire)
+	washProgramGroup.Post("/add", middleware.IsSuperAdmin, washProgramController.CreateProgram)
+	washProgramGroup.Get("/machine_type/:machine_type", washProgramController.FindByMachineType)
+	washProgramGroup.Put("/update", middleware.IsSuperAdmin, washProgramController.UpdateProgram)
+	washProgramGroup.Delete("/delete/:program_id", middleware.IsSuperAdmin, washProgramController.DeleteProgram)
+}
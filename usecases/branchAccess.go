@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"errors"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+)
+
+// checkBranchStaff allows super admin, the branch owner and employees with a contract in the branch
+func checkBranchStaff(branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository, branchID string, userID string, userRole string) error {
+	if userRole == string(model.SuperAdmin) {
+		return nil
+	}
+
+	branch, err := branchRepo.GetByBranchID(branchID)
+	if err != nil {
+		return err
+	}
+
+	if branch.OwnerUserID == userID {
+		return nil
+	}
+
+	contracts, err := contractRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, ec := range *contracts {
+		if ec.BranchID == branchID {
+			return nil
+		}
+	}
+
+	return errors.New("ERR 403: not a staff of this branch")
+}
+
+// checkBranchOwner allows super admin and the branch manager who owns the branch
+func checkBranchOwner(branchRepo repository.BranchReopository, branchID string, userID string, userRole string) error {
+	if userRole == string(model.SuperAdmin) {
+		return nil
+	}
+
+	branch, err := branchRepo.GetByBranchID(branchID)
+	if err != nil {
+		return err
+	}
+
+	if branch.OwnerUserID != userID {
+		return errors.New("ERR 403: forbidden manager try to access unautherized branch")
+	}
+
+	return nil
+}
+
+// checkDefaultOrBranchOwner is checkBranchOwner for settings of one branch or,
+// without a branch, the default of every branch which only super admin manages
+func checkDefaultOrBranchOwner(branchRepo repository.BranchReopository, branchID *string, userID string, userRole string) error {
+	if branchID == nil {
+		if userRole == string(model.SuperAdmin) {
+			return nil
+		}
+		return errors.New("ERR 403: only super admin can manage the default of every branch")
+	}
+
+	return checkBranchOwner(branchRepo, *branchID, userID, userRole)
+}
+
+// checkOrderAccess allows the customer who placed the order and staff of the branch it was placed at
+func checkOrderAccess(branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository, orderUserID string, branchID string, userID string, userRole string) error {
+	if orderUserID == userID {
+		return nil
+	}
+
+	if userRole == string(model.Client) {
+		return errors.New("ERR 403: not your order")
+	}
+
+	return checkBranchStaff(branchRepo, contractRepo, branchID, userID, userRole)
+}
@@ -40,6 +40,8 @@ func (u *branchUsecase) CreateBranch(newBranch *model.CreateBranch, userID strin
 		BranchLon:    newBranch.BranchLon,
 		CreatedBy:    userID,
 		OwnerUserID:  newBranch.OwnerUserID,
+		PromptPayID:  newBranch.PromptPayID,
+		TaxID:        newBranch.TaxID,
 		UpdatedBy:    userID,
 		DeletedBy:    nil,
 	}
@@ -163,6 +165,8 @@ func (u *branchUsecase) UpdateBranch(branch *model.UpdateBranch, role string) (*
 		BranchLat:    branch.BranchLat,
 		BranchLon:    branch.BranchLon,
 		OwnerUserID:  branch.OwnerUserID,
+		PromptPayID:  branch.PromptPayID,
+		TaxID:        branch.TaxID,
 	}
 
 	if role == "SuperAdmin" {
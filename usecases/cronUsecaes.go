@@ -1,34 +1,68 @@
 package usecases
 
 import (
+	"log"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
-	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 )
 
 type KonCronUsecase interface {
-	CleanupExpiredPayment() error
-	CleanUpExpiredOrder() error
-	CompleteZuckProcess() error
+	ScheduleMissingJobs() error
+	AssignWaitingBasket() error
+	CreditLoyaltyPoints() error
+	ExpireLoyaltyPoints() error
+	OpenDueMaintenance() error
 }
 
 type cronUsecase struct {
-	paymentRepo     model.PaymentRepository
-	orderDetailRepo repository.OrderDetailRepository
+	jobRepo           model.JobRepository
+	machineAssignment MachineAssignmentUsecase
+	loyaltyUsecase    model.LoyaltyUsecase
+	maintenance       model.MaintenanceUsecase
 }
 
-func CreateNewKonCronUsecase(paymentRepo model.PaymentRepository, orderDetailRepo repository.OrderDetailRepository) KonCronUsecase {
-	return &cronUsecase{paymentRepo: paymentRepo,
-		orderDetailRepo: orderDetailRepo}
+func CreateNewKonCronUsecase(jobRepo model.JobRepository, machineAssignment MachineAssignmentUsecase, loyaltyUsecase model.LoyaltyUsecase, maintenance model.MaintenanceUsecase) KonCronUsecase {
+	return &cronUsecase{jobRepo: jobRepo,
+		machineAssignment: machineAssignment,
+		loyaltyUsecase:    loyaltyUsecase,
+		maintenance:       maintenance}
 }
 
-func (u *cronUsecase) CleanupExpiredPayment() error {
-	return u.paymentRepo.CleanupExpiredPayment()
+// ScheduleMissingJobs is the safety net of the job queue, payments and baskets
+// are normally scheduled when they're created
+func (u *cronUsecase) ScheduleMissingJobs() error {
+	scheduled, err := u.jobRepo.EnqueueMissing()
+	if err != nil {
+		return err
+	}
+
+	if scheduled > 0 {
+		log.Println("jobs scheduled for", scheduled, "payments and baskets that had none")
+	}
+
+	return nil
+}
+
+func (u *cronUsecase) AssignWaitingBasket() error {
+	return u.machineAssignment.AssignWaitingBasket()
 }
 
-func (u *cronUsecase) CleanUpExpiredOrder() error {
-	return u.orderDetailRepo.CleanUpExpiredOrder()
+func (u *cronUsecase) CreditLoyaltyPoints() error {
+	return u.loyaltyUsecase.CreditCompletedOrders()
 }
 
-func (u *cronUsecase) CompleteZuckProcess() error {
-	return u.orderDetailRepo.CompleteZuckProcess()
+func (u *cronUsecase) ExpireLoyaltyPoints() error {
+	return u.loyaltyUsecase.ExpirePoints()
+}
+
+func (u *cronUsecase) OpenDueMaintenance() error {
+	opened, err := u.maintenance.OpenDueMaintenance()
+	if err != nil {
+		return err
+	}
+
+	if opened > 0 {
+		log.Println("preventive maintenance opened for", opened, "machines")
+	}
+
+	return nil
 }
@@ -0,0 +1,273 @@
+package usecases
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	jobFirstBackoff = 10 * time.Second
+	jobMaxBackoff   = time.Hour
+)
+
+type jobUsecase struct {
+	jobRepo         model.JobRepository
+	paymentRepo     model.PaymentRepository
+	policyRepo      model.PaymentPolicyRepository
+	orderDetailRepo repository.OrderDetailRepository
+	unitOfWork      repository.UnitOfWork
+	subscribers     map[model.JobEvent]map[string]model.JobHook
+	handlers        map[model.JobType]model.JobHandler
+}
+
+func CreateNewJobUsecase(jobRepo model.JobRepository, paymentRepo model.PaymentRepository, policyRepo model.PaymentPolicyRepository, orderDetailRepo repository.OrderDetailRepository, unitOfWork repository.UnitOfWork) model.JobUsecase {
+	return &jobUsecase{
+		jobRepo:         jobRepo,
+		paymentRepo:     paymentRepo,
+		policyRepo:      policyRepo,
+		orderDetailRepo: orderDetailRepo,
+		unitOfWork:      unitOfWork,
+		subscribers:     map[model.JobEvent]map[string]model.JobHook{},
+		handlers:        map[model.JobType]model.JobHandler{},
+	}
+}
+
+// jobBackoff is how long a job waits after its nth failed attempt,
+// doubling from jobFirstBackoff up to jobMaxBackoff
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobFirstBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= jobMaxBackoff {
+			return jobMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Subscribe calls hook every time event happens. Subscribers are told through
+// their own job, so a failing subscriber is retried without bothering the others.
+// Everyone must subscribe before jobs start running.
+func (u *jobUsecase) Subscribe(event model.JobEvent, subscriber string, hook model.JobHook) {
+	if u.subscribers[event] == nil {
+		u.subscribers[event] = map[string]model.JobHook{}
+	}
+	u.subscribers[event][subscriber] = hook
+}
+
+// Handle runs jobs of jobType with handler, for jobs the job module doesn't know itself.
+// Like Subscribe it must be called before jobs start running.
+func (u *jobUsecase) Handle(jobType model.JobType, handler model.JobHandler) {
+	u.handlers[jobType] = handler
+}
+
+// publish schedules a hook job for every subscriber of event, in the transaction of jobRepo
+func (u *jobUsecase) publish(jobRepo model.JobRepository, event model.JobEvent, subjectID string) error {
+	subscribers := []string{}
+	for subscriber := range u.subscribers[event] {
+		subscribers = append(subscribers, subscriber)
+	}
+	sort.Strings(subscribers)
+
+	for _, subscriber := range subscribers {
+		job := model.NewHookJob(event, subscriber, subjectID)
+		if err := jobRepo.Enqueue(&job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expirePayment expires the payment and its waiting baskets once the grace period
+// of its policy is over too. The payment may have been paid or extended since
+// the job was scheduled, so it's looked at again under its lock.
+func (u *jobUsecase) expirePayment(job *model.Job) error {
+	return u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		jobRepo := u.jobRepo.WithTx(tx)
+		paymentRepo := u.paymentRepo.WithTx(tx)
+
+		payment, err := paymentRepo.LockPayment(job.SubjectID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobRepo.Complete(job)
+		} else if err != nil {
+			return err
+		}
+
+		if payment.Payment_Status != model.Pending {
+			return jobRepo.Complete(job)
+		}
+
+		expireAt := payment.DueDate
+		if payment.PolicyID != nil {
+			policy, err := u.policyRepo.FindByPolicyID(*payment.PolicyID)
+			if err != nil {
+				return err
+			}
+			expireAt = expireAt.Add(time.Minute * time.Duration(policy.GraceMinutes))
+		}
+
+		if time.Now().UTC().Before(expireAt) {
+			return jobRepo.Reschedule(job, expireAt)
+		}
+
+		if err := paymentRepo.ExpirePayment(payment.PaymentID); err != nil {
+			return err
+		}
+
+		if err := u.orderDetailRepo.WithTx(tx).ExpireByPaymentID(payment.PaymentID); err != nil {
+			return err
+		}
+
+		if err := u.publish(jobRepo, model.PaymentExpiredEvent, payment.PaymentID); err != nil {
+			return err
+		}
+
+		return jobRepo.Complete(job)
+	})
+}
+
+// completeBasket completes the basket once its machine is done. Staff or the machine
+// may have moved the finish time since the job was scheduled, then the job follows it.
+func (u *jobUsecase) completeBasket(job *model.Job) error {
+	return u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		jobRepo := u.jobRepo.WithTx(tx)
+		orderDetailRepo := u.orderDetailRepo.WithTx(tx)
+
+		completed, err := orderDetailRepo.CompleteBasket(job.SubjectID)
+		if err != nil {
+			return err
+		}
+
+		if completed {
+			if err := u.publish(jobRepo, model.BasketCompletedEvent, job.SubjectID); err != nil {
+				return err
+			}
+			return jobRepo.Complete(job)
+		}
+
+		detail, err := orderDetailRepo.GetDetail(job.SubjectID)
+		if err != nil {
+			return err
+		}
+
+		if detail.OrderStatus != model.Processing || detail.FinishedAt == nil {
+			return jobRepo.Complete(job)
+		}
+
+		// the machine reports it's still running past the finish time or is out of service, look again
+		// when that report would be stale in case the machine goes quiet
+		runAt := *detail.FinishedAt
+		if now := time.Now().UTC(); !runAt.After(now) {
+			runAt = now.Add(model.MachineStateStale)
+		}
+
+		return jobRepo.Reschedule(job, runAt)
+	})
+}
+
+func (u *jobUsecase) runHook(job *model.Job) error {
+	if job.Event == nil || job.Subscriber == nil {
+		return errors.New("ERR: hook job without event or subscriber")
+	}
+
+	hook, ok := u.subscribers[*job.Event][*job.Subscriber]
+	if !ok {
+		return errors.New("ERR: " + *job.Subscriber + " is not subscribed to " + string(*job.Event))
+	}
+
+	if err := hook(job.SubjectID); err != nil {
+		return err
+	}
+
+	return u.jobRepo.Complete(job)
+}
+
+func (u *jobUsecase) runJob(job *model.Job) error {
+	switch job.JobType {
+	case model.ExpirePaymentJob:
+		return u.expirePayment(job)
+	case model.CompleteBasketJob:
+		return u.completeBasket(job)
+	case model.HookJob:
+		return u.runHook(job)
+	}
+
+	handler, ok := u.handlers[job.JobType]
+	if !ok {
+		return errors.New("ERR: unknown job type " + string(job.JobType))
+	}
+
+	runAt, err := handler(job.SubjectID)
+	if err != nil {
+		return err
+	}
+
+	if runAt != nil {
+		return u.jobRepo.Reschedule(job, *runAt)
+	}
+
+	return u.jobRepo.Complete(job)
+}
+
+// failJob schedules the next attempt of a failed job, or buries it when it's out of attempts
+func (u *jobUsecase) failJob(job *model.Job, jobErr error) error {
+	if job.Attempts >= job.MaxAttempts {
+		return u.jobRepo.Bury(job, jobErr.Error())
+	}
+	return u.jobRepo.Retry(job, time.Now().UTC().Add(jobBackoff(job.Attempts)), jobErr.Error())
+}
+
+// RunDueJobs runs up to limit jobs that are due and returns how many it took,
+// a failed job is retried later and doesn't stop the others
+func (u *jobUsecase) RunDueJobs(limit int) (int, error) {
+	jobs, err := u.jobRepo.ClaimDue(limit, model.JobLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range *jobs {
+		job := &(*jobs)[i]
+
+		jobErr := u.runJob(job)
+		if jobErr == nil {
+			continue
+		}
+
+		log.Println("ERR: job", job.JobID, job.JobType, job.JobKey, "attempt", job.Attempts, "failed", jobErr)
+		if err := u.failJob(job, jobErr); err != nil {
+			log.Println("ERR: cannot record failure of job", job.JobID, err)
+		}
+	}
+
+	return len(*jobs), nil
+}
+
+func (u *jobUsecase) GetDeadJobs() (*[]model.Job, error) {
+	return u.jobRepo.GetDead()
+}
+
+// RetryDeadJob gives a dead job another round of attempts, starting now
+func (u *jobUsecase) RetryDeadJob(jobID string) (*model.Job, error) {
+	job, err := u.jobRepo.FindByJobID(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != model.JobDead {
+		return nil, errors.New("ERR 400: only dead jobs can be retried, this one is " + string(job.Status))
+	}
+
+	if err := u.jobRepo.Revive(jobID); err != nil {
+		return nil, err
+	}
+
+	return u.jobRepo.FindByJobID(jobID)
+}
@@ -0,0 +1,293 @@
+package usecases
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+// fakeJobRepo is shared by tests that pay concurrently, so it's locked
+type fakeJobRepo struct {
+	model.JobRepository
+	mu       sync.Mutex
+	due      []model.Job
+	jobs     map[string]model.Job
+	enqueued []model.Job
+}
+
+func (f *fakeJobRepo) WithTx(tx *platform.Postgres) model.JobRepository { return f }
+
+func (f *fakeJobRepo) Enqueue(job *model.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enqueued = append(f.enqueued, *job)
+	return nil
+}
+
+func (f *fakeJobRepo) ClaimDue(limit int, lease time.Duration) (*[]model.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claimed := []model.Job{}
+	for _, job := range f.due {
+		job.Status = model.JobRunning
+		job.Attempts++
+		claimed = append(claimed, job)
+	}
+	return &claimed, nil
+}
+
+func (f *fakeJobRepo) Complete(job *model.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = model.JobDone
+	f.jobs[job.JobID] = *job
+	return nil
+}
+
+func (f *fakeJobRepo) Reschedule(job *model.Job, runAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = model.JobPending
+	job.RunAt = runAt
+	job.Attempts--
+	f.jobs[job.JobID] = *job
+	return nil
+}
+
+func (f *fakeJobRepo) Retry(job *model.Job, runAt time.Time, lastError string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = model.JobPending
+	job.RunAt = runAt
+	job.LastError = &lastError
+	f.jobs[job.JobID] = *job
+	return nil
+}
+
+func (f *fakeJobRepo) Bury(job *model.Job, lastError string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job.Status = model.JobDead
+	job.LastError = &lastError
+	f.jobs[job.JobID] = *job
+	return nil
+}
+
+type fakeJobOrderDetailRepo struct {
+	repository.OrderDetailRepository
+	details        map[string]*model.OrderDetail
+	expiredPayment []string
+}
+
+func (f *fakeJobOrderDetailRepo) WithTx(tx *platform.Postgres) repository.OrderDetailRepository {
+	return f
+}
+
+func (f *fakeJobOrderDetailRepo) ExpireByPaymentID(paymentID string) error {
+	f.expiredPayment = append(f.expiredPayment, paymentID)
+	return nil
+}
+
+func (f *fakeJobOrderDetailRepo) CompleteBasket(orderBasketID string) (bool, error) {
+	detail := f.details[orderBasketID]
+	if detail.OrderStatus != model.Processing || detail.FinishedAt.After(time.Now().UTC()) {
+		return false, nil
+	}
+	detail.OrderStatus = model.Completed
+	return true, nil
+}
+
+func (f *fakeJobOrderDetailRepo) GetDetail(orderBasketID string) (*model.OrderDetail, error) {
+	detail := *f.details[orderBasketID]
+	return &detail, nil
+}
+
+func (f *fakeJobOrderDetailRepo) StartCycle(orderBasketID string, startedAt time.Time, finishedAt time.Time) error {
+	f.details[orderBasketID].StartedAt = &startedAt
+	f.details[orderBasketID].FinishedAt = &finishedAt
+	return nil
+}
+
+func (f *fakeJobOrderDetailRepo) GetByHeaderID(orderHeaderID string, isAdminView bool) (*[]model.OrderDetail, error) {
+	details := []model.OrderDetail{}
+	for _, detail := range f.details {
+		details = append(details, *detail)
+	}
+	return &details, nil
+}
+
+func (f *fakeJobOrderDetailRepo) GetProcessingByMachine(machineSerial string) (*model.OrderDetail, error) {
+	for _, detail := range f.details {
+		if detail.MachineSerial != nil && *detail.MachineSerial == machineSerial && detail.OrderStatus == model.Processing {
+			found := *detail
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeJobOrderDetailRepo) UpdateFinishedAt(orderBasketID string, finishedAt time.Time) error {
+	f.details[orderBasketID].FinishedAt = &finishedAt
+	return nil
+}
+
+func (f *fakeJobOrderDetailRepo) ReleaseMachine(machineSerial string) (int64, error) {
+	var released int64
+	for _, detail := range f.details {
+		if detail.MachineSerial != nil && *detail.MachineSerial == machineSerial && detail.OrderStatus == model.Waiting {
+			detail.MachineSerial = nil
+			released++
+		}
+	}
+	return released, nil
+}
+
+func (f *fakePaymentRepo) ExpirePayment(paymentID string) error {
+	f.payments[paymentID].Payment_Status = model.Expired
+	return nil
+}
+
+func (f *fakePaymentPolicyRepo) FindByPolicyID(policyID string) (*model.PaymentPolicy, error) {
+	for i, policy := range f.policies {
+		if policy.PolicyID == policyID {
+			return &f.policies[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, test := range tests {
+		backoff := jobBackoff(test.attempts)
+		if backoff != test.backoff {
+			t.Errorf("For input '%d', expected %v, but got %v", test.attempts, test.backoff, backoff)
+		}
+	}
+}
+
+func TestRunDueJobs(t *testing.T) {
+	now := time.Now().UTC()
+	overdue := now.Add(-time.Minute)
+	graceOver := overdue.Add(5 * time.Minute)
+	later := now.Add(time.Hour)
+	policyID := "policy-1"
+
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"pay-overdue":  {PaymentID: "pay-overdue", Payment_Status: model.Pending, DueDate: overdue},
+		"pay-in-grace": {PaymentID: "pay-in-grace", Payment_Status: model.Pending, DueDate: overdue, PolicyID: &policyID},
+		"pay-paid":     {PaymentID: "pay-paid", Payment_Status: model.Paid, DueDate: overdue},
+	}}
+	policyRepo := &fakePaymentPolicyRepo{policies: []model.PaymentPolicy{{PolicyID: policyID, GraceMinutes: 5}}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{
+		"basket-done":    {OrderBasketID: "basket-done", OrderStatus: model.Processing, ServiceType: model.Washing, FinishedAt: &overdue},
+		"basket-running": {OrderBasketID: "basket-running", OrderStatus: model.Processing, ServiceType: model.Drying, FinishedAt: &later},
+	}}
+
+	hookFailed := func(subjectID string) error { return errors.New("ERR: machine api is down") }
+	failingHook := model.NewHookJob(model.BasketCompletedEvent, "broken", "basket-old")
+	lastHook := model.NewHookJob(model.BasketCompletedEvent, "broken", "basket-older")
+	lastHook.Attempts = model.JobMaxAttempts - 1
+	unknownHook := model.NewHookJob(model.PaymentExpiredEvent, "gone", "pay-old")
+
+	jobs := []model.Job{
+		model.NewJob(model.ExpirePaymentJob, "pay-overdue", overdue),
+		model.NewJob(model.ExpirePaymentJob, "pay-in-grace", overdue),
+		model.NewJob(model.ExpirePaymentJob, "pay-paid", overdue),
+		model.NewJob(model.CompleteBasketJob, "basket-done", overdue),
+		model.NewJob(model.CompleteBasketJob, "basket-running", overdue),
+		failingHook,
+		lastHook,
+		unknownHook,
+	}
+	jobRepo := &fakeJobRepo{due: jobs, jobs: map[string]model.Job{}}
+
+	u := CreateNewJobUsecase(jobRepo, paymentRepo, policyRepo, orderDetailRepo, &fakeUnitOfWork{})
+	u.Subscribe(model.PaymentExpiredEvent, "assign-machine", func(subjectID string) error { return nil })
+	u.Subscribe(model.BasketCompletedEvent, "assign-machine", func(subjectID string) error { return nil })
+	u.Subscribe(model.BasketCompletedEvent, "loyalty-points", func(subjectID string) error { return nil })
+	u.Subscribe(model.BasketCompletedEvent, "broken", hookFailed)
+
+	ran, err := u.RunDueJobs(10)
+	if err != nil || ran != len(jobs) {
+		t.Fatalf("expected %d jobs to run, but got %d, %v", len(jobs), ran, err)
+	}
+
+	tests := []struct {
+		name     string
+		job      model.Job
+		status   model.JobStatus
+		runAt    *time.Time
+		attempts int
+	}{
+		{"overdue payment expires", jobs[0], model.JobDone, nil, 1},
+		{"payment in its grace period waits for it", jobs[1], model.JobPending, &graceOver, 0},
+		{"paid payment has nothing to do", jobs[2], model.JobDone, nil, 1},
+		{"finished basket completes", jobs[3], model.JobDone, nil, 1},
+		{"basket still running follows its finish time", jobs[4], model.JobPending, &later, 0},
+		{"failed hook is retried", jobs[5], model.JobPending, nil, 1},
+		{"hook out of attempts is dead", jobs[6], model.JobDead, nil, model.JobMaxAttempts},
+		{"hook nobody subscribes to is retried", jobs[7], model.JobPending, nil, 1},
+	}
+
+	for _, test := range tests {
+		job, ok := jobRepo.jobs[test.job.JobID]
+		if !ok {
+			t.Errorf("%s: expected the job to be finished, but it was left running", test.name)
+			continue
+		}
+		if job.Status != test.status || job.Attempts != test.attempts {
+			t.Errorf("%s: expected %s after %d attempts, but got %s after %d", test.name, test.status, test.attempts, job.Status, job.Attempts)
+		}
+		if test.runAt != nil && !job.RunAt.Equal(*test.runAt) {
+			t.Errorf("%s: expected to run at %v, but got %v", test.name, *test.runAt, job.RunAt)
+		}
+		if test.status == model.JobPending && test.runAt == nil && (job.LastError == nil || !job.RunAt.After(now)) {
+			t.Errorf("%s: expected a later retry with the error, but got %v at %v", test.name, job.LastError, job.RunAt)
+		}
+	}
+
+	if paymentRepo.payments["pay-overdue"].Payment_Status != model.Expired || paymentRepo.payments["pay-in-grace"].Payment_Status != model.Pending {
+		t.Errorf("expected only the overdue payment to expire, but got %s and %s", paymentRepo.payments["pay-overdue"].Payment_Status, paymentRepo.payments["pay-in-grace"].Payment_Status)
+	}
+	if len(orderDetailRepo.expiredPayment) != 1 || orderDetailRepo.expiredPayment[0] != "pay-overdue" {
+		t.Errorf("expected the baskets of pay-overdue to expire, but got %v", orderDetailRepo.expiredPayment)
+	}
+
+	hooks := []string{}
+	for _, job := range jobRepo.enqueued {
+		hooks = append(hooks, job.JobKey)
+	}
+	expected := []string{
+		"PaymentExpired:assign-machine:pay-overdue",
+		"BasketCompleted:assign-machine:basket-done",
+		"BasketCompleted:broken:basket-done",
+		"BasketCompleted:loyalty-points:basket-done",
+	}
+	if len(hooks) != len(expected) {
+		t.Fatalf("expected hook jobs %v, but got %v", expected, hooks)
+	}
+	for i := range expected {
+		if hooks[i] != expected[i] {
+			t.Errorf("expected hook jobs %v, but got %v", expected, hooks)
+			break
+		}
+	}
+}
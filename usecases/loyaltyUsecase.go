@@ -0,0 +1,131 @@
+package usecases
+
+import (
+	"errors"
+	"log"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type loyaltyUsecase struct {
+	loyaltyRepo model.LoyaltyRepository
+	unitOfWork  repository.UnitOfWork
+}
+
+func CreateNewLoyaltyUsecase(loyaltyRepo model.LoyaltyRepository, unitOfWork repository.UnitOfWork) model.LoyaltyUsecase {
+	return &loyaltyUsecase{
+		loyaltyRepo: loyaltyRepo,
+		unitOfWork:  unitOfWork,
+	}
+}
+
+// pointsDiscount is how many baht points take off an order
+func pointsDiscount(points int64) float64 {
+	return float64(toSatang(float64(points)*model.PointValue)) / 100
+}
+
+// redeemPoints spends points of the user on an order. loyaltyRepo must be
+// in a transaction, the account stays locked until it ends.
+func redeemPoints(loyaltyRepo model.LoyaltyRepository, userID string, orderHeaderID string, points int64) error {
+	if err := loyaltyRepo.LockAccount(userID); err != nil {
+		return err
+	}
+
+	if err := loyaltyRepo.ExpirePoints(userID); err != nil {
+		return err
+	}
+
+	balance, err := loyaltyRepo.GetBalance(userID)
+	if err != nil {
+		return err
+	}
+
+	if balance < points {
+		return errors.New("ERR 400: not enough points")
+	}
+
+	transaction := model.LoyaltyTransaction{
+		TransactionID:   uuid.New().String(),
+		UserID:          userID,
+		TransactionType: model.PointsRedeemed,
+		Points:          -points,
+		OrderHeaderID:   &orderHeaderID,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	return loyaltyRepo.CreateTransaction(&transaction)
+}
+
+// CreditCompletedOrders is run by the cron, an order only ever earns once
+func (u *loyaltyUsecase) CreditCompletedOrders() error {
+	credited, err := u.loyaltyRepo.CreditCompletedOrders()
+	if err != nil {
+		return err
+	}
+
+	if credited > 0 {
+		log.Println("loyalty points credited for", credited, "orders")
+	}
+
+	return nil
+}
+
+func (u *loyaltyUsecase) expireUserPoints(userID string) error {
+	return u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		if err := u.loyaltyRepo.WithTx(tx).LockAccount(userID); err != nil {
+			return err
+		}
+
+		return u.loyaltyRepo.WithTx(tx).ExpirePoints(userID)
+	})
+}
+
+// ExpirePoints is run by the cron, points are also expired whenever they are read or spent
+func (u *loyaltyUsecase) ExpirePoints() error {
+	userIDs, err := u.loyaltyRepo.FindUsersWithExpiredPoints()
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := u.expireUserPoints(userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetHistory returns the points balance and ledger newest first,
+// customers can only see their own
+func (u *loyaltyUsecase) GetHistory(userID string, requesterID string, requesterRole string) (*model.LoyaltyHistory, error) {
+	if requesterRole == string(model.Client) && userID != requesterID {
+		return nil, errors.New("ERR 403: can't see the points of another user")
+	}
+
+	if err := u.expireUserPoints(userID); err != nil {
+		return nil, err
+	}
+
+	balance, err := u.loyaltyRepo.GetBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := u.loyaltyRepo.GetTransactions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := model.LoyaltyHistory{
+		UserID:       userID,
+		Balance:      balance,
+		Transactions: *transactions,
+	}
+
+	return &history, nil
+}
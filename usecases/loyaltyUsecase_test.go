@@ -0,0 +1,105 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+type fakeLoyaltyRepo struct {
+	model.LoyaltyRepository
+	locked       []string
+	expired      []string
+	transactions []model.LoyaltyTransaction
+}
+
+func (f *fakeLoyaltyRepo) WithTx(tx *platform.Postgres) model.LoyaltyRepository { return f }
+
+func (f *fakeLoyaltyRepo) LockAccount(userID string) error {
+	f.locked = append(f.locked, userID)
+	return nil
+}
+
+func (f *fakeLoyaltyRepo) ExpirePoints(userID string) error {
+	f.expired = append(f.expired, userID)
+	return nil
+}
+
+func (f *fakeLoyaltyRepo) GetBalance(userID string) (int64, error) {
+	var balance int64
+	for _, transaction := range f.transactions {
+		if transaction.UserID == userID {
+			balance += transaction.Points
+		}
+	}
+	return balance, nil
+}
+
+func (f *fakeLoyaltyRepo) CreateTransaction(transaction *model.LoyaltyTransaction) error {
+	f.transactions = append(f.transactions, *transaction)
+	return nil
+}
+
+func (f *fakeLoyaltyRepo) GetTransactions(userID string) (*[]model.LoyaltyTransaction, error) {
+	return &f.transactions, nil
+}
+
+func TestPointsDiscount(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected float64
+	}{
+		{0, 0},
+		{1, 0.1},
+		{15, 1.5},
+		{250, 25},
+		{333, 33.3},
+	}
+
+	for _, test := range tests {
+		result := pointsDiscount(test.input)
+		if result != test.expected {
+			t.Errorf("For input '%d', expected %v, but got %v", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestRedeemPoints(t *testing.T) {
+	repo := &fakeLoyaltyRepo{transactions: []model.LoyaltyTransaction{
+		{UserID: "user-1", TransactionType: model.PointsEarned, Points: 100},
+	}}
+
+	if err := redeemPoints(repo, "user-1", "order-1", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := redeemPoints(repo, "user-1", "order-2", 60); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected 400 when spending more than the balance, but got %v", err)
+	}
+
+	if balance, _ := repo.GetBalance("user-1"); balance != 40 {
+		t.Errorf("Expected 40 points left, but got %d", balance)
+	}
+
+	if len(repo.locked) != 2 || len(repo.expired) != 2 {
+		t.Errorf("Expected the account to be locked and expired before every redemption, but got %d locks and %d expiries", len(repo.locked), len(repo.expired))
+	}
+
+	last := repo.transactions[len(repo.transactions)-1]
+	if last.TransactionType != model.PointsRedeemed || last.Points != -60 || *last.OrderHeaderID != "order-1" {
+		t.Errorf("Expected a redemption of 60 points for order-1, but got %+v", last)
+	}
+}
+
+func TestGetPointsHistoryOfAnotherUser(t *testing.T) {
+	u := CreateNewLoyaltyUsecase(&fakeLoyaltyRepo{}, &fakeUnitOfWork{})
+
+	if _, err := u.GetHistory("user-2", "user-1", string(model.Client)); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 for a customer reading another customer's points, but got %v", err)
+	}
+
+	if _, err := u.GetHistory("user-2", "staff-1", string(model.Employee)); err != nil {
+		t.Errorf("Expected staff to read any customer's points, but got %v", err)
+	}
+}
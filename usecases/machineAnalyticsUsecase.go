@@ -0,0 +1,226 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+)
+
+type machineAnalyticsUsecase struct {
+	machineRepo       repository.MachineRepository
+	machineReportRepo model.MachineReportsRepository
+	branchRepo        repository.BranchReopository
+}
+
+func CreateNewMachineAnalyticsUsecase(machineRepo repository.MachineRepository, machineReportRepo model.MachineReportsRepository, branchRepo repository.BranchReopository) model.MachineAnalyticsUsecase {
+	return &machineAnalyticsUsecase{
+		machineRepo:       machineRepo,
+		machineReportRepo: machineReportRepo,
+		branchRepo:        branchRepo,
+	}
+}
+
+// analyticsRange is the days from the day of from up to and including the day of to,
+// in Thailand time
+func analyticsRange(from time.Time, to time.Time) (time.Time, time.Time, error) {
+	from = from.In(model.ReportLocation)
+	to = to.In(model.ReportLocation)
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, model.ReportLocation)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, model.ReportLocation).AddDate(0, 0, 1)
+
+	if !end.After(start) {
+		return start, end, errors.New("ERR 400: from must not be after to")
+	}
+
+	if end.After(start.AddDate(0, 0, model.MaxAnalyticsDays)) {
+		return start, end, fmt.Errorf("ERR 400: range can not be longer than %d days", model.MaxAnalyticsDays)
+	}
+
+	return start, end, nil
+}
+
+// downtimeWithin adds up the time between from and to the reports kept the machine
+// out of service, reports that overlap are only counted once
+func downtimeWithin(downtime []model.MachineDowntime, from time.Time, to time.Time) time.Duration {
+	type interval struct{ from, to time.Time }
+
+	intervals := []interval{}
+	for _, d := range downtime {
+		start, end := d.From, to
+		if d.To != nil && d.To.Before(end) {
+			end = *d.To
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{start, end})
+		}
+	}
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].from.Before(intervals[j].from) })
+
+	var total time.Duration
+	var last time.Time
+	for _, i := range intervals {
+		if i.from.Before(last) {
+			i.from = last
+		}
+		if i.to.After(i.from) {
+			total += i.to.Sub(i.from)
+			last = i.to
+		}
+	}
+
+	return total
+}
+
+// buildMachineAnalytics sums the cycles and downtime of every machine of the branch
+// between from and to. Time after now isn't counted as idle yet, and a cycle still
+// running is only busy until now. Money is summed in satang.
+func buildMachineAnalytics(machines []model.Machine, cycles []model.MachineCycle, downtime []model.MachineDowntime, from time.Time, to time.Time, now time.Time) model.MachineAnalytics {
+	analytics := model.MachineAnalytics{
+		From:     from,
+		To:       to,
+		Machines: []model.MachineUsage{},
+	}
+
+	until := to
+	if now.Before(until) {
+		until = now
+	}
+
+	dayIndex := map[string]int{}
+	days := []string{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format(time.DateOnly)] = len(days)
+		days = append(days, day.Format(time.DateOnly))
+	}
+
+	machineIndex := map[string]int{}
+	addMachine := func(machine model.Machine) int {
+		usage := model.MachineUsage{
+			MachineSerial: machine.MachineSerial,
+			MachineLabel:  machine.MachineLabel,
+			MachineType:   machine.MachineType,
+			Days:          make([]model.MachineDayUsage, len(days)),
+		}
+		for i, day := range days {
+			usage.Days[i].Date = day
+		}
+		machineIndex[machine.MachineSerial] = len(analytics.Machines)
+		analytics.Machines = append(analytics.Machines, usage)
+		return len(analytics.Machines) - 1
+	}
+
+	for _, machine := range machines {
+		addMachine(machine)
+	}
+
+	machineSatang := make(map[int]int64)
+	daySatang := make(map[[2]int]int64)
+	var branchSatang int64 = 0
+
+	for _, cycle := range cycles {
+		i, ok := machineIndex[cycle.MachineSerial]
+		if !ok {
+			// the machine was removed from the branch since, its cycles still count
+			i = addMachine(model.Machine{MachineSerial: cycle.MachineSerial})
+		}
+		usage := &analytics.Machines[i]
+
+		started := cycle.StartedAt.In(model.ReportLocation)
+		d, ok := dayIndex[started.Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+
+		finished := cycle.FinishedAt
+		if until.Before(finished) {
+			finished = until
+		}
+		busy := 0.0
+		if finished.After(cycle.StartedAt) {
+			busy = finished.Sub(cycle.StartedAt).Minutes()
+		}
+
+		usage.Cycles++
+		usage.BusyMinutes += busy
+		usage.Days[d].Cycles++
+		usage.Days[d].BusyMinutes += busy
+
+		machineSatang[i] += toSatang(cycle.Revenue)
+		daySatang[[2]int{i, d}] += toSatang(cycle.Revenue)
+		branchSatang += toSatang(cycle.Revenue)
+
+		analytics.Cycles++
+		analytics.PeakHours[started.Weekday()][started.Hour()]++
+	}
+
+	downtimeByMachine := map[string][]model.MachineDowntime{}
+	for _, d := range downtime {
+		downtimeByMachine[d.MachineSerial] = append(downtimeByMachine[d.MachineSerial], d)
+	}
+
+	total := 0.0
+	if until.After(from) {
+		total = until.Sub(from).Minutes()
+	}
+
+	for i := range analytics.Machines {
+		usage := &analytics.Machines[i]
+		usage.Revenue = float64(machineSatang[i]) / 100
+		for d := range usage.Days {
+			usage.Days[d].Revenue = float64(daySatang[[2]int{i, d}]) / 100
+		}
+
+		usage.DowntimeMinutes = downtimeWithin(downtimeByMachine[usage.MachineSerial], from, until).Minutes()
+
+		inService := total - usage.DowntimeMinutes
+		usage.IdleMinutes = math.Max(0, inService-usage.BusyMinutes)
+		if inService > 0 {
+			usage.Utilization = math.Round(math.Min(1, usage.BusyMinutes/inService)*10000) / 10000
+		}
+	}
+
+	analytics.Revenue = float64(branchSatang) / 100
+
+	return analytics
+}
+
+// GetAnalytics reports how busy every machine of the branch was from the day of from
+// up to the day of to, for super admin and the manager who owns the branch
+func (u *machineAnalyticsUsecase) GetAnalytics(branchID string, from time.Time, to time.Time, userID string, userRole string) (*model.MachineAnalytics, error) {
+	if err := checkBranchOwner(u.branchRepo, branchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	start, end, err := analyticsRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := u.machineRepo.GetByBranchID(branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	cycles, err := u.machineRepo.GetCycles(branchID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	downtime, err := u.machineReportRepo.GetDowntime(branchID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := buildMachineAnalytics(*machines, *cycles, *downtime, start, end, time.Now().UTC())
+	analytics.BranchID = branchID
+
+	return &analytics, nil
+}
@@ -0,0 +1,133 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+func TestAnalyticsRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 10, d, 12, 0, 0, 0, model.ReportLocation) }
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		days     int
+		expected bool
+	}{
+		{"one day", day(1), day(1), 1, true},
+		{"a week", day(1), day(7), 7, true},
+		{"to before from", day(7), day(1), 0, false},
+		{"longest range", day(1), day(1).AddDate(0, 0, model.MaxAnalyticsDays-1), model.MaxAnalyticsDays, true},
+		{"too long", day(1), day(1).AddDate(0, 0, model.MaxAnalyticsDays), 0, false},
+		{"utc late evening is the next day", time.Date(2024, 9, 30, 20, 0, 0, 0, time.UTC), day(1), 1, true},
+	}
+
+	for _, test := range tests {
+		start, end, err := analyticsRange(test.from, test.to)
+		if (err == nil) != test.expected {
+			t.Errorf("%s: expected valid %v, but got %v", test.name, test.expected, err)
+			continue
+		}
+		if err == nil && int(end.Sub(start).Hours()/24) != test.days {
+			t.Errorf("%s: expected %d days, but got %s to %s", test.name, test.days, start, end)
+		}
+	}
+}
+
+func TestDowntimeWithin(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, model.ReportLocation)
+	to := from.AddDate(0, 0, 1)
+	at := func(hour int) time.Time { return from.Add(time.Duration(hour) * time.Hour) }
+	until := func(hour int) *time.Time { end := at(hour); return &end }
+
+	tests := []struct {
+		name     string
+		downtime []model.MachineDowntime
+		expected time.Duration
+	}{
+		{"none", []model.MachineDowntime{}, 0},
+		{"inside", []model.MachineDowntime{{From: at(2), To: until(5)}}, 3 * time.Hour},
+		{"still open", []model.MachineDowntime{{From: at(20)}}, 4 * time.Hour},
+		{"started before", []model.MachineDowntime{{From: at(-10), To: until(1)}}, time.Hour},
+		{"overlapping", []model.MachineDowntime{{From: at(2), To: until(6)}, {From: at(4), To: until(8)}}, 6 * time.Hour},
+		{"nested", []model.MachineDowntime{{From: at(2), To: until(10)}, {From: at(4), To: until(5)}}, 8 * time.Hour},
+		{"apart", []model.MachineDowntime{{From: at(1), To: until(2)}, {From: at(10), To: until(12)}}, 3 * time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := downtimeWithin(test.downtime, from, to); got != test.expected {
+			t.Errorf("%s: expected %s, but got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestBuildMachineAnalytics(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, model.ReportLocation)
+	to := from.AddDate(0, 0, 2)
+	at := func(hour int, minute int) time.Time {
+		return from.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	fixed := at(30, 0)
+
+	machines := []model.Machine{
+		{MachineSerial: "W1", MachineLabel: "1", MachineType: model.Washer},
+		{MachineSerial: "D1", MachineLabel: "2", MachineType: model.Dryer},
+	}
+	cycles := []model.MachineCycle{
+		{MachineSerial: "W1", StartedAt: at(9, 0), FinishedAt: at(9, 30), Revenue: 40.1},
+		{MachineSerial: "W1", StartedAt: at(9, 30), FinishedAt: at(10, 0), Revenue: 40.2},
+		{MachineSerial: "W1", StartedAt: at(33, 0), FinishedAt: at(34, 0), Revenue: 0}, // unpaid
+		{MachineSerial: "OLD", StartedAt: at(10, 0), FinishedAt: at(10, 30), Revenue: 30},
+	}
+	downtime := []model.MachineDowntime{
+		{MachineSerial: "D1", From: at(24, 0), To: &fixed},
+	}
+
+	analytics := buildMachineAnalytics(machines, cycles, downtime, from, to, to.Add(time.Hour))
+
+	if analytics.Cycles != 4 || analytics.Revenue != 110.3 {
+		t.Errorf("Expected 4 cycles and 110.3 revenue, but got %d and %v", analytics.Cycles, analytics.Revenue)
+	}
+
+	if len(analytics.Machines) != 3 || analytics.Machines[2].MachineSerial != "OLD" {
+		t.Fatalf("Expected every machine and the removed one, but got %v", analytics.Machines)
+	}
+
+	washer := analytics.Machines[0]
+	if washer.Cycles != 3 || washer.BusyMinutes != 120 || washer.Revenue != 80.3 {
+		t.Errorf("Expected washer 3 cycles, 120 busy minutes and 80.3 revenue, but got %d, %v and %v", washer.Cycles, washer.BusyMinutes, washer.Revenue)
+	}
+	if washer.IdleMinutes != 48*60-120 {
+		t.Errorf("Expected washer idle %v minutes, but got %v", 48*60-120, washer.IdleMinutes)
+	}
+	if washer.Days[0].Date != "2024-10-01" || washer.Days[0].Cycles != 2 || washer.Days[1].Cycles != 1 || washer.Days[0].Revenue != 80.3 {
+		t.Errorf("Expected washer 2 cycles on 2024-10-01 and 1 on the next day, but got %v", washer.Days)
+	}
+
+	dryer := analytics.Machines[1]
+	if dryer.DowntimeMinutes != 6*60 || dryer.IdleMinutes != 42*60 || dryer.Utilization != 0 {
+		t.Errorf("Expected dryer 360 minutes down and 2520 idle, but got %v and %v", dryer.DowntimeMinutes, dryer.IdleMinutes)
+	}
+
+	if analytics.PeakHours[time.Tuesday][9] != 2 || analytics.PeakHours[time.Tuesday][10] != 1 || analytics.PeakHours[time.Wednesday][9] != 1 {
+		t.Errorf("Expected cycles counted in the hour they started, but got %v", analytics.PeakHours)
+	}
+}
+
+func TestBuildMachineAnalyticsUntilNow(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, model.ReportLocation)
+	to := from.AddDate(0, 0, 1)
+	now := from.Add(10 * time.Hour)
+
+	machines := []model.Machine{{MachineSerial: "W1"}}
+	cycles := []model.MachineCycle{{MachineSerial: "W1", StartedAt: now.Add(-30 * time.Minute), FinishedAt: now.Add(30 * time.Minute)}}
+
+	analytics := buildMachineAnalytics(machines, cycles, []model.MachineDowntime{}, from, to, now)
+
+	washer := analytics.Machines[0]
+	if washer.BusyMinutes != 30 || washer.IdleMinutes != 570 || washer.Utilization != 0.05 {
+		t.Errorf("Expected a running cycle busy until now, but got busy %v, idle %v and utilization %v", washer.BusyMinutes, washer.IdleMinutes, washer.Utilization)
+	}
+}
@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"errors"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type MachineAssignmentUsecase interface {
+	AssignWaitingBasket() error
+	AssignByPaymentID(paymentID string) error
+}
+
+type machineAssignmentUsecase struct {
+	orderDetailRepo repository.OrderDetailRepository
+	machineRepo     repository.MachineRepository
+}
+
+func CreateNewMachineAssignmentUsecase(orderDetailRepo repository.OrderDetailRepository, machineRepo repository.MachineRepository) MachineAssignmentUsecase {
+	return &machineAssignmentUsecase{
+		orderDetailRepo: orderDetailRepo,
+		machineRepo:     machineRepo,
+	}
+}
+
+func serviceMachineMapper(serviceType model.ServiceType) (model.MachineType, bool) {
+	switch serviceType {
+	case model.Washing:
+		return model.Washer, true
+	case model.Drying:
+		return model.Dryer, true
+	}
+	return "", false
+}
+
+// AssignWaitingBasket goes through every paid basket that still has no machine,
+// oldest first. It's called whenever a machine might have been freed.
+func (u *machineAssignmentUsecase) AssignWaitingBasket() error {
+	baskets, err := u.orderDetailRepo.GetWaitingForMachine("")
+	if err != nil {
+		return err
+	}
+
+	return u.assign(baskets)
+}
+
+// AssignByPaymentID assigns machines to the baskets of the order that was just paid.
+func (u *machineAssignmentUsecase) AssignByPaymentID(paymentID string) error {
+	baskets, err := u.orderDetailRepo.GetWaitingForMachine(paymentID)
+	if err != nil {
+		return err
+	}
+
+	return u.assign(baskets)
+}
+
+func (u *machineAssignmentUsecase) assign(baskets *[]model.BasketToAssign) error {
+	for _, basket := range *baskets {
+		machineType, ok := serviceMachineMapper(basket.ServiceType)
+		if !ok {
+			continue
+		}
+
+		_, err := u.machineRepo.ReserveMachine(basket, machineType)
+
+		// no free machine for this basket right now, next run will try again
+		var busyErr *model.MachineBusyError
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.As(err, &busyErr) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
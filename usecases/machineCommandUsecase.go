@@ -0,0 +1,430 @@
+package usecases
+
+import (
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type machineCommandUsecase struct {
+	commandRepo     model.MachineCommandRepository
+	machineRepo     repository.MachineRepository
+	orderHeaderRepo repository.OrderHeaderRepository
+	orderDetailRepo repository.OrderDetailRepository
+	paymentRepo     model.PaymentRepository
+	branchRepo      repository.BranchReopository
+	contractRepo    repository.EmployeeContractRepository
+	jobRepo         model.JobRepository
+	washProgramRepo model.WashProgramRepository
+	unitOfWork      repository.UnitOfWork
+}
+
+func CreateNewMachineCommandUsecase(commandRepo model.MachineCommandRepository, machineRepo repository.MachineRepository, orderHeaderRepo repository.OrderHeaderRepository, orderDetailRepo repository.OrderDetailRepository, paymentRepo model.PaymentRepository, branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository, jobRepo model.JobRepository, washProgramRepo model.WashProgramRepository, unitOfWork repository.UnitOfWork) model.MachineCommandUsecase {
+	return &machineCommandUsecase{
+		commandRepo:     commandRepo,
+		machineRepo:     machineRepo,
+		orderHeaderRepo: orderHeaderRepo,
+		orderDetailRepo: orderDetailRepo,
+		paymentRepo:     paymentRepo,
+		branchRepo:      branchRepo,
+		contractRepo:    contractRepo,
+		jobRepo:         jobRepo,
+		washProgramRepo: washProgramRepo,
+		unitOfWork:      unitOfWork,
+	}
+}
+
+// nextCommandCheck is what becomes of a command that isn't acknowledged by now,
+// and when to look at it again. A command the machine picked up but didn't
+// acknowledge in time is sent again until it runs out of attempts or expires.
+func nextCommandCheck(command model.MachineCommand, now time.Time) (model.MachineCommandStatus, *time.Time) {
+	if command.Status != model.CommandPending && command.Status != model.CommandSent {
+		return command.Status, nil
+	}
+
+	if !now.Before(command.ExpiresAt) {
+		return model.CommandTimedOut, nil
+	}
+
+	if command.Status == model.CommandSent && command.SentAt != nil {
+		ackBy := command.SentAt.Add(model.CommandAckTimeout)
+		if now.Before(ackBy) {
+			return model.CommandSent, &ackBy
+		}
+
+		if command.Attempts >= model.CommandMaxAttempts {
+			return model.CommandTimedOut, nil
+		}
+
+		return model.CommandPending, &command.ExpiresAt
+	}
+
+	return command.Status, &command.ExpiresAt
+}
+
+// issueCommand stores the command and schedules its check in the transaction of the repositories,
+// nothing is issued and false is returned when a command with the same idempotency key was issued before
+func issueCommand(commandRepo model.MachineCommandRepository, jobRepo model.JobRepository, command *model.MachineCommand) (bool, error) {
+	now := time.Now().UTC()
+	command.CommandID = uuid.New().String()
+	command.Status = model.CommandPending
+	command.ExpiresAt = now.Add(model.CommandAckTimeout * model.CommandMaxAttempts)
+	command.CreatedAt = now
+	command.UpdatedAt = now
+
+	created, err := commandRepo.CreateCommand(command)
+	if err != nil || !created {
+		return false, err
+	}
+
+	job := model.NewJob(model.CheckCommandJob, command.CommandID, command.ExpiresAt)
+	return true, jobRepo.Enqueue(&job)
+}
+
+// basketProgram is the program picked for the basket, nil when none was
+// picked or it was deleted since
+func (u *machineCommandUsecase) basketProgram(basket model.OrderDetail) (*model.WashProgram, error) {
+	if basket.ProgramID == nil {
+		return nil, nil
+	}
+
+	program, err := u.washProgramRepo.FindByProgramID(*basket.ProgramID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return program, err
+}
+
+// withProgram has a start command run the program picked for the basket
+// unless it names a program of its own
+func (u *machineCommandUsecase) withProgram(command *model.MachineCommand, basket model.OrderDetail) error {
+	if command.CommandType != model.StartCommand || command.Program != nil {
+		return nil
+	}
+
+	program, err := u.basketProgram(basket)
+	if err != nil || program == nil {
+		return err
+	}
+
+	command.Program = &program.Name
+	command.Temperature = program.Temperature
+	return nil
+}
+
+// startCycle times the basket from when its machine is started instead of when
+// it was ordered, a customer may pay minutes after ordering. The completion job
+// is moved along with the finish time, for machines that don't report theirs.
+func (u *machineCommandUsecase) startCycle(tx *platform.Postgres, orderBasketID string, start time.Time) error {
+	orderDetailRepo := u.orderDetailRepo.WithTx(tx)
+
+	basket, err := orderDetailRepo.GetDetail(orderBasketID)
+	if err != nil {
+		return err
+	}
+
+	if basket.OrderStatus != model.Processing {
+		return nil
+	}
+
+	program, err := u.basketProgram(*basket)
+	if err != nil {
+		return err
+	}
+
+	finishedAt := cycleEnd(program, start)
+	if err := orderDetailRepo.StartCycle(basket.OrderBasketID, start, finishedAt); err != nil {
+		return err
+	}
+	basket.StartedAt = &start
+	basket.FinishedAt = &finishedAt
+
+	return scheduleBasketCompletion(u.jobRepo.WithTx(tx), *basket)
+}
+
+// paidBasketOnMachine returns the basket running in the machine when its order is paid
+func (u *machineCommandUsecase) paidBasketOnMachine(machineSerial string) (*model.OrderDetail, error) {
+	basket, err := u.orderDetailRepo.GetProcessingByMachine(machineSerial)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("ERR 400: no basket is in this machine")
+	} else if err != nil {
+		return nil, err
+	}
+
+	header, err := u.orderHeaderRepo.GetByID(basket.OrderHeaderID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := u.paymentRepo.FindByPaymentID(header.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Payment_Status != model.Paid {
+		return nil, errors.New("ERR 400: the order in this machine is not paid")
+	}
+
+	return basket, nil
+}
+
+// IssueCommand lets branch staff tell a machine what to do. Start and unlock
+// are only for a machine with a paid basket in it, stop can always be sent.
+func (u *machineCommandUsecase) IssueCommand(machineSerial string, newCommand *model.IssueMachineCommandDTO, userID string, userRole string) (*model.MachineCommand, error) {
+	machine, err := u.machineRepo.GetByMachineSerial(machineSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, machine.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	command := model.MachineCommand{
+		MachineSerial: machineSerial,
+		CommandType:   newCommand.CommandType,
+		IssuedBy:      userID,
+	}
+
+	if newCommand.CommandType == model.StartCommand {
+		command.Program = newCommand.Program
+		command.Temperature = newCommand.Temperature
+	}
+
+	if newCommand.CommandType != model.StopCommand {
+		basket, err := u.paidBasketOnMachine(machineSerial)
+		if err != nil {
+			return nil, err
+		}
+		command.OrderBasketID = &basket.OrderBasketID
+
+		if err := u.withProgram(&command, *basket); err != nil {
+			return nil, err
+		}
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		created, err := issueCommand(u.commandRepo.WithTx(tx), u.jobRepo.WithTx(tx), &command)
+		if err != nil || !created || command.CommandType != model.StartCommand {
+			return err
+		}
+		return u.startCycle(tx, *command.OrderBasketID, command.CreatedAt)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &command, nil
+}
+
+func (u *machineCommandUsecase) GetByMachineSerial(machineSerial string, userID string, userRole string) (*[]model.MachineCommand, error) {
+	machine, err := u.machineRepo.GetByMachineSerial(machineSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, machine.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return u.commandRepo.GetByMachineSerial(machineSerial)
+}
+
+// StartPaidBaskets starts the machines of an onsite order that was just paid.
+// It runs as a job, so a basket only ever gets one start command however often it runs.
+func (u *machineCommandUsecase) StartPaidBaskets(paymentID string) (*time.Time, error) {
+	payment, err := u.paymentRepo.FindByPaymentID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Payment_Status != model.Paid {
+		return nil, nil
+	}
+
+	header, err := u.orderHeaderRepo.GetByPaymentID(paymentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !header.ZuckOnsite {
+		return nil, nil
+	}
+
+	baskets, err := u.orderDetailRepo.GetByHeaderID(header.OrderHeaderID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := []model.MachineCommand{}
+	for _, basket := range *baskets {
+		if _, ok := serviceMachineMapper(basket.ServiceType); !ok || basket.MachineSerial == nil || basket.OrderStatus != model.Processing {
+			continue
+		}
+
+		key := string(model.StartCommand) + ":" + basket.OrderBasketID
+		command := model.MachineCommand{
+			MachineSerial:  *basket.MachineSerial,
+			CommandType:    model.StartCommand,
+			OrderBasketID:  &basket.OrderBasketID,
+			IdempotencyKey: &key,
+			IssuedBy:       model.SystemActor,
+		}
+
+		if err := u.withProgram(&command, basket); err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		for i := range commands {
+			created, err := issueCommand(u.commandRepo.WithTx(tx), u.jobRepo.WithTx(tx), &commands[i])
+			if err != nil {
+				return err
+			}
+
+			// the job may run again, the basket is only timed from its first start
+			if created {
+				if err := u.startCycle(tx, *commands[i].OrderBasketID, commands[i].CreatedAt); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	return nil, err
+}
+
+// PollCommands hands the machine the commands waiting for it, each one has to be
+// acknowledged within CommandAckTimeout or it's sent again
+func (u *machineCommandUsecase) PollCommands(machineSerial string, deviceToken string) (*[]model.MachineCommand, error) {
+	if _, err := authenticateDevice(u.machineRepo, machineSerial, deviceToken); err != nil {
+		return nil, err
+	}
+
+	var commands *[]model.MachineCommand
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		jobRepo := u.jobRepo.WithTx(tx)
+
+		var err error
+		commands, err = u.commandRepo.WithTx(tx).ClaimPending(machineSerial)
+		if err != nil {
+			return err
+		}
+
+		for _, command := range *commands {
+			job := model.NewJob(model.CheckCommandJob, command.CommandID, command.SentAt.Add(model.CommandAckTimeout))
+			if err := jobRepo.Enqueue(&job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
+
+// AckCommand records the machine's answer. A late answer to a command that is
+// waiting to be sent again still counts, one that timed out doesn't.
+func (u *machineCommandUsecase) AckCommand(machineSerial string, deviceToken string, commandID string, ack *model.MachineCommandAckDTO) (*model.MachineCommand, error) {
+	if _, err := authenticateDevice(u.machineRepo, machineSerial, deviceToken); err != nil {
+		return nil, err
+	}
+
+	var command *model.MachineCommand
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		commandRepo := u.commandRepo.WithTx(tx)
+
+		var err error
+		command, err = commandRepo.LockCommand(commandID)
+		if err != nil {
+			return err
+		}
+
+		if command.MachineSerial != machineSerial {
+			return gorm.ErrRecordNotFound
+		}
+
+		if command.Status != model.CommandSent && command.Status != model.CommandPending {
+			return errors.New("ERR 400: command is already " + string(command.Status))
+		}
+
+		now := time.Now().UTC()
+		command.AckedAt = &now
+		command.Status = model.CommandAcked
+		if !ack.Accepted {
+			command.Status = model.CommandRejected
+			command.Error = ack.Error
+		}
+
+		if err := commandRepo.SaveCommand(command); err != nil {
+			return err
+		}
+
+		// the machine runs the program from when it took the command
+		if command.Status == model.CommandAcked && command.CommandType == model.StartCommand && command.OrderBasketID != nil {
+			return u.startCycle(tx, *command.OrderBasketID, now)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// CheckCommand runs when a command should have been acknowledged, it's sent again
+// or timed out. It returns when to look at the command again.
+func (u *machineCommandUsecase) CheckCommand(commandID string) (*time.Time, error) {
+	var next *time.Time
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		commandRepo := u.commandRepo.WithTx(tx)
+
+		command, err := commandRepo.LockCommand(commandID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		status, runAt := nextCommandCheck(*command, time.Now().UTC())
+		next = runAt
+		if status == command.Status {
+			return nil
+		}
+
+		command.Status = status
+		if status == model.CommandTimedOut {
+			timedOut := "machine did not acknowledge the command"
+			command.Error = &timedOut
+		}
+
+		return commandRepo.SaveCommand(command)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
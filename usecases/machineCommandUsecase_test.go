@@ -0,0 +1,288 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeCommandRepo struct {
+	model.MachineCommandRepository
+	commands map[string]*model.MachineCommand
+}
+
+func (f *fakeCommandRepo) WithTx(tx *platform.Postgres) model.MachineCommandRepository { return f }
+
+func (f *fakeCommandRepo) CreateCommand(command *model.MachineCommand) (bool, error) {
+	for _, existing := range f.commands {
+		if command.IdempotencyKey != nil && existing.IdempotencyKey != nil && *existing.IdempotencyKey == *command.IdempotencyKey {
+			return false, nil
+		}
+	}
+	created := *command
+	f.commands[command.CommandID] = &created
+	return true, nil
+}
+
+func (f *fakeCommandRepo) LockCommand(commandID string) (*model.MachineCommand, error) {
+	command, ok := f.commands[commandID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	locked := *command
+	return &locked, nil
+}
+
+func (f *fakeCommandRepo) SaveCommand(command *model.MachineCommand) error {
+	saved := *command
+	f.commands[command.CommandID] = &saved
+	return nil
+}
+
+type fakeContractRepo struct {
+	repository.EmployeeContractRepository
+	contracts []model.EmployeeContract
+}
+
+func (f *fakeContractRepo) GetByUserID(userID string) (*[]model.EmployeeContract, error) {
+	contracts := []model.EmployeeContract{}
+	for _, ec := range f.contracts {
+		if ec.UserID == userID {
+			contracts = append(contracts, ec)
+		}
+	}
+	return &contracts, nil
+}
+
+func (f *fakeOrderByPaymentRepo) GetByID(orderHeaderID string, isAdminView bool) (*model.OrderHeader, error) {
+	order := f.order
+	return &order, nil
+}
+
+func TestNextCommandCheck(t *testing.T) {
+	now := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+	justSent := now.Add(-10 * time.Second)
+	sentLongAgo := now.Add(-model.CommandAckTimeout)
+	expiresAt := now.Add(time.Minute)
+	ackBy := justSent.Add(model.CommandAckTimeout)
+
+	tests := []struct {
+		name     string
+		command  model.MachineCommand
+		expected model.MachineCommandStatus
+		next     *time.Time
+	}{
+		{"acked is done", model.MachineCommand{Status: model.CommandAcked, ExpiresAt: expiresAt}, model.CommandAcked, nil},
+		{"pending waits for the machine", model.MachineCommand{Status: model.CommandPending, ExpiresAt: expiresAt}, model.CommandPending, &expiresAt},
+		{"pending too long times out", model.MachineCommand{Status: model.CommandPending, ExpiresAt: now}, model.CommandTimedOut, nil},
+		{"sent waits for the ack", model.MachineCommand{Status: model.CommandSent, SentAt: &justSent, Attempts: 1, ExpiresAt: expiresAt}, model.CommandSent, &ackBy},
+		{"unacked is sent again", model.MachineCommand{Status: model.CommandSent, SentAt: &sentLongAgo, Attempts: 1, ExpiresAt: expiresAt}, model.CommandPending, &expiresAt},
+		{"unacked on the last attempt times out", model.MachineCommand{Status: model.CommandSent, SentAt: &sentLongAgo, Attempts: model.CommandMaxAttempts, ExpiresAt: expiresAt}, model.CommandTimedOut, nil},
+	}
+
+	for _, test := range tests {
+		status, next := nextCommandCheck(test.command, now)
+		if status != test.expected || (next == nil) != (test.next == nil) || (next != nil && !next.Equal(*test.next)) {
+			t.Errorf("%s: expected %v %v, but got %v %v", test.name, test.expected, test.next, status, next)
+		}
+	}
+}
+
+func TestStartPaidBaskets(t *testing.T) {
+	washer := "WASHER-01"
+	programID := "cotton"
+	temperature := 40
+	programRepo := &fakeWashProgramRepo{programs: map[string]model.WashProgram{
+		programID: {ProgramID: programID, MachineType: model.Washer, Name: "Cotton", DurationMinutes: 45, Temperature: &temperature},
+	}}
+	orderedAt := time.Now().UTC().Add(-10 * time.Minute)
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"payment-1": {PaymentID: "payment-1", Payment_Status: model.Paid},
+	}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{
+		"basket-1": {OrderBasketID: "basket-1", MachineSerial: &washer, OrderStatus: model.Processing, ServiceType: model.Washing, ProgramID: &programID, FinishedAt: &orderedAt},
+		"basket-2": {OrderBasketID: "basket-2", OrderStatus: model.Waiting, ServiceType: model.Drying},
+	}}
+	commandRepo := &fakeCommandRepo{commands: map[string]*model.MachineCommand{}}
+	jobRepo := &fakeJobRepo{}
+	orderHeaderRepo := &fakeOrderByPaymentRepo{order: model.OrderHeader{OrderHeaderID: "order-1", PaymentID: "payment-1", ZuckOnsite: true}}
+	u := CreateNewMachineCommandUsecase(commandRepo, &fakeMachineRepo{}, orderHeaderRepo, orderDetailRepo, paymentRepo, &fakeBranchRepo{}, &fakeContractRepo{}, jobRepo, programRepo, &fakeUnitOfWork{})
+
+	// the job may run again, the machine is still only started once
+	for i := 0; i < 2; i++ {
+		if _, err := u.StartPaidBaskets("payment-1"); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+	}
+
+	if len(commandRepo.commands) != 1 {
+		t.Fatalf("expected one start command, but got %d", len(commandRepo.commands))
+	}
+	for _, command := range commandRepo.commands {
+		if command.CommandType != model.StartCommand || command.MachineSerial != washer || *command.OrderBasketID != "basket-1" || command.Status != model.CommandPending {
+			t.Errorf("expected pending start of basket-1 on %s, but got %+v", washer, command)
+		}
+		if command.Program == nil || *command.Program != "Cotton" || command.Temperature == nil || *command.Temperature != temperature {
+			t.Errorf("expected the basket's program, but got %v %v", command.Program, command.Temperature)
+		}
+	}
+	if len(jobRepo.enqueued) != 2 || jobRepo.enqueued[0].JobType != model.CheckCommandJob {
+		t.Fatalf("expected the command to be checked and the basket completed, but got %v", jobRepo.enqueued)
+	}
+
+	// the basket was paid ten minutes after it was ordered, it runs its program from the start
+	finishedAt := *orderDetailRepo.details["basket-1"].FinishedAt
+	if started := finishedAt.Add(-45 * time.Minute); started.Before(orderedAt.Add(9 * time.Minute)) {
+		t.Errorf("expected the basket timed from the start command, but it finishes at %v", finishedAt)
+	}
+	if completion := jobRepo.enqueued[1]; completion.JobType != model.CompleteBasketJob || !completion.RunAt.Equal(finishedAt) {
+		t.Errorf("expected the completion job moved to %v, but got %+v", finishedAt, completion)
+	}
+
+	orderHeaderRepo.order.ZuckOnsite = false
+	commandRepo.commands = map[string]*model.MachineCommand{}
+	if _, err := u.StartPaidBaskets("payment-1"); err != nil || len(commandRepo.commands) != 0 {
+		t.Errorf("expected delivery orders to be left to staff, but got %v %v", commandRepo.commands, err)
+	}
+}
+
+func TestIssueCommand(t *testing.T) {
+	washer := "WASHER-01"
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"payment-1": {PaymentID: "payment-1", Payment_Status: model.Pending},
+	}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{}}
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{washer: {MachineSerial: washer, BranchID: "branch-1"}}}
+	orderHeaderRepo := &fakeOrderByPaymentRepo{order: model.OrderHeader{OrderHeaderID: "order-1", PaymentID: "payment-1"}}
+	branchRepo := &fakeBranchRepo{branch: model.Branch{BranchID: "branch-1", OwnerUserID: "owner"}}
+	u := CreateNewMachineCommandUsecase(&fakeCommandRepo{commands: map[string]*model.MachineCommand{}}, machineRepo, orderHeaderRepo, orderDetailRepo, paymentRepo, branchRepo, &fakeContractRepo{}, &fakeJobRepo{}, &fakeWashProgramRepo{}, &fakeUnitOfWork{})
+
+	program := "Cotton"
+	tests := []struct {
+		name      string
+		userID    string
+		command   model.IssueMachineCommandDTO
+		setup     func()
+		expectErr string
+	}{
+		{"stranger", "someone", model.IssueMachineCommandDTO{CommandType: model.StopCommand}, func() {}, "403"},
+		{"stop an empty machine", "owner", model.IssueMachineCommandDTO{CommandType: model.StopCommand}, func() {}, ""},
+		{"start an empty machine", "owner", model.IssueMachineCommandDTO{CommandType: model.StartCommand}, func() {}, "400"},
+		{"start before paid", "owner", model.IssueMachineCommandDTO{CommandType: model.StartCommand, Program: &program}, func() {
+			orderDetailRepo.details["basket-1"] = &model.OrderDetail{OrderBasketID: "basket-1", OrderHeaderID: "order-1", MachineSerial: &washer, OrderStatus: model.Processing}
+		}, "400"},
+		{"start once paid", "owner", model.IssueMachineCommandDTO{CommandType: model.StartCommand, Program: &program}, func() {
+			paymentRepo.payments["payment-1"].Payment_Status = model.Paid
+		}, ""},
+		{"unlock once paid", "owner", model.IssueMachineCommandDTO{CommandType: model.UnlockCommand, Program: &program}, func() {}, ""},
+	}
+
+	for _, test := range tests {
+		test.setup()
+		command, err := u.IssueCommand(washer, &test.command, test.userID, string(model.BranchManager))
+		if test.expectErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, but got %v", test.name, err)
+			continue
+		}
+		if command.CommandType != test.command.CommandType || command.Status != model.CommandPending || command.IssuedBy != test.userID {
+			t.Errorf("%s: expected pending %s by %s, but got %+v", test.name, test.command.CommandType, test.userID, command)
+		}
+		if (command.Program != nil) != (test.command.CommandType == model.StartCommand) {
+			t.Errorf("%s: expected program only on start, but got %v", test.name, command.Program)
+		}
+	}
+}
+
+func TestAckCommand(t *testing.T) {
+	tokenHash := hashDeviceToken("secret")
+	sentAt := time.Now().UTC()
+	reason := "door is open"
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{
+		"WASHER-01": {MachineSerial: "WASHER-01", DeviceTokenHash: &tokenHash},
+		"WASHER-02": {MachineSerial: "WASHER-02", DeviceTokenHash: &tokenHash},
+	}}
+	commandRepo := &fakeCommandRepo{commands: map[string]*model.MachineCommand{
+		"command-1": {CommandID: "command-1", MachineSerial: "WASHER-01", Status: model.CommandSent, SentAt: &sentAt},
+		"command-2": {CommandID: "command-2", MachineSerial: "WASHER-01", Status: model.CommandSent, SentAt: &sentAt},
+	}}
+	u := CreateNewMachineCommandUsecase(commandRepo, machineRepo, &fakeOrderByPaymentRepo{}, &fakeJobOrderDetailRepo{}, &fakePaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{}, &fakeJobRepo{}, &fakeWashProgramRepo{}, &fakeUnitOfWork{})
+
+	tests := []struct {
+		name      string
+		serial    string
+		commandID string
+		ack       model.MachineCommandAckDTO
+		expected  model.MachineCommandStatus
+		expectErr string
+	}{
+		{"another machine's command", "WASHER-02", "command-1", model.MachineCommandAckDTO{Accepted: true}, "", "record not found"},
+		{"accepted", "WASHER-01", "command-1", model.MachineCommandAckDTO{Accepted: true}, model.CommandAcked, ""},
+		{"answered twice", "WASHER-01", "command-1", model.MachineCommandAckDTO{Accepted: true}, "", "400"},
+		{"rejected", "WASHER-01", "command-2", model.MachineCommandAckDTO{Error: &reason}, model.CommandRejected, ""},
+	}
+
+	for _, test := range tests {
+		command, err := u.AckCommand(test.serial, "secret", test.commandID, &test.ack)
+		if test.expectErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, but got %v", test.name, err)
+			continue
+		}
+		if stored := commandRepo.commands[test.commandID]; stored.Status != test.expected || stored.AckedAt == nil {
+			t.Errorf("%s: expected %v, but got %+v", test.name, test.expected, stored)
+		}
+		if test.ack.Error != nil && (command.Error == nil || *command.Error != *test.ack.Error) {
+			t.Errorf("%s: expected error %s to be kept, but got %v", test.name, *test.ack.Error, command.Error)
+		}
+	}
+}
+
+func TestAckStartCommand(t *testing.T) {
+	tokenHash := hashDeviceToken("secret")
+	washer := "WASHER-01"
+	basketID := "basket-1"
+	issuedAt := time.Now().UTC().Add(-3 * time.Minute)
+	finishedAt := issuedAt.Add(time.Duration(model.DefaultCycleMinutes) * time.Minute)
+
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{washer: {MachineSerial: washer, DeviceTokenHash: &tokenHash}}}
+	commandRepo := &fakeCommandRepo{commands: map[string]*model.MachineCommand{
+		"command-1": {CommandID: "command-1", MachineSerial: washer, CommandType: model.StartCommand, OrderBasketID: &basketID, Status: model.CommandSent, SentAt: &issuedAt},
+	}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{
+		basketID: {OrderBasketID: basketID, MachineSerial: &washer, OrderStatus: model.Processing, ServiceType: model.Washing, FinishedAt: &finishedAt},
+	}}
+	jobRepo := &fakeJobRepo{}
+	u := CreateNewMachineCommandUsecase(commandRepo, machineRepo, &fakeOrderByPaymentRepo{}, orderDetailRepo, &fakePaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{}, jobRepo, &fakeWashProgramRepo{}, &fakeUnitOfWork{})
+
+	if _, err := u.AckCommand(washer, "secret", "command-1", &model.MachineCommandAckDTO{Accepted: true}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	// the machine took the command three minutes after it was issued
+	retimed := *orderDetailRepo.details[basketID].FinishedAt
+	if !retimed.After(finishedAt.Add(2 * time.Minute)) {
+		t.Errorf("expected the basket timed from the acknowledgement, but it finishes at %v", retimed)
+	}
+	if startedAt := orderDetailRepo.details[basketID].StartedAt; startedAt == nil || !startedAt.Add(time.Duration(model.DefaultCycleMinutes)*time.Minute).Equal(retimed) {
+		t.Errorf("expected the basket started at the acknowledgement, but got %v", startedAt)
+	}
+	if len(jobRepo.enqueued) != 1 || jobRepo.enqueued[0].JobType != model.CompleteBasketJob || !jobRepo.enqueued[0].RunAt.Equal(retimed) {
+		t.Errorf("expected the completion job moved to %v, but got %v", retimed, jobRepo.enqueued)
+	}
+}
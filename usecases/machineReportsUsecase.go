@@ -2,11 +2,14 @@ package usecases
 
 import (
 	"errors"
+	"fmt"
 	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type machineReportUsecase struct {
@@ -14,16 +17,45 @@ type machineReportUsecase struct {
 	machineRepository       repository.MachineRepository
 	branchRepository        repository.BranchReopository
 	employeeContract        repository.EmployeeContractRepository
+	orderDetailRepository   repository.OrderDetailRepository
+	orderEventRepository    repository.OrderEventRepository
+	notificationRepository  repository.NotificationRepository
+	maintenanceRepository   model.MaintenanceRepository
+	unitOfWork              repository.UnitOfWork
 }
 
-func CreateNewMachineReportUsecase(machineReportRepository model.MachineReportsRepository, machineRepository repository.MachineRepository, branchRepository repository.BranchReopository, employeeContract repository.EmployeeContractRepository) model.MachineReportsUsecase {
+func CreateNewMachineReportUsecase(machineReportRepository model.MachineReportsRepository, machineRepository repository.MachineRepository, branchRepository repository.BranchReopository, employeeContract repository.EmployeeContractRepository, orderDetailRepository repository.OrderDetailRepository, orderEventRepository repository.OrderEventRepository, notificationRepository repository.NotificationRepository, maintenanceRepository model.MaintenanceRepository, unitOfWork repository.UnitOfWork) model.MachineReportsUsecase {
 	return &machineReportUsecase{machineReportRepository: machineReportRepository,
-		machineRepository: machineRepository,
-		branchRepository:  branchRepository,
-		employeeContract:  employeeContract,
+		machineRepository:      machineRepository,
+		branchRepository:       branchRepository,
+		employeeContract:       employeeContract,
+		orderDetailRepository:  orderDetailRepository,
+		orderEventRepository:   orderEventRepository,
+		notificationRepository: notificationRepository,
+		maintenanceRepository:  maintenanceRepository,
+		unitOfWork:             unitOfWork,
 	}
 }
 
+// status a report can move to from each status, accepting a report (In Progress)
+// takes its machine out of service until every accepted report is closed
+var reportStatusTransitions = map[model.MachineReportStatus][]model.MachineReportStatus{
+	model.ReportPending:    {model.ReportInProgress, model.ReportCanceled},
+	model.ReportInProgress: {model.ReportFixed, model.ReportCanceled},
+	model.ReportFixed:      {},
+	model.ReportCanceled:   {},
+}
+
+// CheckReportTransition validates moving a report to another status
+func CheckReportTransition(from model.MachineReportStatus, to model.MachineReportStatus) error {
+	for _, next := range reportStatusTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("ERR 400: report can not move from %s to %s", from, to)
+}
+
 func (u *machineReportUsecase) toMachineReportDetail(machineReport *model.MachineReports, isAdminView bool) interface{} {
 	machine, err := u.machineRepository.GetByMachineSerial(machineReport.MacineSerial)
 	if err != nil {
@@ -58,6 +90,7 @@ func (u *machineReportUsecase) toMachineReportDetail(machineReport *model.Machin
 		CreatedAt:         machineReport.CreatedAt,
 		DeletedAt:         &machineReport.DeletedAt,
 		BranchInfo:        branchDetail,
+		Preventive:        machineReport.Preventive,
 	}
 	if !isAdminView {
 		reportData.DeletedAt = nil
@@ -169,14 +202,125 @@ func (u *machineReportUsecase) GetAll() (*[]interface{}, error) {
 	return &result, nil
 }
 
+// takeOutOfService stops the machine for an accepted report. Baskets waiting for it
+// are given back to the assignment sweep, the basket it's running is held and flagged.
+func (u *machineReportUsecase) takeOutOfService(tx *platform.Postgres, machine *model.Machine, report *model.MachineReports, userID string) error {
+	if machine.IsActive {
+		if _, err := u.machineRepository.WithTx(tx).UpdateActive(machine.MachineSerial, false, userID); err != nil {
+			return err
+		}
+
+		if err := u.machineReportRepository.WithTx(tx).MarkDeactivatedMachine(report.ReportID); err != nil {
+			return err
+		}
+	}
+
+	orderDetailRepository := u.orderDetailRepository.WithTx(tx)
+	if _, err := orderDetailRepository.ReleaseMachine(machine.MachineSerial); err != nil {
+		return err
+	}
+
+	running, err := orderDetailRepository.GetProcessingByMachine(machine.MachineSerial)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	event := newOrderEvent(running.OrderHeaderID, &running.OrderBasketID, model.MachineOutOfService, userID)
+	event.MachineSerial = &machine.MachineSerial
+	if err := u.orderEventRepository.WithTx(tx).CreateEvents(&[]model.OrderEvent{event}); err != nil {
+		return err
+	}
+
+	notification := model.Notification{
+		NotificationID: uuid.New().String(),
+		BranchID:       machine.BranchID,
+		OrderHeaderID:  &running.OrderHeaderID,
+		Title:          "Machine out of service",
+		Message:        fmt.Sprintf("Basket %s is held on machine %s until it's fixed: %s", running.OrderBasketID, machine.MachineSerial, report.ReportDescription),
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	return u.notificationRepository.WithTx(tx).CreateNotification(&notification)
+}
+
+// returnToService puts the machine back once none of its reports is accepted anymore,
+// as long as it was a report that took it out of service. A fixed report also
+// restarts its maintenance plan.
+func (u *machineReportUsecase) returnToService(tx *platform.Postgres, report *model.MachineReports, fixed bool, userID string) error {
+	if fixed {
+		if err := u.maintenanceRepository.WithTx(tx).MarkServiced(report.MacineSerial, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+
+	if !report.DeactivatedMachine {
+		return nil
+	}
+
+	machineReportRepository := u.machineReportRepository.WithTx(tx)
+	accepted, err := machineReportRepository.CountAccepted(report.MacineSerial)
+	if err != nil {
+		return err
+	}
+
+	if accepted > 0 {
+		return machineReportRepository.HandOverDeactivation(report.MacineSerial)
+	}
+
+	_, err = u.machineRepository.WithTx(tx).UpdateActive(report.MacineSerial, true, userID)
+	return err
+}
+
 func (u *machineReportUsecase) UpdateMachineReportStatus(updateReport model.UpdateMachineReportStatusDTO, userID string, userRole string) (*interface{}, error) {
 	err := u.checkSith(updateReport.ReportID, userID, userRole)
 	if err != nil {
 		return nil, err
 	}
-	if err := u.machineReportRepository.UpdateMachineReportStatus(updateReport); err != nil {
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		machineReportRepository := u.machineReportRepository.WithTx(tx)
+
+		report, err := machineReportRepository.FindMachinereportByID(updateReport.ReportID)
+		if err != nil {
+			return err
+		}
+
+		// reports of the same machine are changed one at a time, the report is read
+		// again once the machine is locked
+		machine, err := u.machineRepository.WithTx(tx).LockMachine(report.MacineSerial)
+		if err != nil {
+			return err
+		}
+
+		report, err = machineReportRepository.FindMachinereportByID(updateReport.ReportID)
+		if err != nil {
+			return err
+		}
+
+		if err := CheckReportTransition(report.ReportStatus, updateReport.ReportStatus); err != nil {
+			return err
+		}
+
+		if err := machineReportRepository.UpdateMachineReportStatus(updateReport); err != nil {
+			return err
+		}
+
+		if updateReport.ReportStatus == model.ReportInProgress {
+			return u.takeOutOfService(tx, machine, report, userID)
+		}
+
+		if report.ReportStatus == model.ReportInProgress {
+			return u.returnToService(tx, report, updateReport.ReportStatus == model.ReportFixed, userID)
+		}
+
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+
 	updatedReport, err := u.machineReportRepository.FindMachinereportByID(updateReport.ReportID)
 	if err != nil {
 		return nil, err
@@ -190,6 +334,33 @@ func (u *machineReportUsecase) DeleteMachineReport(reportID string, userID strin
 	if errchk != nil {
 		return errchk
 	}
-	err := u.machineReportRepository.DeleteMachineReport(reportID)
-	return err
+
+	return u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		machineReportRepository := u.machineReportRepository.WithTx(tx)
+
+		report, err := machineReportRepository.FindMachinereportByID(reportID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := u.machineRepository.WithTx(tx).LockMachine(report.MacineSerial); err != nil {
+			return err
+		}
+
+		report, err = machineReportRepository.FindMachinereportByID(reportID)
+		if err != nil {
+			return err
+		}
+
+		if err := machineReportRepository.DeleteMachineReport(reportID); err != nil {
+			return err
+		}
+
+		// a deleted accepted report no longer keeps its machine out of service
+		if report.ReportStatus == model.ReportInProgress {
+			return u.returnToService(tx, report, false, userID)
+		}
+
+		return nil
+	})
 }
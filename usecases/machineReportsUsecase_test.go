@@ -0,0 +1,271 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeReportRepo struct {
+	model.MachineReportsRepository
+	reports map[string]*model.MachineReports
+}
+
+func (f *fakeReportRepo) WithTx(tx *platform.Postgres) model.MachineReportsRepository { return f }
+
+func (f *fakeReportRepo) FindMachinereportByID(machineReportID string) (*model.MachineReports, error) {
+	report, ok := f.reports[machineReportID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *report
+	return &found, nil
+}
+
+func (f *fakeReportRepo) UpdateMachineReportStatus(updateReport model.UpdateMachineReportStatusDTO) error {
+	f.reports[updateReport.ReportID].ReportStatus = updateReport.ReportStatus
+	return nil
+}
+
+func (f *fakeReportRepo) DeleteMachineReport(reportID string) error {
+	delete(f.reports, reportID)
+	return nil
+}
+
+func (f *fakeReportRepo) CountAccepted(machineSerial string) (int64, error) {
+	var count int64
+	for _, report := range f.reports {
+		if report.MacineSerial == machineSerial && report.ReportStatus == model.ReportInProgress {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeReportRepo) MarkDeactivatedMachine(reportID string) error {
+	f.reports[reportID].DeactivatedMachine = true
+	return nil
+}
+
+func (f *fakeReportRepo) HandOverDeactivation(machineSerial string) error {
+	var first *model.MachineReports
+	for _, report := range f.reports {
+		if report.MacineSerial == machineSerial && report.ReportStatus == model.ReportInProgress && (first == nil || report.ReportID < first.ReportID) {
+			first = report
+		}
+	}
+	if first != nil {
+		first.DeactivatedMachine = true
+	}
+	return nil
+}
+
+func (f *fakeMachineRepo) WithTx(tx *platform.Postgres) repository.MachineRepository { return f }
+
+func (f *fakeMachineRepo) LockMachine(machineSerial string) (*model.Machine, error) {
+	return f.GetByMachineSerial(machineSerial)
+}
+
+func (f *fakeMachineRepo) UpdateActive(machineSerial string, isActive bool, updatedBy string) (*model.Machine, error) {
+	machine := f.machines[machineSerial]
+	machine.IsActive = isActive
+	f.machines[machineSerial] = machine
+	return &machine, nil
+}
+
+type fakeOrderEventRepo struct {
+	repository.OrderEventRepository
+	events []model.OrderEvent
+}
+
+func (f *fakeOrderEventRepo) WithTx(tx *platform.Postgres) repository.OrderEventRepository { return f }
+
+func (f *fakeOrderEventRepo) CreateEvents(events *[]model.OrderEvent) error {
+	f.events = append(f.events, *events...)
+	return nil
+}
+
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+	notifications []model.Notification
+}
+
+func (f *fakeNotificationRepo) WithTx(tx *platform.Postgres) repository.NotificationRepository {
+	return f
+}
+
+func (f *fakeNotificationRepo) CreateNotification(notification *model.Notification) error {
+	f.notifications = append(f.notifications, *notification)
+	return nil
+}
+
+type fakeMaintenanceRepo struct {
+	model.MaintenanceRepository
+	serviced map[string]time.Time
+}
+
+func (f *fakeMaintenanceRepo) WithTx(tx *platform.Postgres) model.MaintenanceRepository { return f }
+
+func (f *fakeMaintenanceRepo) MarkServiced(machineSerial string, servicedAt time.Time) error {
+	f.serviced[machineSerial] = servicedAt
+	return nil
+}
+
+func TestCheckReportTransition(t *testing.T) {
+	tests := []struct {
+		from     model.MachineReportStatus
+		to       model.MachineReportStatus
+		expected bool
+	}{
+		{model.ReportPending, model.ReportInProgress, true},
+		{model.ReportPending, model.ReportCanceled, true},
+		{model.ReportPending, model.ReportFixed, false}, // has to be accepted first
+		{model.ReportInProgress, model.ReportFixed, true},
+		{model.ReportInProgress, model.ReportCanceled, true},
+		{model.ReportInProgress, model.ReportPending, false},
+		{model.ReportFixed, model.ReportInProgress, false}, // Fixed is terminal
+		{model.ReportCanceled, model.ReportPending, false}, // Cancel is terminal
+		{model.ReportPending, "Broken", false},
+	}
+
+	for _, test := range tests {
+		err := CheckReportTransition(test.from, test.to)
+		if (err == nil) != test.expected {
+			t.Errorf("Moving %s to %s, expected allowed %v, but got %v", test.from, test.to, test.expected, err)
+		}
+	}
+}
+
+func TestMaintenanceDue(t *testing.T) {
+	now := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+	cycles := 100
+	days := 30
+
+	tests := []struct {
+		name     string
+		status   model.MaintenanceStatus
+		expected bool
+		reason   string
+	}{
+		{"no schedule", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{LastServiceAt: now.AddDate(-1, 0, 0)}, Cycles: 1000}, false, ""},
+		{"cycles not reached", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{EveryCycles: &cycles, LastServiceAt: now}, Cycles: 99}, false, ""},
+		{"cycles reached", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{EveryCycles: &cycles, LastServiceAt: now}, Cycles: 100}, true, "100 cycles"},
+		{"days not reached", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{EveryDays: &days, LastServiceAt: now.AddDate(0, 0, -29)}}, false, ""},
+		{"days reached", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{EveryDays: &days, LastServiceAt: now.AddDate(0, 0, -30)}}, true, "30 days"},
+		{"whichever first", model.MaintenanceStatus{MaintenancePlan: model.MaintenancePlan{EveryCycles: &cycles, EveryDays: &days, LastServiceAt: now.AddDate(0, 0, -31)}, Cycles: 3}, true, "30 days"},
+	}
+
+	for _, test := range tests {
+		reason, due := maintenanceDue(test.status, now)
+		if due != test.expected {
+			t.Errorf("%s: expected due %v, but got %v", test.name, test.expected, due)
+		}
+		if !strings.Contains(reason, test.reason) {
+			t.Errorf("%s: expected reason with '%s', but got '%s'", test.name, test.reason, reason)
+		}
+	}
+}
+
+func TestMachineReportLifecycle(t *testing.T) {
+	serial := "M-1"
+	waiting := model.OrderDetail{OrderBasketID: "waiting", OrderHeaderID: "h1", MachineSerial: &serial, OrderStatus: model.Waiting}
+	running := model.OrderDetail{OrderBasketID: "running", OrderHeaderID: "h2", MachineSerial: &serial, OrderStatus: model.Processing}
+
+	reportRepo := &fakeReportRepo{reports: map[string]*model.MachineReports{
+		"r1": {ReportID: "r1", MacineSerial: serial, ReportStatus: model.ReportPending},
+		"r2": {ReportID: "r2", MacineSerial: serial, ReportStatus: model.ReportPending},
+	}}
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{serial: {MachineSerial: serial, BranchID: "b1", IsActive: true}}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{"waiting": &waiting, "running": &running}}
+	eventRepo := &fakeOrderEventRepo{}
+	notificationRepo := &fakeNotificationRepo{}
+	maintenanceRepo := &fakeMaintenanceRepo{serviced: map[string]time.Time{}}
+
+	usecase := CreateNewMachineReportUsecase(reportRepo, machineRepo, &fakeBranchRepo{branch: model.Branch{BranchID: "b1"}}, nil, orderDetailRepo, eventRepo, notificationRepo, maintenanceRepo, &fakeUnitOfWork{})
+
+	steps := []struct {
+		name     string
+		reportID string
+		to       model.MachineReportStatus
+		fails    bool
+		active   bool
+	}{
+		{"accept first report", "r1", model.ReportInProgress, false, false},
+		{"accept second report", "r2", model.ReportInProgress, false, false},
+		{"fix first report", "r1", model.ReportFixed, false, false}, // r2 still keeps it out
+		{"reopen fixed report", "r1", model.ReportInProgress, true, false},
+		{"cancel second report", "r2", model.ReportCanceled, false, true},
+	}
+
+	for _, step := range steps {
+		_, err := usecase.UpdateMachineReportStatus(model.UpdateMachineReportStatusDTO{ReportID: step.reportID, ReportStatus: step.to}, "admin", string(model.SuperAdmin))
+		if (err != nil) != step.fails {
+			t.Errorf("%s: expected fails %v, but got %v", step.name, step.fails, err)
+		}
+		if machineRepo.machines[serial].IsActive != step.active {
+			t.Errorf("%s: expected machine active %v, but got %v", step.name, step.active, machineRepo.machines[serial].IsActive)
+		}
+	}
+
+	if waiting.MachineSerial != nil {
+		t.Errorf("Expected the waiting basket to be released, but got machine %s", *waiting.MachineSerial)
+	}
+
+	if running.MachineSerial == nil || running.OrderStatus != model.Processing {
+		t.Errorf("Expected the running basket to stay on the machine, but got %v", running)
+	}
+
+	flagged := 0
+	for _, event := range eventRepo.events {
+		if event.EventType == model.MachineOutOfService && *event.OrderBasketID == "running" {
+			flagged++
+		}
+	}
+	if flagged != 2 || len(notificationRepo.notifications) != 2 {
+		t.Errorf("Expected the running order flagged on both accepts, but got %d events and %d notifications", flagged, len(notificationRepo.notifications))
+	}
+
+	if _, ok := maintenanceRepo.serviced[serial]; !ok {
+		t.Errorf("Expected fixing a report to count as a service")
+	}
+
+	if !reportRepo.reports["r1"].DeactivatedMachine || !reportRepo.reports["r2"].DeactivatedMachine {
+		t.Errorf("Expected r1 to take the machine out of service and hand it to r2, but got %v and %v", reportRepo.reports["r1"].DeactivatedMachine, reportRepo.reports["r2"].DeactivatedMachine)
+	}
+}
+
+func TestMachineReportKeepsMachineSwitchedOff(t *testing.T) {
+	serial := "M-1"
+	reportRepo := &fakeReportRepo{reports: map[string]*model.MachineReports{
+		"r1": {ReportID: "r1", MacineSerial: serial, ReportStatus: model.ReportPending},
+		"r2": {ReportID: "r2", MacineSerial: serial, ReportStatus: model.ReportPending},
+	}}
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{serial: {MachineSerial: serial, BranchID: "b1", IsActive: false}}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{}}
+
+	usecase := CreateNewMachineReportUsecase(reportRepo, machineRepo, &fakeBranchRepo{branch: model.Branch{BranchID: "b1"}}, nil, orderDetailRepo, &fakeOrderEventRepo{}, &fakeNotificationRepo{}, &fakeMaintenanceRepo{serviced: map[string]time.Time{}}, &fakeUnitOfWork{})
+
+	steps := []struct {
+		reportID string
+		to       model.MachineReportStatus
+	}{
+		{"r1", model.ReportInProgress},
+		{"r1", model.ReportFixed},
+		{"r2", model.ReportInProgress},
+		{"r2", model.ReportCanceled},
+	}
+
+	for _, step := range steps {
+		if _, err := usecase.UpdateMachineReportStatus(model.UpdateMachineReportStatusDTO{ReportID: step.reportID, ReportStatus: step.to}, "admin", string(model.SuperAdmin)); err != nil {
+			t.Fatalf("Moving %s to %s, expected no error, but got %v", step.reportID, step.to, err)
+		}
+		if machineRepo.machines[serial].IsActive {
+			t.Errorf("Moving %s to %s, expected the machine switched off by hand to stay off", step.reportID, step.to)
+		}
+	}
+}
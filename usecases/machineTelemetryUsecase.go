@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type machineTelemetryUsecase struct {
+	telemetryRepo   model.MachineTelemetryRepository
+	machineRepo     repository.MachineRepository
+	orderDetailRepo repository.OrderDetailRepository
+	jobRepo         model.JobRepository
+	branchRepo      repository.BranchReopository
+	unitOfWork      repository.UnitOfWork
+}
+
+func CreateNewMachineTelemetryUsecase(telemetryRepo model.MachineTelemetryRepository, machineRepo repository.MachineRepository, orderDetailRepo repository.OrderDetailRepository, jobRepo model.JobRepository, branchRepo repository.BranchReopository, unitOfWork repository.UnitOfWork) model.MachineTelemetryUsecase {
+	return &machineTelemetryUsecase{
+		telemetryRepo:   telemetryRepo,
+		machineRepo:     machineRepo,
+		orderDetailRepo: orderDetailRepo,
+		jobRepo:         jobRepo,
+		branchRepo:      branchRepo,
+		unitOfWork:      unitOfWork,
+	}
+}
+
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateDevice returns the machine when deviceToken is its credential
+func authenticateDevice(machineRepo repository.MachineRepository, machineSerial string, deviceToken string) (*model.Machine, error) {
+	machine, err := machineRepo.GetByMachineSerial(machineSerial)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("ERR 401: unknown machine or wrong device token")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if machine.DeviceTokenHash == nil ||
+		subtle.ConstantTimeCompare([]byte(*machine.DeviceTokenHash), []byte(hashDeviceToken(deviceToken))) != 1 {
+		return nil, errors.New("ERR 401: unknown machine or wrong device token")
+	}
+
+	return machine, nil
+}
+
+// reportedFinishedAt is when the basket in the machine is done according to the
+// report, false when the report says nothing about it. A cycle is only over when
+// a running machine stops without an error, a broken machine is left to staff.
+func reportedFinishedAt(previous *model.MachineState, current model.MachineState) (time.Time, bool) {
+	if current.Running {
+		return current.ReportedAt.Add(time.Second * time.Duration(current.CycleRemaining)), true
+	}
+
+	if previous != nil && previous.Running && current.ErrorCode == nil {
+		return current.ReportedAt, true
+	}
+
+	return time.Time{}, false
+}
+
+// IssueCredential makes a new token for the machine to report with,
+// the token it had before stops working
+func (u *machineTelemetryUsecase) IssueCredential(machineSerial string, userID string, userRole string) (*model.MachineCredential, error) {
+	machine, err := u.machineRepo.GetByMachineSerial(machineSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchOwner(u.branchRepo, machine.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(secret)
+
+	if err := u.telemetryRepo.SetDeviceToken(machineSerial, hashDeviceToken(token)); err != nil {
+		return nil, err
+	}
+
+	return &model.MachineCredential{MachineSerial: machineSerial, DeviceToken: token}, nil
+}
+
+// Report stores what the machine says about itself. While a basket is in the
+// machine its finish time follows the report, and the basket completes as soon
+// as the machine finishes its cycle.
+func (u *machineTelemetryUsecase) Report(machineSerial string, deviceToken string, report *model.MachineTelemetryDTO) (*model.MachineState, error) {
+	if _, err := authenticateDevice(u.machineRepo, machineSerial, deviceToken); err != nil {
+		return nil, err
+	}
+
+	state := model.MachineState{
+		MachineSerial:  machineSerial,
+		DoorOpen:       report.DoorOpen,
+		Running:        report.Running,
+		CycleRemaining: report.CycleRemaining,
+		ErrorCode:      report.ErrorCode,
+		ReportedAt:     time.Now().UTC(),
+	}
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		telemetryRepo := u.telemetryRepo.WithTx(tx)
+		orderDetailRepo := u.orderDetailRepo.WithTx(tx)
+
+		previous, err := telemetryRepo.LockState(machineSerial)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			previous = nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := telemetryRepo.SaveState(&state); err != nil {
+			return err
+		}
+
+		finishedAt, ok := reportedFinishedAt(previous, state)
+		if !ok {
+			return nil
+		}
+
+		basket, err := orderDetailRepo.GetProcessingByMachine(machineSerial)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := orderDetailRepo.UpdateFinishedAt(basket.OrderBasketID, finishedAt); err != nil {
+			return err
+		}
+
+		basket.FinishedAt = &finishedAt
+		return scheduleBasketCompletion(u.jobRepo.WithTx(tx), *basket)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type fakeMachineRepo struct {
+	repository.MachineRepository
+	machines map[string]model.Machine
+}
+
+func (f *fakeMachineRepo) GetByMachineSerial(machineSerial string) (*model.Machine, error) {
+	machine, ok := f.machines[machineSerial]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &machine, nil
+}
+
+type fakeTelemetryRepo struct {
+	model.MachineTelemetryRepository
+	states map[string]model.MachineState
+}
+
+func (f *fakeTelemetryRepo) WithTx(tx *platform.Postgres) model.MachineTelemetryRepository { return f }
+
+func (f *fakeTelemetryRepo) LockState(machineSerial string) (*model.MachineState, error) {
+	state, ok := f.states[machineSerial]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &state, nil
+}
+
+func (f *fakeTelemetryRepo) SaveState(state *model.MachineState) error {
+	f.states[state.MachineSerial] = *state
+	return nil
+}
+
+func TestReportedFinishedAt(t *testing.T) {
+	now := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+	errorCode := "E21"
+
+	tests := []struct {
+		name     string
+		previous *model.MachineState
+		current  model.MachineState
+		expected time.Time
+		ok       bool
+	}{
+		{"running tells the time left", nil, model.MachineState{Running: true, CycleRemaining: 600, ReportedAt: now}, now.Add(10 * time.Minute), true},
+		{"stopping ends the cycle", &model.MachineState{Running: true}, model.MachineState{ReportedAt: now}, now, true},
+		{"idle machine says nothing", &model.MachineState{DoorOpen: true}, model.MachineState{ReportedAt: now}, time.Time{}, false},
+		{"first report of an idle machine", nil, model.MachineState{ReportedAt: now}, time.Time{}, false},
+		{"stopping with an error is left to staff", &model.MachineState{Running: true}, model.MachineState{ErrorCode: &errorCode, ReportedAt: now}, time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		finishedAt, ok := reportedFinishedAt(test.previous, test.current)
+		if ok != test.ok || !finishedAt.Equal(test.expected) {
+			t.Errorf("%s: expected %v %v, but got %v %v", test.name, test.expected, test.ok, finishedAt, ok)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	tokenHash := hashDeviceToken("secret")
+	serial := "WASHER-01"
+	guess := time.Now().UTC().Add(25 * time.Minute)
+
+	machineRepo := &fakeMachineRepo{machines: map[string]model.Machine{
+		serial:      {MachineSerial: serial, DeviceTokenHash: &tokenHash},
+		"DRYER-01":  {MachineSerial: "DRYER-01"},
+		"WASHER-02": {MachineSerial: "WASHER-02", DeviceTokenHash: &tokenHash},
+	}}
+	orderDetailRepo := &fakeJobOrderDetailRepo{details: map[string]*model.OrderDetail{
+		"basket-1": {OrderBasketID: "basket-1", MachineSerial: &serial, OrderStatus: model.Processing, ServiceType: model.Washing, FinishedAt: &guess},
+	}}
+	jobRepo := &fakeJobRepo{}
+	u := CreateNewMachineTelemetryUsecase(&fakeTelemetryRepo{states: map[string]model.MachineState{}}, machineRepo, orderDetailRepo, jobRepo, &fakeBranchRepo{}, &fakeUnitOfWork{})
+
+	tests := []struct {
+		name      string
+		serial    string
+		token     string
+		report    model.MachineTelemetryDTO
+		finishIn  time.Duration
+		expectErr string
+	}{
+		{"wrong token", serial, "guess", model.MachineTelemetryDTO{Running: true}, 25 * time.Minute, "401"},
+		{"machine without credential", "DRYER-01", "secret", model.MachineTelemetryDTO{}, 25 * time.Minute, "401"},
+		{"unknown machine", "WASHER-99", "secret", model.MachineTelemetryDTO{}, 25 * time.Minute, "401"},
+		{"door open before the cycle keeps the guess", serial, "secret", model.MachineTelemetryDTO{DoorOpen: true}, 25 * time.Minute, ""},
+		{"running moves the finish time", serial, "secret", model.MachineTelemetryDTO{Running: true, CycleRemaining: 2400}, 40 * time.Minute, ""},
+		{"stopped is done now", serial, "secret", model.MachineTelemetryDTO{}, 0, ""},
+		{"machine without basket", "WASHER-02", "secret", model.MachineTelemetryDTO{Running: true, CycleRemaining: 60}, 0, ""},
+	}
+
+	for _, test := range tests {
+		state, err := u.Report(test.serial, test.token, &test.report)
+		if test.expectErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: expected no error, but got %v", test.name, err)
+			continue
+		} else if state.Running != test.report.Running || state.DoorOpen != test.report.DoorOpen {
+			t.Errorf("%s: expected state %+v, but got %+v", test.name, test.report, state)
+		}
+
+		finishIn := time.Until(*orderDetailRepo.details["basket-1"].FinishedAt)
+		if finishIn > test.finishIn+time.Second || finishIn < test.finishIn-time.Second {
+			t.Errorf("%s: expected basket done in %v, but got %v", test.name, test.finishIn, finishIn)
+		}
+	}
+
+	if len(jobRepo.enqueued) != 2 {
+		t.Fatalf("expected the completion to be scheduled twice, but got %v", jobRepo.enqueued)
+	}
+	last := jobRepo.enqueued[1]
+	if last.JobType != model.CompleteBasketJob || last.SubjectID != "basket-1" || last.RunAt.After(time.Now().UTC()) {
+		t.Errorf("expected basket-1 to complete now, but got %+v", last)
+	}
+}
@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"fmt"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type maintenanceUsecase struct {
+	maintenanceRepo   model.MaintenanceRepository
+	machineReportRepo model.MachineReportsRepository
+	machineRepo       repository.MachineRepository
+	branchRepo        repository.BranchReopository
+	notificationRepo  repository.NotificationRepository
+	unitOfWork        repository.UnitOfWork
+}
+
+func CreateNewMaintenanceUsecase(maintenanceRepo model.MaintenanceRepository, machineReportRepo model.MachineReportsRepository, machineRepo repository.MachineRepository, branchRepo repository.BranchReopository, notificationRepo repository.NotificationRepository, unitOfWork repository.UnitOfWork) model.MaintenanceUsecase {
+	return &maintenanceUsecase{
+		maintenanceRepo:   maintenanceRepo,
+		machineReportRepo: machineReportRepo,
+		machineRepo:       machineRepo,
+		branchRepo:        branchRepo,
+		notificationRepo:  notificationRepo,
+		unitOfWork:        unitOfWork,
+	}
+}
+
+// maintenanceDue tells whether the machine should be serviced by now and why,
+// the cycle count is checked before the calendar
+func maintenanceDue(status model.MaintenanceStatus, now time.Time) (string, bool) {
+	if status.EveryCycles != nil && status.Cycles >= int64(*status.EveryCycles) {
+		return fmt.Sprintf("Preventive maintenance, %d cycles since the last service", status.Cycles), true
+	}
+
+	if status.EveryDays != nil && !now.Before(status.LastServiceAt.AddDate(0, 0, *status.EveryDays)) {
+		return fmt.Sprintf("Preventive maintenance, %d days since the last service", *status.EveryDays), true
+	}
+
+	return "", false
+}
+
+func (u *maintenanceUsecase) SetPlan(machineSerial string, plan *model.SetMaintenancePlanDTO, userID string, userRole string) (*model.MaintenanceStatus, error) {
+	machine, err := u.machineRepo.GetByMachineSerial(machineSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchOwner(u.branchRepo, machine.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	// a machine without a plan starts counting now, changing the plan keeps its last service
+	data := model.MaintenancePlan{
+		MachineSerial: machineSerial,
+		EveryCycles:   plan.EveryCycles,
+		EveryDays:     plan.EveryDays,
+		LastServiceAt: time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+		UpdatedBy:     userID,
+	}
+
+	if err := u.maintenanceRepo.SavePlan(&data); err != nil {
+		return nil, err
+	}
+
+	return u.maintenanceRepo.FindPlan(machineSerial)
+}
+
+func (u *maintenanceUsecase) GetPlan(machineSerial string, userID string, userRole string) (*model.MaintenanceStatus, error) {
+	machine, err := u.machineRepo.GetByMachineSerial(machineSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchOwner(u.branchRepo, machine.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return u.maintenanceRepo.FindPlan(machineSerial)
+}
+
+// openPreventiveReport opens the report and tells the branch, nothing happens
+// when the machine still has a preventive report open
+func (u *maintenanceUsecase) openPreventiveReport(status model.MaintenanceStatus, reason string) (bool, error) {
+	opened := false
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		report := model.MachineReports{
+			ReportID:          uuid.New().String(),
+			UserID:            model.SystemActor,
+			ReportDescription: reason,
+			MacineSerial:      status.MachineSerial,
+			ReportStatus:      model.ReportPending,
+			CreatedAt:         time.Now().UTC(),
+			Preventive:        true,
+		}
+
+		created, err := u.machineReportRepo.WithTx(tx).CreateOpenPreventive(&report)
+		if err != nil || !created {
+			return err
+		}
+
+		notification := model.Notification{
+			NotificationID: uuid.New().String(),
+			BranchID:       status.BranchID,
+			Title:          "Preventive maintenance due",
+			Message:        fmt.Sprintf("Machine %s: %s", status.MachineSerial, reason),
+			CreatedAt:      time.Now().UTC(),
+		}
+
+		if err := u.notificationRepo.WithTx(tx).CreateNotification(&notification); err != nil {
+			return err
+		}
+
+		opened = true
+		return nil
+	})
+
+	return opened, err
+}
+
+// OpenDueMaintenance is run by the cron, it opens a preventive report for every
+// machine that is due. The machine keeps running until staff accept the report.
+func (u *maintenanceUsecase) OpenDueMaintenance() (int, error) {
+	plans, err := u.maintenanceRepo.GetPlans()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	opened := 0
+	for _, plan := range *plans {
+		reason, due := maintenanceDue(plan, now)
+		if !due {
+			continue
+		}
+
+		created, err := u.openPreventiveReport(plan, reason)
+		if err != nil {
+			return opened, err
+		}
+
+		if created {
+			opened++
+		}
+	}
+
+	return opened, nil
+}
@@ -0,0 +1,46 @@
+package usecases
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+)
+
+type NotificationUsecase interface {
+	GetByBranchID(branchID string, unreadOnly bool, userID string, userRole string) (*[]model.Notification, error)
+	MarkRead(notificationID string, userID string, userRole string) (*model.Notification, error)
+}
+
+type notificationUsecase struct {
+	notificationRepo repository.NotificationRepository
+	branchRepo       repository.BranchReopository
+	contractRepo     repository.EmployeeContractRepository
+}
+
+func CreateNewNotificationUsecase(notificationRepo repository.NotificationRepository, branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository) NotificationUsecase {
+	return &notificationUsecase{
+		notificationRepo: notificationRepo,
+		branchRepo:       branchRepo,
+		contractRepo:     contractRepo,
+	}
+}
+
+func (u *notificationUsecase) GetByBranchID(branchID string, unreadOnly bool, userID string, userRole string) (*[]model.Notification, error) {
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, branchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return u.notificationRepo.GetByBranchID(branchID, unreadOnly)
+}
+
+func (u *notificationUsecase) MarkRead(notificationID string, userID string, userRole string) (*model.Notification, error) {
+	notification, err := u.notificationRepo.GetByNotificationID(notificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, notification.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return u.notificationRepo.MarkRead(notificationID, userID)
+}
@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+// basket status a staff member can move to from each status,
+// Expired is only ever set by the system when the payment expires.
+// Baskets are only canceled with their whole order, see CancelOrder,
+// so the payment is canceled or refunded along with them.
+var orderStatusTransitions = map[model.OrderStatus][]model.OrderStatus{
+	model.Waiting:      {model.Processing},
+	model.Processing:   {model.Completed},
+	model.Completed:    {},
+	model.Canceled:     {},
+	model.OrderExpired: {},
+}
+
+// serviceStage orders baskets of an online order,
+// Pickup -> Washing (and Agents) -> Drying -> Delivery
+func serviceStage(serviceType model.ServiceType) int {
+	switch serviceType {
+	case model.Pickup:
+		return 0
+	case model.Washing, model.Agents:
+		return 1
+	case model.Drying:
+		return 2
+	case model.Delivery:
+		return 3
+	}
+	return 0
+}
+
+// blockingBasket returns a basket of an earlier stage that is not done yet,
+// a basket can only start when everything before it is completed or canceled
+func blockingBasket(detail *model.OrderDetail, details []model.OrderDetail) *model.OrderDetail {
+	stage := serviceStage(detail.ServiceType)
+	for i, d := range details {
+		if d.OrderBasketID == detail.OrderBasketID || serviceStage(d.ServiceType) >= stage {
+			continue
+		}
+		if d.OrderStatus != model.Completed && d.OrderStatus != model.Canceled {
+			return &details[i]
+		}
+	}
+	return nil
+}
+
+// NextOrderStatus lists the status the basket can be moved to right now
+func NextOrderStatus(detail *model.OrderDetail, details []model.OrderDetail) []model.OrderStatus {
+	nextStatus := []model.OrderStatus{}
+	for _, to := range orderStatusTransitions[detail.OrderStatus] {
+		if CheckOrderTransition(detail, to, details) == nil {
+			nextStatus = append(nextStatus, to)
+		}
+	}
+	return nextStatus
+}
+
+// CheckOrderTransition validates moving a basket to another status,
+// details are every basket of the same order. Keeping the same status is allowed
+// so staff can still update machine or finish time of a basket.
+func CheckOrderTransition(detail *model.OrderDetail, to model.OrderStatus, details []model.OrderDetail) error {
+	if detail.OrderStatus == to {
+		return nil
+	}
+
+	isAllowed := false
+	for _, next := range orderStatusTransitions[detail.OrderStatus] {
+		if next == to {
+			isAllowed = true
+			break
+		}
+	}
+
+	if !isAllowed && to == model.Canceled {
+		return &model.InvalidOrderTransitionError{From: detail.OrderStatus, To: to, Reason: "baskets are canceled with their order"}
+	}
+
+	if !isAllowed {
+		return &model.InvalidOrderTransitionError{From: detail.OrderStatus, To: to, Reason: "transition not allowed"}
+	}
+
+	if to == model.Processing {
+		if blocking := blockingBasket(detail, details); blocking != nil {
+			return &model.InvalidOrderTransitionError{
+				From:   detail.OrderStatus,
+				To:     to,
+				Reason: string(blocking.ServiceType) + " is not completed yet",
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeriveOrderStatus sums up the status of every basket into the status of the order
+func DeriveOrderStatus(details []model.OrderDetail) model.OrderStatus {
+	counts := make(map[model.OrderStatus]int)
+	for _, d := range details {
+		counts[d.OrderStatus] += 1
+	}
+
+	total := len(details)
+
+	switch {
+	case total == 0:
+		return model.Waiting
+	case counts[model.OrderExpired] > 0 && counts[model.OrderExpired]+counts[model.Canceled] == total:
+		return model.OrderExpired
+	case counts[model.Canceled] == total:
+		return model.Canceled
+	case counts[model.Completed]+counts[model.Canceled] == total:
+		return model.Completed
+	case counts[model.Waiting]+counts[model.Canceled] == total:
+		return model.Waiting
+	}
+
+	return model.Processing
+}
@@ -0,0 +1,69 @@
+package usecases
+
+import (
+	"testing"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+func basket(id string, serviceType model.ServiceType, status model.OrderStatus) model.OrderDetail {
+	return model.OrderDetail{OrderBasketID: id, ServiceType: serviceType, OrderStatus: status}
+}
+
+func TestCheckOrderTransition(t *testing.T) {
+	tests := []struct {
+		details  []model.OrderDetail
+		to       model.OrderStatus
+		expected bool
+	}{
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting)}, model.Processing, true},                                               // Waiting -> Processing
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting)}, model.Completed, false},                                               // skip Processing
+		{[]model.OrderDetail{basket("a", model.Washing, model.Processing)}, model.Completed, true},                                             // Processing -> Completed
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting)}, model.Canceled, false},                                                // baskets are canceled with their order
+		{[]model.OrderDetail{basket("a", model.Washing, model.Processing)}, model.Canceled, false},                                             // running baskets too
+		{[]model.OrderDetail{basket("a", model.Washing, model.Completed)}, model.Processing, false},                                            // Completed is terminal
+		{[]model.OrderDetail{basket("a", model.Washing, model.OrderExpired)}, model.Waiting, false},                                            // Expired is terminal
+		{[]model.OrderDetail{basket("a", model.Washing, model.Processing)}, model.Processing, true},                                            // same status
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting)}, model.OrderExpired, false},                                            // Expired is system only
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting), basket("b", model.Pickup, model.Waiting)}, model.Processing, false},    // Pickup not done
+		{[]model.OrderDetail{basket("a", model.Washing, model.Waiting), basket("b", model.Pickup, model.Completed)}, model.Processing, true},   // Pickup done
+		{[]model.OrderDetail{basket("a", model.Drying, model.Waiting), basket("b", model.Washing, model.Processing)}, model.Processing, false}, // Washing still running
+		{[]model.OrderDetail{basket("a", model.Drying, model.Waiting), basket("b", model.Washing, model.Canceled)}, model.Processing, true},    // Washing canceled
+		{[]model.OrderDetail{basket("a", model.Drying, model.Waiting), basket("b", model.Washing, model.Canceled)}, model.Canceled, false},     // not even after another basket was
+	}
+
+	for i, test := range tests {
+		err := CheckOrderTransition(&test.details[0], test.to, test.details)
+		if (err == nil) != test.expected {
+			t.Errorf("Case %d: moving %s to %s, expected allowed %v, but got %v", i, test.details[0].OrderStatus, test.to, test.expected, err)
+		}
+	}
+}
+
+func TestDeriveOrderStatus(t *testing.T) {
+	tests := []struct {
+		statuses []model.OrderStatus
+		expected model.OrderStatus
+	}{
+		{[]model.OrderStatus{}, model.Waiting},
+		{[]model.OrderStatus{model.Waiting, model.Waiting}, model.Waiting},
+		{[]model.OrderStatus{model.Waiting, model.Processing}, model.Processing},
+		{[]model.OrderStatus{model.Completed, model.Waiting}, model.Processing},
+		{[]model.OrderStatus{model.Completed, model.Canceled}, model.Completed},
+		{[]model.OrderStatus{model.Canceled, model.Canceled}, model.Canceled},
+		{[]model.OrderStatus{model.OrderExpired, model.OrderExpired}, model.OrderExpired},
+		{[]model.OrderStatus{model.OrderExpired, model.Canceled}, model.OrderExpired},
+		{[]model.OrderStatus{model.Waiting, model.Canceled}, model.Waiting},
+	}
+
+	for i, test := range tests {
+		details := []model.OrderDetail{}
+		for _, status := range test.statuses {
+			details = append(details, model.OrderDetail{OrderStatus: status})
+		}
+
+		result := DeriveOrderStatus(details)
+		if result != test.expected {
+			t.Errorf("Case %d: for %v, expected %s, but got %s", i, test.statuses, test.expected, result)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package usecases
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
 	repo "zuck-my-clothe/zuck-my-clothe-backend/repository"
 
 	"github.com/google/uuid"
@@ -13,37 +18,93 @@ import (
 )
 
 type orderUsecase struct {
-	orderDetailRepo repo.OrderDetailRepository
-	orderHeaderRepo repo.OrderHeaderRepository
-	userRepo        repo.UserRepository
-	machineRepo     repo.MachineRepository
-	contractRepo    repo.EmployeeContractRepository
-	paymentUsecase  model.PaymentUsecase
+	orderDetailRepo  repo.OrderDetailRepository
+	orderHeaderRepo  repo.OrderHeaderRepository
+	userRepo         repo.UserRepository
+	machineRepo      repo.MachineRepository
+	contractRepo     repo.EmployeeContractRepository
+	paymentUsecase   model.PaymentUsecase
+	unitOfWork       repo.UnitOfWork
+	servicePriceRepo model.ServicePriceRepository
+	priceLineRepo    repo.OrderPriceLineRepository
+	orderEventRepo   repo.OrderEventRepository
+	notificationRepo repo.NotificationRepository
+	refundRepo       model.RefundRepository
+	promoCodeRepo    model.PromoCodeRepository
+	loyaltyRepo      model.LoyaltyRepository
+	jobRepo          model.JobRepository
+	washProgramRepo  model.WashProgramRepository
+	branchRepo       repo.BranchReopository
 }
 
 type OrderUsecase interface {
 	CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrder, error)
-	GetAll() ([]interface{}, error)
+	QuoteOrder(newOrder *model.NewOrder) (*model.OrderQuote, error)
+	GetAll(filter model.OrderListFilter) (*model.OrderPage, error)
 	GetByHeaderID(orderHeaderID string, isAdminView bool, option string) (interface{}, error)
-	GetByBranchID(branchID string, managerUserID string, status string) ([]interface{}, error)
-	GetByUserID(userID string, status string) ([]interface{}, error)
+	GetByBranchID(branchID string, userID string, userRole string, filter model.OrderListFilter) (*model.OrderPage, error)
+	GetByUserID(userID string, filter model.OrderListFilter) (*model.OrderPage, error)
 	UpdateStatus(order model.UpdateOrder) (interface{}, error)
 	UpdateReview(review model.OrderReview) (*model.FullOrder, error)
 	SoftDelete(orderHeaderID string, deletedBy string) (*model.FullOrder, error)
+	CancelOrder(orderHeaderID string, userID string) (*model.FullOrder, error)
+	GetTimeline(orderHeaderID string, userID string, role string) ([]model.OrderTimelineEvent, error)
 }
 
-func CreateOrderUsecase(orderHeaderRepository repo.OrderHeaderRepository, orderDetailRepository repo.OrderDetailRepository, userRepository repo.UserRepository, machineRepo repo.MachineRepository, paymentUsecase model.PaymentUsecase, contractRepo repo.EmployeeContractRepository) OrderUsecase {
+func CreateOrderUsecase(orderHeaderRepository repo.OrderHeaderRepository, orderDetailRepository repo.OrderDetailRepository, userRepository repo.UserRepository, machineRepo repo.MachineRepository, paymentUsecase model.PaymentUsecase, contractRepo repo.EmployeeContractRepository, unitOfWork repo.UnitOfWork, servicePriceRepo model.ServicePriceRepository, priceLineRepo repo.OrderPriceLineRepository, orderEventRepo repo.OrderEventRepository, notificationRepo repo.NotificationRepository, refundRepo model.RefundRepository, promoCodeRepo model.PromoCodeRepository, loyaltyRepo model.LoyaltyRepository, jobRepo model.JobRepository, washProgramRepo model.WashProgramRepository, branchRepo repo.BranchReopository) OrderUsecase {
 	return &orderUsecase{
-		orderHeaderRepo: orderHeaderRepository,
-		orderDetailRepo: orderDetailRepository,
-		userRepo:        userRepository,
-		machineRepo:     machineRepo,
-		paymentUsecase:  paymentUsecase,
-		contractRepo:    contractRepo,
+		orderHeaderRepo:  orderHeaderRepository,
+		orderDetailRepo:  orderDetailRepository,
+		userRepo:         userRepository,
+		machineRepo:      machineRepo,
+		paymentUsecase:   paymentUsecase,
+		contractRepo:     contractRepo,
+		unitOfWork:       unitOfWork,
+		servicePriceRepo: servicePriceRepo,
+		priceLineRepo:    priceLineRepo,
+		orderEventRepo:   orderEventRepo,
+		notificationRepo: notificationRepo,
+		refundRepo:       refundRepo,
+		promoCodeRepo:    promoCodeRepo,
+		loyaltyRepo:      loyaltyRepo,
+		jobRepo:          jobRepo,
+		washProgramRepo:  washProgramRepo,
+		branchRepo:       branchRepo,
+	}
+}
+
+// scheduleBasketCompletion schedules a running washing or drying basket to complete
+// when its machine is done, other baskets are moved along by staff
+func scheduleBasketCompletion(jobRepo model.JobRepository, detail model.OrderDetail) error {
+	if _, ok := serviceMachineMapper(detail.ServiceType); !ok {
+		return nil
+	}
+
+	if detail.OrderStatus != model.Processing || detail.FinishedAt == nil {
+		return nil
+	}
+
+	job := model.NewJob(model.CompleteBasketJob, detail.OrderBasketID, *detail.FinishedAt)
+	return jobRepo.Enqueue(&job)
+}
+
+func newOrderEvent(orderHeaderID string, orderBasketID *string, eventType model.OrderEventType, actorID string) model.OrderEvent {
+	return model.OrderEvent{
+		EventID:       uuid.New().String(),
+		OrderHeaderID: orderHeaderID,
+		OrderBasketID: orderBasketID,
+		EventType:     eventType,
+		ActorID:       actorID,
+		CreatedAt:     time.Now().UTC(),
 	}
 }
 
 func toUserDetailDTO(userModel *model.Users) *model.UserDetailDTO {
+	// user could be deleted already, the order is still shown without the detail
+	if userModel == nil {
+		return &model.UserDetailDTO{}
+	}
+
 	detail := model.UserDetailDTO{
 		UserID:          userModel.UserID,
 		GoogleID:        userModel.GoogleID,
@@ -57,20 +118,121 @@ func toUserDetailDTO(userModel *model.Users) *model.UserDetailDTO {
 	return &detail
 }
 
-func servicePriceMapper(weight int) int {
-	var price int = 0
-	switch weight {
-	case 7:
-		price = model.SevenKGMachinePrice
-	case 14:
-		price = model.FourteenKGMachinePrice
-	case 21:
-		price = model.TwentyOneKGMachinePrice
+// cycleEnd is when a basket that goes into its machine at start is done,
+// by the program picked for it or the default cycle
+func cycleEnd(program *model.WashProgram, start time.Time) time.Time {
+	minutes := model.DefaultCycleMinutes
+	if program != nil {
+		minutes = program.DurationMinutes
+	}
+	return start.Add(time.Minute * time.Duration(minutes))
+}
+
+// programPrice is the catalog price scaled by the program, rounded to satang
+func programPrice(price float64, program *model.WashProgram) float64 {
+	if program == nil {
+		return price
+	}
+	return math.Round(float64(toSatang(price))*program.PriceModifier) / 100
+}
+
+// findProgram returns the program picked for a basket of serviceType,
+// nil when the basket runs the default cycle
+func (u *orderUsecase) findProgram(programID *string, serviceType model.ServiceType) (*model.WashProgram, error) {
+	if programID == nil {
+		return nil, nil
+	}
+
+	machineType, ok := serviceMachineMapper(serviceType)
+	if !ok {
+		return nil, errors.New("ERR 400: only washing and drying have programs")
+	}
+
+	program, err := u.washProgramRepo.FindByProgramID(*programID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("ERR 400: no such program")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if program.MachineType != machineType {
+		return nil, errors.New("ERR 400: program does not fit this machine")
+	}
+
+	return program, nil
+}
+
+func sameProgram(a *string, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// priceOrderDetails looks up the catalog price of every basket and scales it by the
+// basket's program, baskets with the same service, weight and program are grouped
+// into one price line
+func (u *orderUsecase) priceOrderDetails(branchID string, orderHeaderID string, orderDetails []model.OrderDetail, programs map[string]*model.WashProgram) ([]model.OrderPriceLine, float64, error) {
+	var priceLines []model.OrderPriceLine
+	var calculatedPrice float64 = 0.0
+	now := time.Now().UTC()
+
+	for _, detail := range orderDetails {
+		lineIndex := -1
+		for i, line := range priceLines {
+			if line.ServiceType == detail.ServiceType && line.Weight == detail.Weight && sameProgram(line.ProgramID, detail.ProgramID) {
+				lineIndex = i
+				break
+			}
+		}
+
+		if lineIndex >= 0 {
+			priceLines[lineIndex].Quantity += 1
+			priceLines[lineIndex].Amount += priceLines[lineIndex].UnitPrice
+			calculatedPrice += priceLines[lineIndex].UnitPrice
+			continue
+		}
+
+		price, err := u.servicePriceRepo.FindEffectivePrice(branchID, detail.ServiceType, detail.Weight, now)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, fmt.Errorf("ERR: no price for %s %d Kg in this branch", detail.ServiceType, detail.Weight)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		unitPrice := price.Price
+		var programName *string
+		if detail.ProgramID != nil {
+			program := programs[*detail.ProgramID]
+			unitPrice = programPrice(price.Price, program)
+			programName = &program.Name
+		}
+
+		priceLines = append(priceLines, model.OrderPriceLine{
+			PriceLineID:   uuid.New().String(),
+			OrderHeaderID: orderHeaderID,
+			LineNo:        len(priceLines) + 1,
+			PriceID:       &price.PriceID,
+			ProgramID:     detail.ProgramID,
+			ProgramName:   programName,
+			ServiceType:   detail.ServiceType,
+			Weight:        detail.Weight,
+			Quantity:      1,
+			UnitPrice:     unitPrice,
+			Amount:        unitPrice,
+			CreatedAt:     now,
+		})
+		calculatedPrice += unitPrice
 	}
-	return price
+
+	return priceLines, calculatedPrice, nil
 }
 
 func combineFullOrder(h *model.OrderHeader, d *[]model.OrderDetail, user *model.Users, isAdminView bool) *model.FullOrder {
+	details := make([]model.OrderDetail, len(*d))
+	copy(details, *d)
+	for i := range details {
+		details[i].NextStatus = NextOrderStatus(&details[i], *d)
+	}
+
 	fullOrder := model.FullOrder{
 		OrderHeaderID:   h.OrderHeaderID,
 		UserID:          h.UserID,
@@ -90,7 +252,8 @@ func combineFullOrder(h *model.OrderHeader, d *[]model.OrderDetail, user *model.
 		UpdatedBy:       &h.UpdatedBy,
 		DeletedAt:       &h.DeletedAt,
 		DeletedBy:       h.DeletedBy,
-		OrderDetails:    *d,
+		OrderStatus:     DeriveOrderStatus(*d),
+		OrderDetails:    details,
 	}
 
 	if !isAdminView {
@@ -101,7 +264,94 @@ func combineFullOrder(h *model.OrderHeader, d *[]model.OrderDetail, user *model.
 	return &fullOrder
 }
 
-func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrder, error) {
+// preparedOrder is everything CreateNewOrder writes, built and priced
+// without touching the database
+type preparedOrder struct {
+	header       model.OrderHeader
+	details      []model.OrderDetail
+	priceLines   []model.OrderPriceLine
+	subtotal     float64
+	promoCode    *model.PromoCode
+	redeemPoints int64
+	discount     float64
+	totalPrice   float64
+}
+
+// applyPromoCode takes the discount of the promo code off the order as its own price line
+func (u *orderUsecase) applyPromoCode(prepared *preparedOrder, code string) error {
+	promo, err := u.promoCodeRepo.FindByCode(normalizePromoCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("ERR 400: promo code not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	usage, err := u.promoCodeRepo.CountRedemptions(promo.PromoCodeID, prepared.header.UserID)
+	if err != nil {
+		return err
+	}
+
+	discount, err := promoDiscount(promo, prepared.header.BranchID, prepared.totalPrice, time.Now().UTC(), usage)
+	if err != nil {
+		return err
+	}
+
+	prepared.priceLines = append(prepared.priceLines, model.OrderPriceLine{
+		PriceLineID:   uuid.New().String(),
+		OrderHeaderID: prepared.header.OrderHeaderID,
+		LineNo:        len(prepared.priceLines) + 1,
+		PromoCodeID:   &promo.PromoCodeID,
+		ServiceType:   model.DiscountLine,
+		Quantity:      1,
+		UnitPrice:     -discount,
+		Amount:        -discount,
+		CreatedAt:     time.Now().UTC(),
+	})
+	prepared.promoCode = promo
+	prepared.discount = discount
+	prepared.totalPrice = float64(toSatang(prepared.totalPrice)-toSatang(discount)) / 100
+
+	return nil
+}
+
+// applyPoints takes redeemed points off the order as its own price line,
+// the balance is only checked here, CreateNewOrder spends them under the account lock
+func (u *orderUsecase) applyPoints(prepared *preparedOrder, points int64) error {
+	discount := pointsDiscount(points)
+	if toSatang(discount) >= toSatang(prepared.totalPrice) {
+		return errors.New("ERR 400: points can't cover the whole order")
+	}
+
+	balance, err := u.loyaltyRepo.GetBalance(prepared.header.UserID)
+	if err != nil {
+		return err
+	}
+
+	if balance < points {
+		return errors.New("ERR 400: not enough points")
+	}
+
+	prepared.priceLines = append(prepared.priceLines, model.OrderPriceLine{
+		PriceLineID:   uuid.New().String(),
+		OrderHeaderID: prepared.header.OrderHeaderID,
+		LineNo:        len(prepared.priceLines) + 1,
+		ServiceType:   model.PointsLine,
+		Quantity:      1,
+		UnitPrice:     -discount,
+		Amount:        -discount,
+		CreatedAt:     time.Now().UTC(),
+	})
+	prepared.redeemPoints = points
+	prepared.discount = float64(toSatang(prepared.discount)+toSatang(discount)) / 100
+	prepared.totalPrice = float64(toSatang(prepared.totalPrice)-toSatang(discount)) / 100
+
+	return nil
+}
+
+// prepareOrder validates the new order and prices it from the catalog.
+// Quote and create both go through here so they can never disagree.
+func (u *orderUsecase) prepareOrder(newOrder *model.NewOrder) (*preparedOrder, error) {
 	// validate order detail zuck onsite - online
 	if newOrder.ZuckOnsite {
 		if newOrder.DeliveryAddress != nil ||
@@ -120,14 +370,14 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 			return nil, errors.New("ERR: zuck onsite order detail policy violated")
 		}
 
+		// only a quick check, CreateNewOrder checks again under the machine lock
 		isAvailable, err := u.machineRepo.MachineWangMaiWa(*newOrder.OrderDetails[0].MachineSerial)
-
 		if err != nil {
 			return nil, errors.New("ERR: something wrong while check available")
 		}
 
 		if !isAvailable {
-			return nil, errors.New("ERR: mai wang ja")
+			return nil, &model.MachineBusyError{MachineSerial: *newOrder.OrderDetails[0].MachineSerial}
 		}
 	} else {
 		if newOrder.DeliveryAddress == nil ||
@@ -156,8 +406,6 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 
 	var washingBasketCount int = 0
 	var dryinBasketCount int = 0
-	var basketWeight int16 = 0
-	var dryingWeight int16 = 0
 
 	var isDeliveryExist bool = false
 	var isPickupExist bool = false
@@ -168,9 +416,7 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 		if serviceType == "Washing" {
 			allWashingWeight += detail.Weight
 			washingBasketCount += 1
-			basketWeight = detail.Weight
 		} else if serviceType == "Drying" {
-			dryingWeight = detail.Weight
 			allDryingweight += detail.Weight
 			dryinBasketCount += 1
 		} else if serviceType == "Pickup" {
@@ -211,32 +457,12 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 		}
 	}
 
-	var calculatedPrice float64 = 0.0
-	washingUnitPrice := servicePriceMapper(int(basketWeight))
-	calculatedPrice += float64(washingBasketCount * washingUnitPrice)
-	dryingUnitPrice := servicePriceMapper(int(dryingWeight))
-	calculatedPrice += float64(dryingUnitPrice * dryinBasketCount)
-	if !newOrder.ZuckOnsite {
-		calculatedPrice += float64(model.DeliveryPrice + model.PickupPrice)
-	}
-	if isAgentsExist {
-		calculatedPrice += float64(model.AgentsPrice)
-	}
-
-	// Create new payment
-	payment := model.Payments{Amount: calculatedPrice}
-	paymentResponse, err := u.paymentUsecase.CreatePayment(payment)
-	if err != nil {
-		return nil, errors.New("ERR: cannont create payment")
-	}
-
 	// actually create order starts here
 	orderHeader := model.OrderHeader{
 		OrderHeaderID:   uuid.New().String(),
 		UserID:          newOrder.UserID,
 		BranchID:        newOrder.BranchID,
 		OrderNote:       newOrder.OrderNote,
-		PaymentID:       paymentResponse.PaymentID,
 		ZuckOnsite:      newOrder.ZuckOnsite,
 		DeliveryAddress: newOrder.DeliveryAddress,
 		DeliveryLat:     newOrder.DeliveryLat,
@@ -247,15 +473,10 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 		UpdatedBy:       newOrder.UserID,
 	}
 
-	header, err := u.orderHeaderRepo.CreateOrderHeader(&orderHeader)
-
-	if err != nil {
-		return nil, err
-	}
-
 	var orderDetails []model.OrderDetail
+	programs := map[string]*model.WashProgram{}
 
-	if header.ZuckOnsite {
+	if newOrder.ZuckOnsite {
 		machineData, merr := u.machineRepo.GetByMachineSerial(*newOrder.OrderDetails[0].MachineSerial)
 
 		if merr != nil {
@@ -267,7 +488,17 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 			machineType = "Drying"
 		}
 
-		var finishedTime = time.Now().UTC().Add(time.Minute * 25)
+		programID := newOrder.OrderDetails[0].ProgramID
+		program, err := u.findProgram(programID, machineType)
+		if err != nil {
+			return nil, err
+		}
+		if program != nil {
+			programs[program.ProgramID] = program
+		}
+
+		startedTime := time.Now().UTC()
+		finishedTime := cycleEnd(program, startedTime)
 		d := model.OrderDetail{
 			OrderBasketID: uuid.New().String(),
 			OrderHeaderID: orderHeader.OrderHeaderID,
@@ -275,6 +506,8 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 			Weight:        machineData.Weight,
 			OrderStatus:   model.Processing,
 			ServiceType:   machineType,
+			ProgramID:     programID,
+			StartedAt:     &startedTime,
 			FinishedAt:    &finishedTime,
 			CreatedBy:     &newOrder.UserID,
 			UpdatedBy:     &newOrder.UserID,
@@ -283,6 +516,14 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 		orderDetails = append(orderDetails, d)
 	} else {
 		for _, detail := range newOrder.OrderDetails {
+			program, err := u.findProgram(detail.ProgramID, detail.ServiceType)
+			if err != nil {
+				return nil, err
+			}
+			if program != nil {
+				programs[program.ProgramID] = program
+			}
+
 			d := model.OrderDetail{
 				OrderBasketID: uuid.New().String(),
 				OrderHeaderID: orderHeader.OrderHeaderID,
@@ -290,6 +531,7 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 				Weight:        detail.Weight,
 				OrderStatus:   model.Waiting,
 				ServiceType:   detail.ServiceType,
+				ProgramID:     detail.ProgramID,
 				FinishedAt:    nil,
 				CreatedBy:     &newOrder.UserID,
 				UpdatedBy:     &newOrder.UserID,
@@ -298,262 +540,400 @@ func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrde
 		}
 	}
 
-	details, err := u.orderDetailRepo.CreateOrderDetails(&orderDetails)
-
+	priceLines, calculatedPrice, err := u.priceOrderDetails(newOrder.BranchID, orderHeader.OrderHeaderID, orderDetails, programs)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := u.userRepo.FindUserByUserID(newOrder.UserID)
+	prepared := preparedOrder{
+		header:     orderHeader,
+		details:    orderDetails,
+		priceLines: priceLines,
+		subtotal:   calculatedPrice,
+		totalPrice: calculatedPrice,
+	}
+
+	if newOrder.PromoCode != nil && *newOrder.PromoCode != "" {
+		if err := u.applyPromoCode(&prepared, *newOrder.PromoCode); err != nil {
+			return nil, err
+		}
+	}
+
+	if newOrder.RedeemPoints > 0 {
+		if err := u.applyPoints(&prepared, newOrder.RedeemPoints); err != nil {
+			return nil, err
+		}
+	}
+
+	return &prepared, nil
+}
+
+func (u *orderUsecase) QuoteOrder(newOrder *model.NewOrder) (*model.OrderQuote, error) {
+	prepared, err := u.prepareOrder(newOrder)
 	if err != nil {
 		return nil, err
 	}
-	res := combineFullOrder(header, details, user, false)
 
-	return res, nil
+	// nothing is written, so lines don't belong to any order yet
+	for i := range prepared.priceLines {
+		prepared.priceLines[i].PriceLineID = ""
+		prepared.priceLines[i].OrderHeaderID = ""
+	}
+
+	quote := model.OrderQuote{
+		BranchID:   newOrder.BranchID,
+		ZuckOnsite: newOrder.ZuckOnsite,
+		PriceLines: prepared.priceLines,
+		Discount:   prepared.discount,
+		TotalPrice: prepared.totalPrice,
+	}
+
+	return &quote, nil
 }
 
-func (u *orderUsecase) GetAll() ([]interface{}, error) {
-	var (
-		headers     *[]model.OrderHeader
-		details     *[]model.OrderDetail
-		users       []model.Users
-		fullOrder   []interface{}
-		headersErr  error
-		detailsErr  error
-		wg          sync.WaitGroup
-		headersChan = make(chan *[]model.OrderHeader, 1)
-		detailsChan = make(chan *[]model.OrderDetail, 1)
-		usersChan   = make(chan []model.Users, 1)
-		errorChan   = make(chan error, 1)
-	)
+func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrder, error) {
+	prepared, err := u.prepareOrder(newOrder)
+	if err != nil {
+		return nil, err
+	}
 
-	// Fetch headers concurrently
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if headers, headersErr = u.orderHeaderRepo.GetAll(); headersErr != nil {
-			errorChan <- headersErr
-		} else {
-			headersChan <- headers
+	// payment, header and details are committed together or not at all
+	var header *model.OrderHeader
+	var details *[]model.OrderDetail
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		// onsite machine is checked under its row lock so two customers
+		// scanning the same machine can't both start it
+		if newOrder.ZuckOnsite {
+			machineSerial := *newOrder.OrderDetails[0].MachineSerial
+			if _, err := u.machineRepo.WithTx(tx).LockMachine(machineSerial); err != nil {
+				return err
+			}
+
+			isAvailable, err := u.machineRepo.WithTx(tx).MachineWangMaiWa(machineSerial)
+			if err != nil {
+				return errors.New("ERR: something wrong while check available")
+			}
+
+			if !isAvailable {
+				return &model.MachineBusyError{MachineSerial: machineSerial}
+			}
 		}
-	}()
 
-	// Fetch details concurrently
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if details, detailsErr = u.orderDetailRepo.GetAll(); detailsErr != nil {
-			errorChan <- detailsErr
-		} else {
-			detailsChan <- details
+		// caps are checked again under the code's lock, the quick check in
+		// prepareOrder may have raced with another order using the same code
+		if prepared.promoCode != nil {
+			promoCodeRepo := u.promoCodeRepo.WithTx(tx)
+			promo, err := promoCodeRepo.LockPromoCode(prepared.promoCode.PromoCodeID)
+			if err != nil {
+				return err
+			}
+
+			usage, err := promoCodeRepo.CountRedemptions(promo.PromoCodeID, newOrder.UserID)
+			if err != nil {
+				return err
+			}
+
+			if _, err := promoDiscount(promo, newOrder.BranchID, prepared.subtotal, time.Now().UTC(), usage); err != nil {
+				return err
+			}
+
+			redemption := model.PromoRedemption{
+				RedemptionID:   uuid.New().String(),
+				PromoCodeID:    promo.PromoCodeID,
+				OrderHeaderID:  prepared.header.OrderHeaderID,
+				UserID:         newOrder.UserID,
+				BranchID:       newOrder.BranchID,
+				DiscountAmount: prepared.discount,
+				CreatedAt:      time.Now().UTC(),
+			}
+
+			if err := promoCodeRepo.CreateRedemption(&redemption); err != nil {
+				return err
+			}
 		}
-	}()
 
-	// Wait for header and detail fetching to complete
-	wg.Wait()
-	close(headersChan)
-	close(detailsChan)
-	close(errorChan)
+		if prepared.redeemPoints > 0 {
+			if err := redeemPoints(u.loyaltyRepo.WithTx(tx), newOrder.UserID, prepared.header.OrderHeaderID, prepared.redeemPoints); err != nil {
+				return err
+			}
+		}
 
-	// Check for errors in fetching
-	if headersErr != nil || detailsErr != nil {
-		return nil, <-errorChan
-	}
+		paymentResponse, err := u.paymentUsecase.WithTx(tx).CreateOrderPayment(prepared.totalPrice, newOrder.BranchID, newOrder.ZuckOnsite)
+		if err != nil {
+			return errors.New("ERR: cannont create payment")
+		}
+		prepared.header.PaymentID = paymentResponse.PaymentID
 
-	headers = <-headersChan
-	details = <-detailsChan
+		// settled in the same transaction, not enough balance means no order at all
+		if newOrder.PayWithWallet {
+			if _, err := u.paymentUsecase.WithTx(tx).PayWithWallet(paymentResponse.PaymentID, newOrder.UserID); err != nil {
+				return err
+			}
+		}
 
-	if len(*headers) == 0 {
-		return []interface{}{}, nil
-	}
+		header, err = u.orderHeaderRepo.WithTx(tx).CreateOrderHeader(&prepared.header)
+		if err != nil {
+			return err
+		}
 
-	// Fetch user data concurrently for all headers
-	wg.Add(len(*headers))
-	go func() {
-		for _, header := range *headers {
-			go func(h model.OrderHeader) {
-				defer wg.Done()
-				user, err := u.userRepo.FindUserByUserID(h.UserID)
-				if err != nil {
-					errorChan <- errors.New("ERR: error occurred when trying to query user data")
-				} else {
-					users = append(users, *user)
-				}
-			}(header)
+		details, err = u.orderDetailRepo.WithTx(tx).CreateOrderDetails(&prepared.details)
+		if err != nil {
+			return err
 		}
-	}()
-	wg.Wait()
-	close(usersChan)
 
-	if len(errorChan) > 0 {
-		return nil, <-errorChan
-	}
+		events := []model.OrderEvent{}
+		for _, d := range *details {
+			event := newOrderEvent(header.OrderHeaderID, &d.OrderBasketID, model.OrderCreated, newOrder.UserID)
+			event.ToStatus = &d.OrderStatus
+			event.MachineSerial = d.MachineSerial
+			events = append(events, event)
+		}
+
+		if err := u.orderEventRepo.WithTx(tx).CreateEvents(&events); err != nil {
+			return err
+		}
+
+		jobRepo := u.jobRepo.WithTx(tx)
+		expireJob := model.NewJob(model.ExpirePaymentJob, paymentResponse.PaymentID, paymentResponse.DueDate)
+		if err := jobRepo.Enqueue(&expireJob); err != nil {
+			return err
+		}
 
-	// Combine full orders
-	for i, h := range *headers {
-		var thisDetail []model.OrderDetail
 		for _, d := range *details {
-			if d.OrderHeaderID == h.OrderHeaderID {
-				thisDetail = append(thisDetail, d)
+			if err := scheduleBasketCompletion(jobRepo, d); err != nil {
+				return err
 			}
 		}
-		fullOrder = append(fullOrder, combineFullOrder(&h, &thisDetail, &users[i], true))
+
+		return u.priceLineRepo.WithTx(tx).CreatePriceLines(&prepared.priceLines)
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return fullOrder, nil
-}
+	if newOrder.PayWithWallet {
+		u.paymentUsecase.AfterPaid(header.PaymentID)
 
-func (u *orderUsecase) GetByHeaderID(orderHeaderID string, isAdminView bool, option string) (interface{}, error) {
-	headers, err := u.orderHeaderRepo.GetByID(orderHeaderID, isAdminView)
+		details, err = u.orderDetailRepo.GetByHeaderID(header.OrderHeaderID, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := u.userRepo.FindUserByUserID(newOrder.UserID)
 	if err != nil {
 		return nil, err
 	}
+	res := combineFullOrder(header, details, user, false)
+	res.PriceLines = prepared.priceLines
 
-	if headers.OrderHeaderID == "" {
-		return nil, gorm.ErrRecordNotFound
+	return res, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
 	}
+	return unique
+}
 
-	if option == "header" {
-		return headers, err
+// assembleFullOrders joins baskets and users onto their headers by id,
+// orders keep the order of headers and baskets keep the order they came in
+func assembleFullOrders(headers []model.OrderHeader, details []model.OrderDetail, users []model.Users, isAdminView bool) []model.FullOrder {
+	detailMap := make(map[string][]model.OrderDetail)
+	for _, d := range details {
+		detailMap[d.OrderHeaderID] = append(detailMap[d.OrderHeaderID], d)
 	}
 
-	detail, err := u.orderDetailRepo.GetByHeaderID(orderHeaderID, isAdminView)
+	userMap := make(map[string]*model.Users)
+	for i := range users {
+		userMap[users[i].UserID] = &users[i]
+	}
 
-	if err != nil {
-		return nil, err
+	fullOrders := []model.FullOrder{}
+	for i := range headers {
+		thisDetail := detailMap[headers[i].OrderHeaderID]
+		fullOrders = append(fullOrders, *combineFullOrder(&headers[i], &thisDetail, userMap[headers[i].UserID], isAdminView))
 	}
 
-	if option == "detail" {
-		return detail, err
+	return fullOrders
+}
+
+// encodeOrderCursor and decodeOrderCursor keep the cursor opaque to clients
+func encodeOrderCursor(cursor model.OrderCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeOrderCursor(encoded string) (*model.OrderCursor, error) {
+	if encoded == "" {
+		return nil, nil
 	}
-	user, err := u.userRepo.FindUserByUserID(headers.UserID)
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("ERR 400: invalid cursor")
 	}
-	fullOrder := combineFullOrder(headers, detail, user, isAdminView)
 
-	return fullOrder, err
+	cursor := new(model.OrderCursor)
+	if err := json.Unmarshal(raw, cursor); err != nil || cursor.OrderHeaderID == "" {
+		return nil, errors.New("ERR 400: invalid cursor")
+	}
+
+	return cursor, nil
 }
 
-func (u *orderUsecase) GetByBranchID(branchID string, managerUserID string, status string) ([]interface{}, error) {
-	manager, err := u.userRepo.FindUserByUserID(managerUserID)
-	if err != nil {
-		return []interface{}{}, err
+// listOrders loads one page of full orders, filtering, sorting and paging
+// are done by the database and the baskets of the page come in one query
+func (u *orderUsecase) listOrders(filter model.OrderListFilter) (*model.OrderPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = model.DefaultOrderPageLimit
 	}
-	if manager.Role != "SuperAdmin" && manager.UserID != managerUserID {
-		return []interface{}{}, errors.New("ERR: forbidden manager try to access unautherized branch")
+	if filter.Limit > model.MaxOrderPageLimit {
+		filter.Limit = model.MaxOrderPageLimit
 	}
 
-	headers, err := u.orderHeaderRepo.GetByBranchID(branchID, status)
+	cursor, err := decodeOrderCursor(filter.Cursor)
 	if err != nil {
-		return []interface{}{}, err
+		return nil, err
 	}
 
-	detail, err := u.orderDetailRepo.GetAll()
+	headers, err := u.orderHeaderRepo.GetPage(filter, cursor)
 	if err != nil {
-		return []interface{}{}, err
+		return nil, err
 	}
 
-	if len(*headers) == 0 {
-		return []interface{}{}, nil
+	page := model.OrderPage{
+		Data:  []model.FullOrder{},
+		Limit: filter.Limit,
 	}
 
-	users, err := u.userRepo.GetUserByBranchID(branchID)
-	userMap := make(map[string]*model.Users)
-	for _, user := range users {
-		if userMap[user.UserID] == nil {
-			userMap[user.UserID] = &user
-		}
+	if len(*headers) > filter.Limit {
+		page.HasMore = true
+		*headers = (*headers)[:filter.Limit]
+	}
+
+	if len(*headers) == 0 {
+		return &page, nil
 	}
 
-	var fullOrder []interface{}
+	headerIDs := []string{}
+	userIDs := []string{}
 	for _, h := range *headers {
-		var thisDetail []model.OrderDetail
-		for _, d := range *detail {
-			if d.OrderHeaderID == h.OrderHeaderID {
-				thisDetail = append(thisDetail, d)
-			}
+		headerIDs = append(headerIDs, h.OrderHeaderID)
+		userIDs = append(userIDs, h.UserID)
+	}
+
+	// details and users don't depend on each other, each goroutine
+	// only writes its own result and both are read after Wait
+	var (
+		details    *[]model.OrderDetail
+		users      []model.Users
+		detailsErr error
+		usersErr   error
+		wg         sync.WaitGroup
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		details, detailsErr = u.orderDetailRepo.GetByHeaderIDs(headerIDs)
+	}()
+	go func() {
+		defer wg.Done()
+		users, usersErr = u.userRepo.FindUsersByUserIDs(uniqueStrings(userIDs))
+	}()
+	wg.Wait()
+
+	if detailsErr != nil {
+		return nil, detailsErr
+	}
+	if usersErr != nil {
+		return nil, errors.New("ERR: error occurred when trying to query user data")
+	}
+
+	page.Data = assembleFullOrders(*headers, *details, users, true)
+
+	if page.HasMore {
+		last := (*headers)[len(*headers)-1]
+		next := model.OrderCursor{SortValue: last.CreatedAt, OrderHeaderID: last.OrderHeaderID}
+		if filter.SortBy == model.SortByUpdatedAt {
+			next.SortValue = last.UpdatedAt
 		}
-		fullOrder = append(fullOrder, combineFullOrder(&h, &thisDetail, userMap[h.UserID], true))
+		page.NextCursor = encodeOrderCursor(next)
 	}
 
-	return fullOrder, err
+	return &page, nil
 }
 
-func (u *orderUsecase) GetByUserID(userID string, status string) ([]interface{}, error) {
-	headers, err := u.orderHeaderRepo.GetByUserID(userID)
+func (u *orderUsecase) GetAll(filter model.OrderListFilter) (*model.OrderPage, error) {
+	return u.listOrders(filter)
+}
 
+func (u *orderUsecase) GetByHeaderID(orderHeaderID string, isAdminView bool, option string) (interface{}, error) {
+	headers, err := u.orderHeaderRepo.GetByID(orderHeaderID, isAdminView)
 	if err != nil {
-		return []interface{}{}, err
+		return nil, err
 	}
 
-	if len(*headers) == 0 {
-		return []interface{}{}, nil
+	if headers.OrderHeaderID == "" {
+		return nil, gorm.ErrRecordNotFound
 	}
 
-	detail, err := u.orderDetailRepo.GetByUserID(userID)
-	if err != nil {
-		return []interface{}{}, err
+	if option == "header" {
+		return headers, err
 	}
 
-	user, err := u.userRepo.FindUserByUserID(userID)
+	detail, err := u.orderDetailRepo.GetByHeaderID(orderHeaderID, isAdminView)
+
 	if err != nil {
-		return nil, errors.New("ERR: error occured when trying to query user data")
+		return nil, err
 	}
 
-	var fullOrder []interface{}
-	for _, h := range *headers {
-		var thisDetail []model.OrderDetail
-
-		// for some
-		isProcessing := false
-		isAnyCompleted := false
-
-		// for all
-		isExpired := true
-		isAllCompleted := true
-		isWaiting := true
+	if option == "detail" {
+		return detail, err
+	}
+	user, err := u.userRepo.FindUserByUserID(headers.UserID)
+	if err != nil {
+		return nil, err
+	}
+	fullOrder := combineFullOrder(headers, detail, user, isAdminView)
 
-		for _, d := range *detail {
-			if d.OrderHeaderID == h.OrderHeaderID {
-				if d.OrderStatus == model.Processing {
-					isProcessing = true
-				}
-				if d.OrderStatus != model.OrderExpired {
-					isExpired = false
-				}
-				if d.OrderStatus == model.Completed {
-					isAnyCompleted = true
-				} else {
-					isAllCompleted = false
-				}
-				if d.OrderStatus != model.Waiting {
-					isWaiting = false
-				}
+	priceLines, err := u.priceLineRepo.GetByHeaderID(orderHeaderID)
+	if err != nil {
+		return nil, err
+	}
+	fullOrder.PriceLines = *priceLines
 
-				thisDetail = append(thisDetail, d)
-			}
-		}
+	refunds, err := u.refundRepo.FindByPaymentID(headers.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	fullOrder.Refunds = *refunds
 
-		if status == string(model.Processing) && !isProcessing && !isAnyCompleted {
-			continue
-		} else if status == string(model.Waiting) && !isWaiting {
-			continue
-		} else if status == string(model.OrderExpired) && !isExpired {
-			continue
-		} else if status == string(model.Completed) && !isAllCompleted {
-			continue
-		}
+	return fullOrder, err
+}
 
-		fullOrder = append(fullOrder, combineFullOrder(&h, &thisDetail, user, true))
+// GetByBranchID pages through the orders of the branch, for its staff only
+func (u *orderUsecase) GetByBranchID(branchID string, userID string, userRole string, filter model.OrderListFilter) (*model.OrderPage, error) {
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, branchID, userID, userRole); err != nil {
+		return nil, err
 	}
 
-	if len(fullOrder) == 0 {
-		return []interface{}{}, nil
-	}
+	filter.BranchID = branchID
+	return u.listOrders(filter)
+}
 
-	return fullOrder, err
+func (u *orderUsecase) GetByUserID(userID string, filter model.OrderListFilter) (*model.OrderPage, error) {
+	filter.UserID = userID
+	return u.listOrders(filter)
 }
 
 func (u *orderUsecase) UpdateStatus(order model.UpdateOrder) (interface{}, error) {
@@ -576,15 +956,46 @@ func (u *orderUsecase) UpdateStatus(order model.UpdateOrder) (interface{}, error
 		return nil, errors.New("ERR 400: payment expired")
 	}
 
+	// -------- check status transition against the rest of the order
+	orderDetails, err := u.orderDetailRepo.GetByHeaderID(checkDetail.OrderHeaderID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckOrderTransition(checkDetail, order.OrderStatus, *orderDetails); err != nil {
+		return nil, err
+	}
+
 	// -------- check machine available
-	if updatedOrder.MachineSerial != nil {
+	// staff can override the machine picked by auto assignment,
+	// keeping the machine already reserved for this basket is always fine
+	isSameMachine := updatedOrder.MachineSerial != nil &&
+		checkDetail.MachineSerial != nil &&
+		*updatedOrder.MachineSerial == *checkDetail.MachineSerial
+
+	if updatedOrder.MachineSerial != nil && !isSameMachine {
+		machine, err := u.machineRepo.GetByMachineSerial(*order.MachineSerial)
+		if err != nil {
+			return nil, err
+		}
+
+		header, err := u.orderHeaderRepo.GetByID(checkDetail.OrderHeaderID, true)
+		if err != nil {
+			return nil, err
+		}
+
+		machineType, ok := serviceMachineMapper(checkDetail.ServiceType)
+		if !ok || machine.MachineType != machineType || machine.BranchID != header.BranchID {
+			return nil, errors.New("ERR 400: machine does not fit this basket")
+		}
+
 		isAvailable, err := u.machineRepo.MachineWangMaiWa(*order.MachineSerial)
 		if err != nil {
 			return nil, err
 		}
 
 		if !isAvailable {
-			return nil, errors.New("ERR 400: mai wang ja")
+			return nil, &model.MachineBusyError{MachineSerial: *order.MachineSerial}
 		}
 	}
 
@@ -610,8 +1021,64 @@ func (u *orderUsecase) UpdateStatus(order model.UpdateOrder) (interface{}, error
 		}
 	}
 
-	// -------- actually update order
-	orderDetail, err := u.orderDetailRepo.UpdateStatus(updatedOrder)
+	// -------- a basket going into its machine starts now and is done when its
+	// program is, unless staff tells otherwise
+	_, isMachineWork := serviceMachineMapper(checkDetail.ServiceType)
+	if isMachineWork && order.OrderStatus == model.Processing && checkDetail.OrderStatus != model.Processing {
+		startedAt := time.Now().UTC()
+		updatedOrder.StartedAt = &startedAt
+	}
+
+	if isMachineWork && order.OrderStatus == model.Processing &&
+		checkDetail.OrderStatus != model.Processing && updatedOrder.FinishedAt == nil {
+
+		var program *model.WashProgram
+		if checkDetail.ProgramID != nil {
+			program, err = u.washProgramRepo.FindByProgramID(*checkDetail.ProgramID)
+			// a program deleted after the order was placed runs as the default cycle
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				program = nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		finishedAt := cycleEnd(program, *updatedOrder.StartedAt)
+		updatedOrder.FinishedAt = &finishedAt
+	}
+
+	// -------- actually update order, with its history
+	var orderDetail *model.OrderDetail
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		var err error
+		orderDetail, err = u.orderDetailRepo.WithTx(tx).UpdateStatus(updatedOrder)
+		if err != nil {
+			return err
+		}
+
+		events := []model.OrderEvent{}
+
+		if order.OrderStatus != checkDetail.OrderStatus {
+			event := newOrderEvent(checkDetail.OrderHeaderID, &checkDetail.OrderBasketID, model.OrderStatusChanged, order.UpdatedBy)
+			event.FromStatus = &checkDetail.OrderStatus
+			event.ToStatus = &order.OrderStatus
+			event.MachineSerial = orderDetail.MachineSerial
+			events = append(events, event)
+		}
+
+		if updatedOrder.MachineSerial != nil && !isSameMachine {
+			event := newOrderEvent(checkDetail.OrderHeaderID, &checkDetail.OrderBasketID, model.MachineAssigned, order.UpdatedBy)
+			event.MachineSerial = updatedOrder.MachineSerial
+			events = append(events, event)
+		}
+
+		if err := scheduleBasketCompletion(u.jobRepo.WithTx(tx), *orderDetail); err != nil {
+			return err
+		}
+
+		return u.orderEventRepo.WithTx(tx).CreateEvents(&events)
+	})
 
 	if err != nil {
 		return nil, err
@@ -644,7 +1111,19 @@ func (u *orderUsecase) UpdateReview(review model.OrderReview) (*model.FullOrder,
 		return nil, errors.New("err: forbidden review update")
 	}
 
-	orderHeader, err := u.orderHeaderRepo.UpdateReview(orderModel)
+	var orderHeader *model.OrderHeader
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		var err error
+		orderHeader, err = u.orderHeaderRepo.WithTx(tx).UpdateReview(orderModel)
+		if err != nil {
+			return err
+		}
+
+		events := []model.OrderEvent{newOrderEvent(orderModel.OrderHeaderID, nil, model.OrderReviewed, review.UserID)}
+		return u.orderEventRepo.WithTx(tx).CreateEvents(&events)
+	})
+
 	if err != nil {
 		return nil, err
 	}
@@ -663,13 +1142,24 @@ func (u *orderUsecase) UpdateReview(review model.OrderReview) (*model.FullOrder,
 }
 
 func (u *orderUsecase) SoftDelete(orderHeaderID string, deletedBy string) (*model.FullOrder, error) {
-	orderHeader, err := u.orderHeaderRepo.SoftDelete(orderHeaderID, deletedBy)
+	var orderHeader *model.OrderHeader
+	var orderDetails *[]model.OrderDetail
 
-	if err != nil {
-		return nil, err
-	}
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		var err error
+		orderHeader, err = u.orderHeaderRepo.WithTx(tx).SoftDelete(orderHeaderID, deletedBy)
+		if err != nil {
+			return err
+		}
 
-	orderDetails, err := u.orderDetailRepo.DeleteByHeaderID(orderHeaderID, deletedBy)
+		orderDetails, err = u.orderDetailRepo.WithTx(tx).DeleteByHeaderID(orderHeaderID, deletedBy)
+		if err != nil {
+			return err
+		}
+
+		events := []model.OrderEvent{newOrderEvent(orderHeaderID, nil, model.OrderDeleted, deletedBy)}
+		return u.orderEventRepo.WithTx(tx).CreateEvents(&events)
+	})
 
 	if err != nil {
 		return nil, err
@@ -683,6 +1173,144 @@ func (u *orderUsecase) SoftDelete(orderHeaderID string, deletedBy string) (*mode
 	return fullOrder, err
 }
 
+// CancelOrder lets the customer cancel their order before any work started.
+// A pending payment is canceled, a paid one gets a refund record,
+// and the branch is notified so staff don't pick the baskets up.
+func (u *orderUsecase) CancelOrder(orderHeaderID string, userID string) (*model.FullOrder, error) {
+	header, err := u.orderHeaderRepo.GetByID(orderHeaderID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.OrderHeaderID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if header.UserID != userID {
+		return nil, errors.New("ERR 403: not your order")
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		// baskets are locked so staff can't start one while we cancel
+		orderDetails, err := u.orderDetailRepo.WithTx(tx).LockByHeaderID(orderHeaderID)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range *orderDetails {
+			if d.OrderStatus != model.Waiting {
+				return errors.New("ERR 400: order can only be canceled while every basket is waiting")
+			}
+		}
+
+		if _, err := u.paymentUsecase.WithTx(tx).CancelPayment(header.PaymentID, orderHeaderID, userID); err != nil {
+			return err
+		}
+
+		if _, err := u.orderDetailRepo.WithTx(tx).CancelByHeaderID(orderHeaderID, userID); err != nil {
+			return err
+		}
+
+		if err := u.loyaltyRepo.WithTx(tx).RestoreRedeemed(orderHeaderID); err != nil {
+			return err
+		}
+
+		canceled := model.Canceled
+		events := []model.OrderEvent{}
+		for _, d := range *orderDetails {
+			event := newOrderEvent(orderHeaderID, &d.OrderBasketID, model.OrderStatusChanged, userID)
+			event.FromStatus = &d.OrderStatus
+			event.ToStatus = &canceled
+			event.MachineSerial = d.MachineSerial
+			events = append(events, event)
+		}
+
+		if err := u.orderEventRepo.WithTx(tx).CreateEvents(&events); err != nil {
+			return err
+		}
+
+		notification := model.Notification{
+			NotificationID: uuid.New().String(),
+			BranchID:       header.BranchID,
+			OrderHeaderID:  &header.OrderHeaderID,
+			Title:          "Order canceled",
+			Message:        fmt.Sprintf("Order %s was canceled by the customer", orderHeaderID),
+			CreatedAt:      time.Now().UTC(),
+		}
+
+		return u.notificationRepo.WithTx(tx).CreateNotification(&notification)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	fullOrder, err := u.GetByHeaderID(orderHeaderID, false, "full")
+	if err != nil {
+		return nil, err
+	}
+
+	return fullOrder.(*model.FullOrder), nil
+}
+
+// GetTimeline returns the history of an order oldest first,
+// customers can only see the timeline of their own order
+func (u *orderUsecase) GetTimeline(orderHeaderID string, userID string, role string) ([]model.OrderTimelineEvent, error) {
+	header, err := u.orderHeaderRepo.GetByID(orderHeaderID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.OrderHeaderID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if role == string(model.Client) && header.UserID != userID {
+		return nil, errors.New("ERR 403: not your order")
+	}
+
+	events, err := u.orderEventRepo.GetByHeaderID(orderHeaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	actorIDs := []string{}
+	for _, e := range *events {
+		if e.ActorID != model.SystemActor {
+			actorIDs = append(actorIDs, e.ActorID)
+		}
+	}
+
+	// actor could be a deleted user, keep the id and leave the detail empty
+	users, err := u.userRepo.FindUsersByUserIDs(uniqueStrings(actorIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	actors := make(map[string]*model.UserDetailDTO)
+	for i := range users {
+		actors[users[i].UserID] = toUserDetailDTO(&users[i])
+	}
+
+	timeline := []model.OrderTimelineEvent{}
+
+	for _, e := range *events {
+		timeline = append(timeline, model.OrderTimelineEvent{
+			EventID:       e.EventID,
+			OrderBasketID: e.OrderBasketID,
+			EventType:     e.EventType,
+			FromStatus:    e.FromStatus,
+			ToStatus:      e.ToStatus,
+			MachineSerial: e.MachineSerial,
+			ActorID:       e.ActorID,
+			Actor:         actors[e.ActorID],
+			CreatedAt:     e.CreatedAt,
+		})
+	}
+
+	return timeline, nil
+}
+
 //HUm
 // func (u *orderUsecase) CreateNewOrder(newOrder *model.NewOrder) (*model.FullOrder, error) {
 
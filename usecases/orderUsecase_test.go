@@ -0,0 +1,426 @@
+package usecases
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	repo "zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+// fakes only implement what the order listing calls,
+// anything else panics through the nil embedded interface
+
+type fakeOrderHeaderRepo struct {
+	repo.OrderHeaderRepository
+	headers []model.OrderHeader // sorted by created_at, order_header_id
+}
+
+func (f *fakeOrderHeaderRepo) GetPage(filter model.OrderListFilter, cursor *model.OrderCursor) (*[]model.OrderHeader, error) {
+	page := []model.OrderHeader{}
+	for _, h := range f.headers {
+		if cursor != nil && (h.CreatedAt.Before(cursor.SortValue) ||
+			(h.CreatedAt.Equal(cursor.SortValue) && h.OrderHeaderID <= cursor.OrderHeaderID)) {
+			continue
+		}
+		page = append(page, h)
+		if len(page) == filter.Limit+1 {
+			break
+		}
+	}
+	return &page, nil
+}
+
+type fakeOrderDetailRepo struct {
+	repo.OrderDetailRepository
+	details []model.OrderDetail
+}
+
+func (f *fakeOrderDetailRepo) GetByHeaderIDs(orderHeaderIDs []string) (*[]model.OrderDetail, error) {
+	wanted := make(map[string]bool)
+	for _, id := range orderHeaderIDs {
+		wanted[id] = true
+	}
+
+	details := []model.OrderDetail{}
+	for _, d := range f.details {
+		if wanted[d.OrderHeaderID] {
+			details = append(details, d)
+		}
+	}
+	return &details, nil
+}
+
+type fakeUserRepo struct {
+	repo.UserRepository
+	users []model.Users
+	calls atomic.Int32
+}
+
+func (f *fakeUserRepo) FindUsersByUserIDs(userIDs []string) ([]model.Users, error) {
+	f.calls.Add(1)
+
+	wanted := make(map[string]bool)
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+
+	users := []model.Users{}
+	// reversed on purpose, the join must not depend on the order users come back in
+	for i := len(f.users) - 1; i >= 0; i-- {
+		if wanted[f.users[i].UserID] {
+			users = append(users, f.users[i])
+		}
+	}
+	return users, nil
+}
+
+// newListingUsecase builds orders with 3 customers taking turns and 2 baskets each
+func newListingUsecase(orderCount int) (*orderUsecase, *fakeUserRepo) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	headers := []model.OrderHeader{}
+	details := []model.OrderDetail{}
+	for i := 0; i < orderCount; i++ {
+		headerID := fmt.Sprintf("order-%03d", i)
+		headers = append(headers, model.OrderHeader{
+			OrderHeaderID: headerID,
+			UserID:        fmt.Sprintf("user-%d", i%3),
+			CreatedAt:     start.Add(time.Duration(i/2) * time.Minute), // two orders share each timestamp
+		})
+		details = append(details,
+			model.OrderDetail{OrderBasketID: headerID + "-washing", OrderHeaderID: headerID, ServiceType: model.Washing, OrderStatus: model.Waiting},
+			model.OrderDetail{OrderBasketID: headerID + "-drying", OrderHeaderID: headerID, ServiceType: model.Drying, OrderStatus: model.Waiting},
+		)
+	}
+
+	users := &fakeUserRepo{users: []model.Users{{UserID: "user-0"}, {UserID: "user-1"}, {UserID: "user-2"}}}
+
+	return &orderUsecase{
+		orderHeaderRepo: &fakeOrderHeaderRepo{headers: headers},
+		orderDetailRepo: &fakeOrderDetailRepo{details: details},
+		userRepo:        users,
+	}, users
+}
+
+func checkFullOrder(t *testing.T, order model.FullOrder) {
+	t.Helper()
+
+	if order.UserDetail.UserID != order.UserID {
+		t.Errorf("Order %s belongs to %s, but got user detail of '%s'", order.OrderHeaderID, order.UserID, order.UserDetail.UserID)
+	}
+
+	if len(order.OrderDetails) != 2 {
+		t.Fatalf("Order %s, expected 2 baskets, but got %d", order.OrderHeaderID, len(order.OrderDetails))
+	}
+
+	expectedBaskets := []string{order.OrderHeaderID + "-washing", order.OrderHeaderID + "-drying"}
+	for i, d := range order.OrderDetails {
+		if d.OrderBasketID != expectedBaskets[i] {
+			t.Errorf("Order %s basket %d, expected %s, but got %s", order.OrderHeaderID, i, expectedBaskets[i], d.OrderBasketID)
+		}
+	}
+}
+
+func TestAssembleFullOrders(t *testing.T) {
+	headers := []model.OrderHeader{
+		{OrderHeaderID: "b", UserID: "user-2"},
+		{OrderHeaderID: "a", UserID: "user-1"},
+		{OrderHeaderID: "c", UserID: "deleted-user"},
+	}
+	details := []model.OrderDetail{
+		{OrderBasketID: "a-1", OrderHeaderID: "a"},
+		{OrderBasketID: "b-1", OrderHeaderID: "b"},
+		{OrderBasketID: "a-2", OrderHeaderID: "a"},
+	}
+	users := []model.Users{{UserID: "user-1"}, {UserID: "user-2"}}
+
+	tests := []struct {
+		orderHeaderID string
+		userID        string
+		baskets       []string
+	}{
+		{"b", "user-2", []string{"b-1"}},
+		{"a", "user-1", []string{"a-1", "a-2"}},
+		{"c", "", []string{}}, // user is gone, order is still listed
+	}
+
+	result := assembleFullOrders(headers, details, users, true)
+
+	if len(result) != len(tests) {
+		t.Fatalf("Expected %d orders, but got %d", len(tests), len(result))
+	}
+
+	for i, test := range tests {
+		order := result[i]
+		if order.OrderHeaderID != test.orderHeaderID {
+			t.Errorf("Position %d, expected order %s, but got %s", i, test.orderHeaderID, order.OrderHeaderID)
+		}
+		if order.UserDetail.UserID != test.userID {
+			t.Errorf("Order %s, expected user '%s', but got '%s'", test.orderHeaderID, test.userID, order.UserDetail.UserID)
+		}
+		if len(order.OrderDetails) != len(test.baskets) {
+			t.Errorf("Order %s, expected %d baskets, but got %d", test.orderHeaderID, len(test.baskets), len(order.OrderDetails))
+			continue
+		}
+		for j, basketID := range test.baskets {
+			if order.OrderDetails[j].OrderBasketID != basketID {
+				t.Errorf("Order %s basket %d, expected %s, but got %s", test.orderHeaderID, j, basketID, order.OrderDetails[j].OrderBasketID)
+			}
+		}
+	}
+}
+
+func TestListOrdersPaging(t *testing.T) {
+	u, users := newListingUsecase(25)
+
+	seen := make(map[string]bool)
+	filter := model.OrderListFilter{Limit: 10}
+	pages := 0
+
+	for {
+		page, err := u.GetAll(filter)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		pages += 1
+
+		for _, order := range page.Data {
+			if seen[order.OrderHeaderID] {
+				t.Errorf("Order %s is on more than one page", order.OrderHeaderID)
+			}
+			seen[order.OrderHeaderID] = true
+			checkFullOrder(t, order)
+		}
+
+		if !page.HasMore {
+			if page.NextCursor != "" {
+				t.Errorf("Last page should not have a next cursor, but got %s", page.NextCursor)
+			}
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	if len(seen) != 25 || pages != 3 {
+		t.Errorf("Expected 25 orders on 3 pages, but got %d orders on %d pages", len(seen), pages)
+	}
+
+	if calls := users.calls.Load(); calls != int32(pages) {
+		t.Errorf("Expected one user lookup per page, but got %d lookups for %d pages", calls, pages)
+	}
+}
+
+func TestGetByBranchIDAccess(t *testing.T) {
+	u, _ := newListingUsecase(3)
+	u.branchRepo = &fakeBranchRepo{branch: model.Branch{BranchID: "branch-1", OwnerUserID: "owner"}}
+	u.contractRepo = &fakeContractRepo{contracts: []model.EmployeeContract{
+		{UserID: "employee-1", BranchID: "branch-1"},
+		{UserID: "employee-2", BranchID: "branch-2"},
+	}}
+
+	tests := []struct {
+		name     string
+		userID   string
+		userRole model.Roles
+		expected bool
+	}{
+		{"super admin", "admin", model.SuperAdmin, true},
+		{"owner of the branch", "owner", model.BranchManager, true},
+		{"employee of the branch", "employee-1", model.Employee, true},
+		{"manager of another branch", "other-manager", model.BranchManager, false},
+		{"employee of another branch", "employee-2", model.Employee, false},
+	}
+
+	for _, test := range tests {
+		page, err := u.GetByBranchID("branch-1", test.userID, string(test.userRole), model.OrderListFilter{Limit: 10})
+		if (err == nil) != test.expected {
+			t.Errorf("%s: expected allowed %v, but got %v", test.name, test.expected, err)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), "403") {
+			t.Errorf("%s: expected forbidden, but got %v", test.name, err)
+		}
+		if err == nil && len(page.Data) != 3 {
+			t.Errorf("%s: expected 3 orders, but got %d", test.name, len(page.Data))
+		}
+	}
+}
+
+// TestListOrdersConcurrent is meant for go test -race,
+// many listings at once must not share state and must attach the right users
+func TestListOrdersConcurrent(t *testing.T) {
+	u, _ := newListingUsecase(60)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			page, err := u.GetAll(model.OrderListFilter{Limit: 50})
+			if err != nil {
+				t.Errorf("Unexpected error %v", err)
+				return
+			}
+
+			if len(page.Data) != 50 || !page.HasMore {
+				t.Errorf("Expected a full page of 50 with more to come, but got %d, has more %v", len(page.Data), page.HasMore)
+			}
+
+			for _, order := range page.Data {
+				checkFullOrder(t, order)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecodeOrderCursor(t *testing.T) {
+	cursor := model.OrderCursor{SortValue: time.Date(2024, 1, 1, 0, 0, 0, 123456000, time.UTC), OrderHeaderID: "order-001"}
+
+	tests := []struct {
+		input     string
+		expectErr bool
+	}{
+		{encodeOrderCursor(cursor), false},
+		{"", false},           // first page
+		{"not base64!", true}, // garbage
+		{"e30", true},         // valid base64 of {} without an order id
+	}
+
+	for _, test := range tests {
+		result, err := decodeOrderCursor(test.input)
+		if (err != nil) != test.expectErr {
+			t.Errorf("For input '%s', expected error %v, but got %v", test.input, test.expectErr, err)
+		}
+		if test.input == encodeOrderCursor(cursor) && (result == nil || !result.SortValue.Equal(cursor.SortValue) || result.OrderHeaderID != cursor.OrderHeaderID) {
+			t.Errorf("Cursor did not round trip, expected %v, but got %v", cursor, result)
+		}
+	}
+}
+
+type fakeWashProgramRepo struct {
+	model.WashProgramRepository
+	programs map[string]model.WashProgram
+}
+
+func (f *fakeWashProgramRepo) FindByProgramID(programID string) (*model.WashProgram, error) {
+	program, ok := f.programs[programID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &program, nil
+}
+
+type fakeServicePriceRepo struct {
+	model.ServicePriceRepository
+	price float64
+}
+
+func (f *fakeServicePriceRepo) FindEffectivePrice(branchID string, serviceType model.ServiceType, weight int16, at time.Time) (*model.ServicePrice, error) {
+	return &model.ServicePrice{PriceID: string(serviceType), ServiceType: serviceType, Weight: weight, Price: f.price}, nil
+}
+
+func TestCycleEnd(t *testing.T) {
+	start := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		program  *model.WashProgram
+		expected time.Time
+	}{
+		{nil, start.Add(25 * time.Minute)},
+		{&model.WashProgram{DurationMinutes: 15}, start.Add(15 * time.Minute)},
+		{&model.WashProgram{DurationMinutes: 70}, start.Add(70 * time.Minute)},
+	}
+
+	for _, test := range tests {
+		result := cycleEnd(test.program, start)
+		if !result.Equal(test.expected) {
+			t.Errorf("For input '%v', expected %v, but got %v", test.program, test.expected, result)
+		}
+	}
+}
+
+func TestFindProgram(t *testing.T) {
+	u := &orderUsecase{washProgramRepo: &fakeWashProgramRepo{programs: map[string]model.WashProgram{
+		"quick": {ProgramID: "quick", MachineType: model.Washer, DurationMinutes: 15},
+		"low":   {ProgramID: "low", MachineType: model.Dryer, DurationMinutes: 50},
+	}}}
+	quick, low, unknown := "quick", "low", "unknown"
+
+	tests := []struct {
+		name        string
+		programID   *string
+		serviceType model.ServiceType
+		expected    string
+		expectErr   string
+	}{
+		{"no program runs the default cycle", nil, model.Washing, "", ""},
+		{"washer program for washing", &quick, model.Washing, "quick", ""},
+		{"dryer program for drying", &low, model.Drying, "low", ""},
+		{"dryer program for washing", &low, model.Washing, "", "400"},
+		{"program for delivery", &quick, model.Delivery, "", "400"},
+		{"deleted program", &unknown, model.Washing, "", "400"},
+	}
+
+	for _, test := range tests {
+		program, err := u.findProgram(test.programID, test.serviceType)
+		if test.expectErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil || (program == nil) != (test.expected == "") || (program != nil && program.ProgramID != test.expected) {
+			t.Errorf("%s: expected %s, but got %v %v", test.name, test.expected, program, err)
+		}
+	}
+}
+
+func TestPriceOrderDetailsWithPrograms(t *testing.T) {
+	u := &orderUsecase{servicePriceRepo: &fakeServicePriceRepo{price: 40}}
+	heavy, quick := "heavy", "quick"
+	programs := map[string]*model.WashProgram{
+		heavy: {ProgramID: heavy, Name: "Heavy", PriceModifier: 1.25},
+		quick: {ProgramID: quick, Name: "Quick", PriceModifier: 0.9},
+	}
+	details := []model.OrderDetail{
+		{ServiceType: model.Washing, Weight: 14},
+		{ServiceType: model.Washing, Weight: 14, ProgramID: &heavy},
+		{ServiceType: model.Washing, Weight: 14, ProgramID: &heavy},
+		{ServiceType: model.Washing, Weight: 14, ProgramID: &quick},
+	}
+
+	lines, total, err := u.priceOrderDetails("branch-1", "order-1", details, programs)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	expected := []struct {
+		programID *string
+		quantity  int
+		unitPrice float64
+	}{
+		{nil, 1, 40},
+		{&heavy, 2, 50},
+		{&quick, 1, 36},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d price lines, but got %v", len(expected), lines)
+	}
+	for i, line := range lines {
+		if !sameProgram(line.ProgramID, expected[i].programID) || line.Quantity != expected[i].quantity || line.UnitPrice != expected[i].unitPrice {
+			t.Errorf("line %d: expected %+v, but got %+v", i+1, expected[i], line)
+		}
+	}
+	if total != 176 {
+		t.Errorf("expected total 176, but got %v", total)
+	}
+}
@@ -0,0 +1,135 @@
+package usecases
+
+import (
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type paymentPolicyUsecase struct {
+	policyRepo      model.PaymentPolicyRepository
+	paymentRepo     model.PaymentRepository
+	orderHeaderRepo repository.OrderHeaderRepository
+	branchRepo      repository.BranchReopository
+	contractRepo    repository.EmployeeContractRepository
+	unitOfWork      repository.UnitOfWork
+}
+
+func CreateNewPaymentPolicyUsecase(policyRepo model.PaymentPolicyRepository, paymentRepo model.PaymentRepository, orderHeaderRepo repository.OrderHeaderRepository, branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository, unitOfWork repository.UnitOfWork) model.PaymentPolicyUsecase {
+	return &paymentPolicyUsecase{
+		policyRepo:      policyRepo,
+		paymentRepo:     paymentRepo,
+		orderHeaderRepo: orderHeaderRepo,
+		branchRepo:      branchRepo,
+		contractRepo:    contractRepo,
+		unitOfWork:      unitOfWork,
+	}
+}
+
+// extendedDueDate is the due date of payment after one more extension by policy,
+// a payment already past its due date gets the whole extension from now
+func extendedDueDate(payment *model.Payments, policy model.PaymentPolicy, now time.Time) (time.Time, error) {
+	if payment.Payment_Status != model.Pending {
+		return time.Time{}, errors.New("ERR 400: cannot extend payment that is " + string(payment.Payment_Status))
+	}
+
+	if payment.ExtensionCount >= policy.MaxExtensions {
+		return time.Time{}, errors.New("ERR 400: payment was already extended the most times allowed")
+	}
+
+	from := payment.DueDate
+	if from.Before(now) {
+		from = now
+	}
+
+	return from.Add(time.Minute * time.Duration(policy.ExtendMinutes)), nil
+}
+
+// SetPolicy creates the policy of a branch and order type or replaces its settings,
+// payments already created keep the due date they have
+func (u *paymentPolicyUsecase) SetPolicy(newPolicy *model.SetPaymentPolicyDTO, userID string, userRole string) (*model.PaymentPolicy, error) {
+	if err := checkDefaultOrBranchOwner(u.branchRepo, newPolicy.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	policy, err := u.policyRepo.FindPolicy(newPolicy.BranchID, newPolicy.ZuckOnsite)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = &model.PaymentPolicy{
+			PolicyID:   uuid.New().String(),
+			BranchID:   newPolicy.BranchID,
+			ZuckOnsite: newPolicy.ZuckOnsite,
+			CreatedAt:  now,
+			CreatedBy:  userID,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	policy.DueMinutes = newPolicy.DueMinutes
+	policy.GraceMinutes = newPolicy.GraceMinutes
+	policy.ExtendMinutes = newPolicy.ExtendMinutes
+	policy.MaxExtensions = newPolicy.MaxExtensions
+	policy.UpdatedAt = now
+	policy.UpdatedBy = userID
+
+	if err := u.policyRepo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return u.policyRepo.FindByPolicyID(policy.PolicyID)
+}
+
+func (u *paymentPolicyUsecase) FindByBranchID(branchID string) (*[]model.PaymentPolicy, error) {
+	return u.policyRepo.FindByBranchID(branchID)
+}
+
+// ExtendPayment gives the customer more time to pay, for staff of the branch
+// the order was placed at. How often and by how much is up to the payment's policy.
+func (u *paymentPolicyUsecase) ExtendPayment(paymentID string, userID string, userRole string) (*model.Payments, error) {
+	order, err := u.orderHeaderRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchStaff(u.branchRepo, u.contractRepo, order.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		paymentRepo := u.paymentRepo.WithTx(tx)
+
+		payment, err := paymentRepo.LockPayment(paymentID)
+		if err != nil {
+			return err
+		}
+
+		policy := model.DefaultPaymentPolicy(order.ZuckOnsite)
+		if payment.PolicyID != nil {
+			found, err := u.policyRepo.FindByPolicyID(*payment.PolicyID)
+			if err != nil {
+				return err
+			}
+			policy = *found
+		}
+
+		dueDate, err := extendedDueDate(payment, policy, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		return paymentRepo.ExtendPayment(paymentID, dueDate)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return u.paymentRepo.FindByPaymentID(paymentID)
+}
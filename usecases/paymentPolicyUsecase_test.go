@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"gorm.io/gorm"
+)
+
+type fakePaymentPolicyRepo struct {
+	model.PaymentPolicyRepository
+	policies []model.PaymentPolicy
+}
+
+func (f *fakePaymentPolicyRepo) FindEffectivePolicy(branchID string, zuckOnsite bool) (*model.PaymentPolicy, error) {
+	var found *model.PaymentPolicy
+	for i, policy := range f.policies {
+		if policy.ZuckOnsite != zuckOnsite {
+			continue
+		}
+		if policy.BranchID != nil && *policy.BranchID == branchID {
+			return &f.policies[i], nil
+		}
+		if policy.BranchID == nil {
+			found = &f.policies[i]
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+func (f *fakePaymentRepo) CreatePayment(newPayment model.Payments) (*model.Payments, error) {
+	f.payments[newPayment.PaymentID] = &newPayment
+	return f.FindByPaymentID(newPayment.PaymentID)
+}
+
+func TestExtendedDueDate(t *testing.T) {
+	now := time.Date(2024, 10, 1, 22, 0, 0, 0, time.UTC)
+	policy := model.PaymentPolicy{ExtendMinutes: 30, MaxExtensions: 2}
+
+	tests := []struct {
+		name      string
+		payment   model.Payments
+		expected  time.Time
+		expectErr bool
+	}{
+		{"extends from the due date", model.Payments{Payment_Status: model.Pending, DueDate: now.Add(5 * time.Minute)}, now.Add(35 * time.Minute), false},
+		{"past due extends from now", model.Payments{Payment_Status: model.Pending, DueDate: now.Add(-5 * time.Minute), ExtensionCount: 1}, now.Add(30 * time.Minute), false},
+		{"no extensions left", model.Payments{Payment_Status: model.Pending, DueDate: now, ExtensionCount: 2}, time.Time{}, true},
+		{"paid", model.Payments{Payment_Status: model.Paid, DueDate: now}, time.Time{}, true},
+		{"expired", model.Payments{Payment_Status: model.Expired, DueDate: now}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		dueDate, err := extendedDueDate(&test.payment, policy, now)
+		if (err != nil) != test.expectErr {
+			t.Errorf("%s: expected error %v, but got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if !dueDate.Equal(test.expected) {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.expected, dueDate)
+		}
+	}
+}
+
+func TestCreateOrderPayment(t *testing.T) {
+	branchID := "branch-1"
+	policyRepo := &fakePaymentPolicyRepo{policies: []model.PaymentPolicy{
+		{PolicyID: "default-online", ZuckOnsite: false, DueMinutes: 60},
+		{PolicyID: "branch-online", BranchID: &branchID, ZuckOnsite: false, DueMinutes: 180},
+	}}
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{}}
+	u := CreateNewPaymentUsecase(paymentRepo, &fakeRefundRepo{}, &fakeWalletRepo{}, policyRepo, nil, &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, &fakeOrderByPaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+	tests := []struct {
+		name       string
+		branchID   string
+		zuckOnsite bool
+		policyID   *string
+		minutes    int
+	}{
+		{"branch policy", branchID, false, &policyRepo.policies[1].PolicyID, 180},
+		{"default policy", "branch-2", false, &policyRepo.policies[0].PolicyID, 60},
+		{"no policy", branchID, true, nil, model.DefaultDueMinutes},
+	}
+
+	for _, test := range tests {
+		before := time.Now().UTC()
+		payment, err := u.CreateOrderPayment(100, test.branchID, test.zuckOnsite)
+		if err != nil {
+			t.Errorf("%s: expected no error, but got %v", test.name, err)
+			continue
+		}
+
+		if (payment.PolicyID == nil) != (test.policyID == nil) || (payment.PolicyID != nil && *payment.PolicyID != *test.policyID) {
+			t.Errorf("%s: expected policy %v, but got %v", test.name, test.policyID, payment.PolicyID)
+		}
+
+		window := payment.DueDate.Sub(before)
+		expected := time.Duration(test.minutes) * time.Minute
+		if window < expected || window > expected+time.Minute {
+			t.Errorf("%s: expected due in %v, but got %v", test.name, expected, window)
+		}
+
+		if payment.Payment_Status != model.Pending {
+			t.Errorf("%s: expected %s, but got %s", test.name, model.Pending, payment.Payment_Status)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+)
+
+type paymentReportUsecase struct {
+	paymentRepo model.PaymentRepository
+	refundRepo  model.RefundRepository
+	branchRepo  repository.BranchReopository
+}
+
+func CreateNewPaymentReportUsecase(paymentRepo model.PaymentRepository, refundRepo model.RefundRepository, branchRepo repository.BranchReopository) model.PaymentReportUsecase {
+	return &paymentReportUsecase{
+		paymentRepo: paymentRepo,
+		refundRepo:  refundRepo,
+		branchRepo:  branchRepo,
+	}
+}
+
+// isUnmatched tells if the money that came in doesn't agree with the payment status
+func isUnmatched(line model.PaymentReportLine) bool {
+	if line.PaymentStatus != model.Paid {
+		return line.ReceivedAmount != nil
+	}
+
+	return line.ReceivedAmount == nil || toSatang(*line.ReceivedAmount) != toSatang(line.Amount)
+}
+
+// summarizePaymentReport fills the totals and lists of report from its payments and refunds,
+// sums are done in satang so they add up to what the lines show
+func summarizePaymentReport(report *model.PaymentReport) {
+	report.ByStatus = []model.PaymentStatusSummary{
+		{PaymentStatus: model.Pending},
+		{PaymentStatus: model.Paid},
+		{PaymentStatus: model.Expired},
+		{PaymentStatus: model.Cancel},
+	}
+	statusSatang := make([]int64, len(report.ByStatus))
+
+	report.ByMethod = []model.PaymentMethodSummary{}
+	methodSatang := []int64{}
+
+	report.Unmatched = []model.PaymentReportLine{}
+	report.Expired = []model.PaymentReportLine{}
+
+	var paidSatang int64 = 0
+	for _, line := range report.Payments {
+		for i := range report.ByStatus {
+			if report.ByStatus[i].PaymentStatus == line.PaymentStatus {
+				report.ByStatus[i].Count++
+				statusSatang[i] += toSatang(line.Amount)
+			}
+		}
+
+		if line.PaymentStatus == model.Paid {
+			paidSatang += toSatang(line.Amount)
+
+			i := 0
+			for i < len(report.ByMethod) && report.ByMethod[i].Method != line.Method {
+				i++
+			}
+			if i == len(report.ByMethod) {
+				report.ByMethod = append(report.ByMethod, model.PaymentMethodSummary{Method: line.Method})
+				methodSatang = append(methodSatang, 0)
+			}
+			report.ByMethod[i].Count++
+			methodSatang[i] += toSatang(line.Amount)
+		}
+
+		if isUnmatched(line) {
+			report.Unmatched = append(report.Unmatched, line)
+		}
+
+		if line.PaymentStatus == model.Expired {
+			report.Expired = append(report.Expired, line)
+		}
+	}
+
+	for i := range report.ByStatus {
+		report.ByStatus[i].Total = float64(statusSatang[i]) / 100
+	}
+	for i := range report.ByMethod {
+		report.ByMethod[i].Total = float64(methodSatang[i]) / 100
+	}
+
+	report.Refunds = []model.RefundStatusSummary{
+		{RefundStatus: model.RefundRequested},
+		{RefundStatus: model.RefundCompleted},
+		{RefundStatus: model.RefundRejected},
+	}
+	refundSatang := make([]int64, len(report.Refunds))
+
+	var refundedSatang int64 = 0
+	for _, refund := range report.RefundLines {
+		for i := range report.Refunds {
+			if report.Refunds[i].RefundStatus == refund.RefundStatus {
+				report.Refunds[i].Count++
+				refundSatang[i] += toSatang(refund.Amount)
+			}
+		}
+
+		if refund.RefundStatus == model.RefundCompleted {
+			refundedSatang += toSatang(refund.Amount)
+		}
+	}
+
+	for i := range report.Refunds {
+		report.Refunds[i].Total = float64(refundSatang[i]) / 100
+	}
+
+	report.NetTotal = float64(paidSatang-refundedSatang) / 100
+}
+
+// GetDailyReport builds the reconciliation of a branch for the day date falls on,
+// for super admin and the manager who owns the branch
+func (u *paymentReportUsecase) GetDailyReport(branchID string, date time.Time, userID string, userRole string) (*model.PaymentReport, error) {
+	if err := checkBranchOwner(u.branchRepo, branchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	day := date.In(model.ReportLocation)
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, model.ReportLocation)
+	to := from.AddDate(0, 0, 1)
+
+	payments, err := u.paymentRepo.GetReportLines(branchID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := u.refundRepo.GetByBranchID(branchID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	report := model.PaymentReport{
+		BranchID:    branchID,
+		Date:        from.Format(time.DateOnly),
+		From:        from,
+		To:          to,
+		Payments:    *payments,
+		RefundLines: *refunds,
+	}
+
+	summarizePaymentReport(&report)
+
+	return &report, nil
+}
@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"testing"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+func TestIsUnmatched(t *testing.T) {
+	paid := 100.0
+	short := 99.5
+
+	tests := []struct {
+		name     string
+		line     model.PaymentReportLine
+		expected bool
+	}{
+		{"paid through provider", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Paid, ReceivedAmount: &paid}, false},
+		{"paid without money", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Paid}, true},
+		{"paid wrong amount", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Paid, ReceivedAmount: &short}, true},
+		{"money after expiry", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Expired, ReceivedAmount: &paid}, true},
+		{"expired unpaid", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Expired}, false},
+		{"pending", model.PaymentReportLine{Amount: 100, PaymentStatus: model.Pending}, false},
+	}
+
+	for _, test := range tests {
+		if got := isUnmatched(test.line); got != test.expected {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSummarizePaymentReport(t *testing.T) {
+	amount := func(a float64) *float64 { return &a }
+
+	report := model.PaymentReport{
+		Payments: []model.PaymentReportLine{
+			{PaymentID: "p1", Amount: 100.1, PaymentStatus: model.Paid, Method: "promptpay", ReceivedAmount: amount(100.1)},
+			{PaymentID: "p2", Amount: 50.2, PaymentStatus: model.Paid, Method: model.WalletMethod, ReceivedAmount: amount(50.2)},
+			{PaymentID: "p3", Amount: 0.7, PaymentStatus: model.Paid, Method: "promptpay", ReceivedAmount: amount(0.7)},
+			{PaymentID: "p4", Amount: 80, PaymentStatus: model.Expired, Method: "promptpay", ReceivedAmount: amount(80)},
+			{PaymentID: "p5", Amount: 40, PaymentStatus: model.Expired},
+			{PaymentID: "p6", Amount: 20, PaymentStatus: model.Pending},
+		},
+		RefundLines: []model.Refund{
+			{RefundID: "r1", Amount: 10.1, RefundStatus: model.RefundCompleted},
+			{RefundID: "r2", Amount: 80, RefundStatus: model.RefundRequested},
+			{RefundID: "r3", Amount: 5, RefundStatus: model.RefundRejected},
+		},
+	}
+
+	summarizePaymentReport(&report)
+
+	expectedStatus := []model.PaymentStatusSummary{
+		{PaymentStatus: model.Pending, Count: 1, Total: 20},
+		{PaymentStatus: model.Paid, Count: 3, Total: 151},
+		{PaymentStatus: model.Expired, Count: 2, Total: 120},
+		{PaymentStatus: model.Cancel, Count: 0, Total: 0},
+	}
+	for i, expected := range expectedStatus {
+		if report.ByStatus[i] != expected {
+			t.Errorf("For status '%s', expected %v, but got %v", expected.PaymentStatus, expected, report.ByStatus[i])
+		}
+	}
+
+	expectedMethod := []model.PaymentMethodSummary{
+		{Method: "promptpay", Count: 2, Total: 100.8},
+		{Method: model.WalletMethod, Count: 1, Total: 50.2},
+	}
+	if len(report.ByMethod) != len(expectedMethod) {
+		t.Fatalf("expected %d methods, but got %v", len(expectedMethod), report.ByMethod)
+	}
+	for i, expected := range expectedMethod {
+		if report.ByMethod[i] != expected {
+			t.Errorf("For method '%s', expected %v, but got %v", expected.Method, expected, report.ByMethod[i])
+		}
+	}
+
+	expectedRefunds := []model.RefundStatusSummary{
+		{RefundStatus: model.RefundRequested, Count: 1, Total: 80},
+		{RefundStatus: model.RefundCompleted, Count: 1, Total: 10.1},
+		{RefundStatus: model.RefundRejected, Count: 1, Total: 5},
+	}
+	for i, expected := range expectedRefunds {
+		if report.Refunds[i] != expected {
+			t.Errorf("For refund '%s', expected %v, but got %v", expected.RefundStatus, expected, report.Refunds[i])
+		}
+	}
+
+	if report.NetTotal != 140.9 {
+		t.Errorf("expected net total 140.9, but got %v", report.NetTotal)
+	}
+
+	if len(report.Unmatched) != 1 || report.Unmatched[0].PaymentID != "p4" {
+		t.Errorf("expected p4 unmatched, but got %v", report.Unmatched)
+	}
+
+	if len(report.Expired) != 2 {
+		t.Errorf("expected 2 expired, but got %v", report.Expired)
+	}
+}
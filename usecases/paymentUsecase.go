@@ -2,28 +2,83 @@ package usecases
 
 import (
 	"errors"
+	"log"
+	"math"
 	"time"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type paymentUsecase struct {
 	paymentRepository model.PaymentRepository
+	refundRepository  model.RefundRepository
+	walletRepository  model.WalletRepository
+	policyRepository  model.PaymentPolicyRepository
+	paymentProvider   model.PaymentProvider
+	unitOfWork        repository.UnitOfWork
+	machineAssignment MachineAssignmentUsecase
+	jobRepository     model.JobRepository
+	orderHeaderRepo   repository.OrderHeaderRepository
+	branchRepo        repository.BranchReopository
+	contractRepo      repository.EmployeeContractRepository
 }
 
-func CreateNewPaymentUsecase(paymentRepository model.PaymentRepository) model.PaymentUsecase {
-	return &paymentUsecase{paymentRepository: paymentRepository}
+func CreateNewPaymentUsecase(paymentRepository model.PaymentRepository, refundRepository model.RefundRepository, walletRepository model.WalletRepository, policyRepository model.PaymentPolicyRepository, paymentProvider model.PaymentProvider, unitOfWork repository.UnitOfWork, machineAssignment MachineAssignmentUsecase, jobRepository model.JobRepository, orderHeaderRepo repository.OrderHeaderRepository, branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository) model.PaymentUsecase {
+	return &paymentUsecase{
+		paymentRepository: paymentRepository,
+		refundRepository:  refundRepository,
+		walletRepository:  walletRepository,
+		policyRepository:  policyRepository,
+		paymentProvider:   paymentProvider,
+		unitOfWork:        unitOfWork,
+		machineAssignment: machineAssignment,
+		jobRepository:     jobRepository,
+		orderHeaderRepo:   orderHeaderRepo,
+		branchRepo:        branchRepo,
+		contractRepo:      contractRepo,
+	}
 }
 
-func (u *paymentUsecase) CreatePayment(newPayment model.Payments) (*model.Payments, error) {
-	data := model.Payments{
+// WithTx returns a payment usecase that writes through the given transaction
+func (u *paymentUsecase) WithTx(tx *platform.Postgres) model.PaymentUsecase {
+	return &paymentUsecase{
+		paymentRepository: u.paymentRepository.WithTx(tx),
+		refundRepository:  u.refundRepository.WithTx(tx),
+		walletRepository:  u.walletRepository.WithTx(tx),
+		policyRepository:  u.policyRepository,
+		paymentProvider:   u.paymentProvider,
+		unitOfWork:        u.unitOfWork,
+		machineAssignment: u.machineAssignment,
+		jobRepository:     u.jobRepository.WithTx(tx),
+		orderHeaderRepo:   u.orderHeaderRepo,
+		branchRepo:        u.branchRepo,
+		contractRepo:      u.contractRepo,
+	}
+}
+
+// pendingPayment is a new payment of amount that is due by the window of policy
+func pendingPayment(amount float64, policy model.PaymentPolicy, now time.Time) model.Payments {
+	payment := model.Payments{
 		PaymentID:      uuid.New().String(),
-		Amount:         newPayment.Amount,
-		Payment_Status: "Pending",
-		DueDate:        time.Now().UTC().Add(time.Minute * 10),
-		CreatedAt:      time.Now().UTC(),
+		Amount:         amount,
+		Payment_Status: model.Pending,
+		DueDate:        now.Add(time.Minute * time.Duration(policy.DueMinutes)),
+		CreatedAt:      now,
+	}
+
+	if policy.PolicyID != "" {
+		payment.PolicyID = &policy.PolicyID
 	}
+
+	return payment
+}
+
+func (u *paymentUsecase) CreatePayment(newPayment model.Payments) (*model.Payments, error) {
+	data := pendingPayment(newPayment.Amount, model.DefaultPaymentPolicy(false), time.Now().UTC())
 	response, err := u.paymentRepository.CreatePayment(data)
 	if err != nil {
 		return nil, err
@@ -31,6 +86,21 @@ func (u *paymentUsecase) CreatePayment(newPayment model.Payments) (*model.Paymen
 	return response, nil
 }
 
+// CreateOrderPayment creates the payment of an order placed at branchID, it's
+// due by the policy of the branch and order type
+func (u *paymentUsecase) CreateOrderPayment(amount float64, branchID string, zuckOnsite bool) (*model.Payments, error) {
+	policy := model.DefaultPaymentPolicy(zuckOnsite)
+
+	found, err := u.policyRepository.FindEffectivePolicy(branchID, zuckOnsite)
+	if err == nil {
+		policy = *found
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return u.paymentRepository.CreatePayment(pendingPayment(amount, policy, time.Now().UTC()))
+}
+
 func (u *paymentUsecase) FindByPaymentID(paymentID string) (*model.Payments, error) {
 	data, err := u.paymentRepository.FindByPaymentID(paymentID)
 	if err != nil {
@@ -39,10 +109,16 @@ func (u *paymentUsecase) FindByPaymentID(paymentID string) (*model.Payments, err
 	return data, nil
 }
 
+// UpdatePaymentStatus is for staff to fix a payment by hand,
+// a payment only becomes Paid through HandleWebhook
 func (u *paymentUsecase) UpdatePaymentStatus(paymentID string, status model.PaymentStatus) (*model.Payments, error) {
 	var response *model.Payments = nil
 	var err error
 
+	if status == model.Paid {
+		return nil, errors.New("ERR 403: payment can only be paid through the payment provider")
+	}
+
 	response, err = u.paymentRepository.FindByPaymentID(paymentID)
 	if err != nil {
 		return nil, err
@@ -58,5 +134,237 @@ func (u *paymentUsecase) UpdatePaymentStatus(paymentID string, status model.Paym
 	if err != nil {
 		return nil, err
 	}
+
 	return response, nil
 }
+
+// CancelPayment cancels a payment that is still pending, a paid payment is kept
+// as is and a refund of what wasn't refunded yet is recorded instead.
+// Payments made from a wallet are refunded back to the wallet right away.
+// Call it through WithTx so the payment stays locked until the order is canceled too.
+func (u *paymentUsecase) CancelPayment(paymentID string, orderHeaderID string, canceledBy string) (*model.Payments, error) {
+	payment, err := u.paymentRepository.LockPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payment.Payment_Status {
+	case model.Pending:
+		if err := u.paymentRepository.CancelPayment(paymentID); err != nil {
+			return nil, err
+		}
+	case model.Paid:
+		refunded, err := u.refundRepository.SumByPaymentID(paymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if toSatang(refunded) >= toSatang(payment.Amount) {
+			break
+		}
+
+		refund := model.Refund{
+			RefundID:      uuid.New().String(),
+			PaymentID:     paymentID,
+			OrderHeaderID: orderHeaderID,
+			Amount:        float64(toSatang(payment.Amount)-toSatang(refunded)) / 100,
+			RefundStatus:  model.RefundRequested,
+			Reason:        "order canceled by customer",
+			CreatedAt:     time.Now().UTC(),
+			CreatedBy:     canceledBy,
+		}
+
+		debit, err := u.walletRepository.FindDebitByPaymentID(paymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if debit != nil {
+			refund.RefundStatus = model.RefundCompleted
+			refund.ProcessedAt = &refund.CreatedAt
+			refund.ProcessedBy = &refund.CreatedBy
+		}
+
+		if err := u.refundRepository.CreateRefund(&refund); err != nil {
+			return nil, err
+		}
+
+		if debit != nil {
+			if err := creditRefund(u.walletRepository, debit, refund, canceledBy); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("ERR 400: cannot cancel payment that is " + string(payment.Payment_Status))
+	}
+
+	return u.paymentRepository.FindByPaymentID(paymentID)
+}
+
+// PayWithWallet settles a pending payment from the wallet of userID.
+// Call it through WithTx so a failed debit rolls back whatever the payment is for.
+func (u *paymentUsecase) PayWithWallet(paymentID string, userID string) (*model.Payments, error) {
+	payment, err := u.paymentRepository.LockPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Payment_Status != model.Pending || !time.Now().UTC().Before(payment.DueDate) {
+		return nil, errors.New("ERR 400: payment is not waiting to be paid")
+	}
+
+	balance, err := u.walletRepository.Debit(userID, payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := model.WalletTransaction{
+		TransactionID:   uuid.New().String(),
+		UserID:          userID,
+		TransactionType: model.WalletDebit,
+		Amount:          payment.Amount,
+		BalanceAfter:    balance,
+		PaymentID:       &payment.PaymentID,
+		CreatedAt:       time.Now().UTC(),
+		CreatedBy:       userID,
+	}
+
+	if err := u.walletRepository.CreateTransaction(&transaction); err != nil {
+		return nil, err
+	}
+
+	if err := u.paymentRepository.MarkPaid(paymentID); err != nil {
+		return nil, err
+	}
+
+	if err := u.startMachines(u.jobRepository, paymentID); err != nil {
+		return nil, err
+	}
+
+	return u.paymentRepository.FindByPaymentID(paymentID)
+}
+
+// startMachines queues the start of the machines the order is waiting at,
+// in the transaction that marks the payment as paid
+func (u *paymentUsecase) startMachines(jobRepository model.JobRepository, paymentID string) error {
+	job := model.NewJob(model.StartMachinesJob, paymentID, time.Now().UTC())
+	return jobRepository.Enqueue(&job)
+}
+
+// AfterPaid starts the work of an order once its payment is committed as paid,
+// if no machine is free the cron will pick it up later
+func (u *paymentUsecase) AfterPaid(paymentID string) {
+	if err := u.machineAssignment.AssignByPaymentID(paymentID); err != nil {
+		log.Println("ERR: cannot assign machine for payment", paymentID, err)
+	}
+}
+
+// CreateCharge asks the provider how the customer can pay, e.g. the QR to scan.
+// The money goes to the branch the order was placed at. Only the customer of
+// the order and staff of its branch can ask.
+func (u *paymentUsecase) CreateCharge(paymentID string, userID string, userRole string) (*model.PaymentCharge, error) {
+	order, err := u.orderHeaderRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkOrderAccess(u.branchRepo, u.contractRepo, order.UserID, order.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	payment, err := u.paymentRepository.FindByPaymentID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Payment_Status != model.Pending || !time.Now().UTC().Before(payment.DueDate) {
+		return nil, errors.New("ERR 400: payment is not waiting to be paid")
+	}
+
+	merchantID, err := u.paymentRepository.FindMerchantID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.paymentProvider.CreateCharge(*payment, merchantID)
+}
+
+// HandleWebhook applies a paid notification from the provider. The event id is
+// recorded in the same transaction as the payment update so a retried webhook
+// is applied exactly once. Money that comes in after the payment expired or
+// was canceled can't be used anymore, so it's recorded as a refund instead.
+func (u *paymentUsecase) HandleWebhook(body []byte, signature string) (*model.Payments, error) {
+	event, err := u.paymentProvider.VerifyWebhook(body, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Provider = u.paymentProvider.Name()
+	event.ReceivedAt = time.Now().UTC()
+
+	isPaid := false
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		paymentRepository := u.paymentRepository.WithTx(tx)
+
+		isNew, err := paymentRepository.RecordWebhookEvent(event)
+		if err != nil {
+			return err
+		}
+
+		if !isNew {
+			return nil
+		}
+
+		payment, err := paymentRepository.LockPayment(event.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if math.Abs(payment.Amount-event.Amount) >= 0.01 {
+			return errors.New("ERR 400: paid amount does not match the payment")
+		}
+
+		switch payment.Payment_Status {
+		case model.Paid:
+			// paid by an earlier event with another id
+			return nil
+		case model.Pending:
+			// the customer already paid, so a payment the cron hasn't expired yet is still fine
+			isPaid = true
+			if err := paymentRepository.MarkPaid(event.PaymentID); err != nil {
+				return err
+			}
+			return u.startMachines(u.jobRepository.WithTx(tx), event.PaymentID)
+		}
+
+		// refunds are looked up and checked through their order
+		order, err := u.orderHeaderRepo.WithTx(tx).GetByPaymentID(event.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		refund := model.Refund{
+			RefundID:      uuid.New().String(),
+			PaymentID:     event.PaymentID,
+			OrderHeaderID: order.OrderHeaderID,
+			Amount:        event.Amount,
+			RefundStatus:  model.RefundRequested,
+			Reason:        "paid after the payment was " + string(payment.Payment_Status),
+			CreatedAt:     time.Now().UTC(),
+			CreatedBy:     model.SystemActor,
+		}
+
+		return u.refundRepository.WithTx(tx).CreateRefund(&refund)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isPaid {
+		u.AfterPaid(event.PaymentID)
+	}
+
+	return u.paymentRepository.FindByPaymentID(event.PaymentID)
+}
@@ -0,0 +1,224 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/paymentgateway"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type fakeUnitOfWork struct{}
+
+func (f *fakeUnitOfWork) Do(work func(tx *platform.Postgres) error) error {
+	return work(nil)
+}
+
+type fakePaymentRepo struct {
+	model.PaymentRepository
+	payments map[string]*model.Payments
+	events   map[string]bool
+}
+
+func (f *fakePaymentRepo) WithTx(tx *platform.Postgres) model.PaymentRepository { return f }
+
+func (f *fakePaymentRepo) FindByPaymentID(paymentID string) (*model.Payments, error) {
+	payment := *f.payments[paymentID]
+	return &payment, nil
+}
+
+func (f *fakePaymentRepo) LockPayment(paymentID string) (*model.Payments, error) {
+	return f.FindByPaymentID(paymentID)
+}
+
+func (f *fakePaymentRepo) MarkPaid(paymentID string) error {
+	f.payments[paymentID].Payment_Status = model.Paid
+	return nil
+}
+
+func (f *fakePaymentRepo) RecordWebhookEvent(event *model.PaymentWebhookEvent) (bool, error) {
+	if f.events[event.EventID] {
+		return false, nil
+	}
+	f.events[event.EventID] = true
+	return true, nil
+}
+
+func (f *fakePaymentRepo) FindMerchantID(paymentID string) (string, error) {
+	return "0812345678", nil
+}
+
+type fakeRefundRepo struct {
+	model.RefundRepository
+	refunds []model.Refund
+}
+
+func (f *fakeRefundRepo) WithTx(tx *platform.Postgres) model.RefundRepository { return f }
+
+func (f *fakeRefundRepo) CreateRefund(refund *model.Refund) error {
+	f.refunds = append(f.refunds, *refund)
+	return nil
+}
+
+func (f *fakeRefundRepo) FindByRefundID(refundID string) (*model.Refund, error) {
+	for _, refund := range f.refunds {
+		if refund.RefundID == refundID {
+			return &refund, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeRefundRepo) SumByPaymentID(paymentID string) (float64, error) {
+	total := 0.0
+	for _, refund := range f.refunds {
+		if refund.PaymentID == paymentID && refund.RefundStatus != model.RefundRejected {
+			total += refund.Amount
+		}
+	}
+	return total, nil
+}
+
+type fakeMachineAssignment struct {
+	MachineAssignmentUsecase
+	assigned []string
+}
+
+func (f *fakeMachineAssignment) AssignByPaymentID(paymentID string) error {
+	f.assigned = append(f.assigned, paymentID)
+	return nil
+}
+
+func TestHandleWebhook(t *testing.T) {
+	provider := paymentgateway.CreateFakeProvider("secret")
+
+	tests := []struct {
+		name          string
+		status        model.PaymentStatus
+		amount        float64
+		replay        bool
+		expectErr     string
+		expectStatus  model.PaymentStatus
+		expectRefunds int
+		expectAssign  int
+	}{
+		{"pending payment is paid", model.Pending, 100, false, "", model.Paid, 0, 1},
+		{"same event twice is applied once", model.Pending, 100, true, "", model.Paid, 0, 1},
+		{"wrong amount is refused", model.Pending, 90, false, "400", model.Pending, 0, 0},
+		{"paid after expiry is refunded", model.Expired, 100, false, "", model.Expired, 1, 0},
+		{"paid after cancel is refunded", model.Cancel, 100, false, "", model.Cancel, 1, 0},
+		{"already paid is left alone", model.Paid, 100, false, "", model.Paid, 0, 0},
+	}
+
+	for _, test := range tests {
+		paymentRepo := &fakePaymentRepo{
+			payments: map[string]*model.Payments{
+				"payment-1": {PaymentID: "payment-1", Amount: 100, Payment_Status: test.status, DueDate: time.Now().Add(time.Minute)},
+			},
+			events: map[string]bool{},
+		}
+		refundRepo := &fakeRefundRepo{}
+		assignment := &fakeMachineAssignment{}
+		u := CreateNewPaymentUsecase(paymentRepo, refundRepo, &fakeWalletRepo{}, &fakePaymentPolicyRepo{}, provider, &fakeUnitOfWork{}, assignment, &fakeJobRepo{}, &fakeOrderByPaymentRepo{order: model.OrderHeader{OrderHeaderID: "order-1", PaymentID: "payment-1"}}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+		body, signature := provider.PaidEvent("event-1", "payment-1", test.amount)
+
+		_, err := u.HandleWebhook(body, signature)
+		if test.replay {
+			_, err = u.HandleWebhook(body, signature)
+		}
+
+		if test.expectErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if test.expectErr != "" && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+		}
+		if status := paymentRepo.payments["payment-1"].Payment_Status; status != test.expectStatus {
+			t.Errorf("%s: expected payment %s, but got %s", test.name, test.expectStatus, status)
+		}
+		if len(refundRepo.refunds) != test.expectRefunds {
+			t.Errorf("%s: expected %d refunds, but got %d", test.name, test.expectRefunds, len(refundRepo.refunds))
+		}
+		for _, refund := range refundRepo.refunds {
+			if refund.OrderHeaderID != "order-1" {
+				t.Errorf("%s: expected the refund of order-1, but got order '%s'", test.name, refund.OrderHeaderID)
+			}
+		}
+		if len(assignment.assigned) != test.expectAssign {
+			t.Errorf("%s: expected %d machine assignments, but got %d", test.name, test.expectAssign, len(assignment.assigned))
+		}
+	}
+}
+
+func TestHandleWebhookRejectsBadSignature(t *testing.T) {
+	provider := paymentgateway.CreateFakeProvider("secret")
+	paymentRepo := &fakePaymentRepo{
+		payments: map[string]*model.Payments{"payment-1": {PaymentID: "payment-1", Amount: 100, Payment_Status: model.Pending}},
+		events:   map[string]bool{},
+	}
+	u := CreateNewPaymentUsecase(paymentRepo, &fakeRefundRepo{}, &fakeWalletRepo{}, &fakePaymentPolicyRepo{}, provider, &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, &fakeOrderByPaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+	body, _ := provider.PaidEvent("event-1", "payment-1", 100)
+	forged := paymentgateway.Sign("guessed-secret", body)
+
+	if _, err := u.HandleWebhook(body, forged); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected 401 for a forged signature, but got %v", err)
+	}
+
+	if paymentRepo.payments["payment-1"].Payment_Status != model.Pending || len(paymentRepo.events) != 0 {
+		t.Errorf("Forged webhook must not touch the payment")
+	}
+}
+
+func TestUpdatePaymentStatusCannotPay(t *testing.T) {
+	u := CreateNewPaymentUsecase(&fakePaymentRepo{}, &fakeRefundRepo{}, &fakeWalletRepo{}, &fakePaymentPolicyRepo{}, paymentgateway.CreateFakeProvider("secret"), &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, &fakeOrderByPaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+	if _, err := u.UpdatePaymentStatus("payment-1", model.Paid); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 when setting Paid by hand, but got %v", err)
+	}
+}
+
+func TestCreateChargeAccess(t *testing.T) {
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"payment-1": {PaymentID: "payment-1", Amount: 100, Payment_Status: model.Pending, DueDate: time.Now().UTC().Add(time.Hour)},
+	}}
+	orders := &fakeOrderByPaymentRepo{order: model.OrderHeader{OrderHeaderID: "order-1", UserID: "customer", BranchID: "branch-1", PaymentID: "payment-1"}}
+	branchRepo := &fakeBranchRepo{branch: model.Branch{BranchID: "branch-1", OwnerUserID: "owner"}}
+	contractRepo := &fakeContractRepo{contracts: []model.EmployeeContract{
+		{UserID: "employee-1", BranchID: "branch-1"},
+		{UserID: "employee-2", BranchID: "branch-2"},
+	}}
+	u := CreateNewPaymentUsecase(paymentRepo, &fakeRefundRepo{}, &fakeWalletRepo{}, &fakePaymentPolicyRepo{}, paymentgateway.CreateFakeProvider("secret"), &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, orders, branchRepo, contractRepo)
+
+	tests := []struct {
+		name     string
+		userID   string
+		userRole model.Roles
+		expected bool
+	}{
+		{"customer of the order", "customer", model.Client, true},
+		{"another customer", "someone", model.Client, false},
+		{"employee of the branch", "employee-1", model.Employee, true},
+		{"employee of another branch", "employee-2", model.Employee, false},
+		{"manager of another branch", "other-manager", model.BranchManager, false},
+		{"super admin", "admin", model.SuperAdmin, true},
+	}
+
+	for _, test := range tests {
+		charge, err := u.CreateCharge("payment-1", test.userID, string(test.userRole))
+		if (err == nil) != test.expected {
+			t.Errorf("%s: expected allowed %v, but got %v", test.name, test.expected, err)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), "403") {
+			t.Errorf("%s: expected forbidden, but got %v", test.name, err)
+		}
+		if err == nil && charge.PaymentID != "payment-1" {
+			t.Errorf("%s: expected the charge of payment-1, but got %v", test.name, charge)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package usecases
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type promoCodeUsecase struct {
+	promoCodeRepo model.PromoCodeRepository
+	branchRepo    repository.BranchReopository
+}
+
+func CreateNewPromoCodeUsecase(promoCodeRepo model.PromoCodeRepository, branchRepo repository.BranchReopository) model.PromoCodeUsecase {
+	return &promoCodeUsecase{
+		promoCodeRepo: promoCodeRepo,
+		branchRepo:    branchRepo,
+	}
+}
+
+// normalizePromoCode makes codes case insensitive, they are stored upper case
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promoDiscount checks promo can be used on an order of subtotal baht at branchID
+// and returns how much it takes off, rounded to satang
+func promoDiscount(promo *model.PromoCode, branchID string, subtotal float64, at time.Time, usage *model.PromoUsage) (float64, error) {
+	if at.Before(promo.ValidFrom) || (promo.ValidUntil != nil && !at.Before(*promo.ValidUntil)) {
+		return 0, errors.New("ERR 400: promo code is not valid at this time")
+	}
+
+	if promo.BranchID != nil && *promo.BranchID != branchID {
+		return 0, errors.New("ERR 400: promo code can't be used in this branch")
+	}
+
+	if toSatang(subtotal) < toSatang(promo.MinOrderAmount) {
+		return 0, errors.New("ERR 400: order is below the minimum amount of the promo code")
+	}
+
+	if promo.MaxRedemptions != nil && usage.Total >= int64(*promo.MaxRedemptions) {
+		return 0, errors.New("ERR 400: promo code is fully redeemed")
+	}
+
+	if promo.MaxRedemptionsPerUser != nil && usage.ByUser >= int64(*promo.MaxRedemptionsPerUser) {
+		return 0, errors.New("ERR 400: promo code was already used the most times allowed")
+	}
+
+	discount := promo.DiscountValue
+	if promo.DiscountType == model.PercentageDiscount {
+		discount = subtotal * promo.DiscountValue / 100
+		if promo.MaxDiscount != nil {
+			discount = math.Min(discount, *promo.MaxDiscount)
+		}
+	}
+
+	// creating the code makes sure this never happens, it's only a safety net
+	if toSatang(discount) >= toSatang(subtotal) {
+		return 0, errors.New("ERR 400: promo code can't cover the whole order")
+	}
+
+	return float64(toSatang(discount)) / 100, nil
+}
+
+// CreatePromoCode adds a code, a code can't make an order free so the payment
+// always has something to be paid
+func (u *promoCodeUsecase) CreatePromoCode(newPromoCode *model.NewPromoCode, userID string) (*model.PromoCode, error) {
+	if newPromoCode.DiscountType == model.PercentageDiscount && newPromoCode.DiscountValue >= 100 {
+		return nil, errors.New("ERR 400: percentage discount must be less than 100")
+	}
+
+	if newPromoCode.DiscountType == model.FixedDiscount && toSatang(newPromoCode.MinOrderAmount) <= toSatang(newPromoCode.DiscountValue) {
+		return nil, errors.New("ERR 400: min order amount must be more than a fixed discount")
+	}
+
+	if newPromoCode.DiscountType == model.FixedDiscount && newPromoCode.MaxDiscount != nil {
+		return nil, errors.New("ERR 400: max discount is only for percentage discounts")
+	}
+
+	now := time.Now().UTC()
+	validFrom := now
+	if newPromoCode.ValidFrom != nil {
+		validFrom = newPromoCode.ValidFrom.UTC()
+	}
+
+	if newPromoCode.ValidUntil != nil && !validFrom.Before(*newPromoCode.ValidUntil) {
+		return nil, errors.New("ERR 400: valid until must be after valid from")
+	}
+
+	if newPromoCode.BranchID != nil {
+		if _, err := u.branchRepo.GetByBranchID(*newPromoCode.BranchID); err != nil {
+			return nil, err
+		}
+	}
+
+	code := normalizePromoCode(newPromoCode.Code)
+	if _, err := u.promoCodeRepo.FindByCode(code); err == nil {
+		return nil, errors.New("ERR 400: promo code already exists")
+	}
+
+	promoCode := model.PromoCode{
+		PromoCodeID:           uuid.New().String(),
+		Code:                  code,
+		DiscountType:          newPromoCode.DiscountType,
+		DiscountValue:         newPromoCode.DiscountValue,
+		MaxDiscount:           newPromoCode.MaxDiscount,
+		BranchID:              newPromoCode.BranchID,
+		MinOrderAmount:        newPromoCode.MinOrderAmount,
+		MaxRedemptions:        newPromoCode.MaxRedemptions,
+		MaxRedemptionsPerUser: newPromoCode.MaxRedemptionsPerUser,
+		ValidFrom:             validFrom,
+		ValidUntil:            newPromoCode.ValidUntil,
+		CreatedAt:             now,
+		CreatedBy:             userID,
+	}
+
+	if err := u.promoCodeRepo.CreatePromoCode(&promoCode); err != nil {
+		return nil, err
+	}
+
+	return u.promoCodeRepo.FindByPromoCodeID(promoCode.PromoCodeID)
+}
+
+func (u *promoCodeUsecase) GetAll() (*[]model.PromoCode, error) {
+	return u.promoCodeRepo.GetAll()
+}
+
+// GetReport sums up every redemption of a code
+func (u *promoCodeUsecase) GetReport(promoCodeID string) (*model.PromoCodeReport, error) {
+	promoCode, err := u.promoCodeRepo.FindByPromoCodeID(promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions, err := u.promoCodeRepo.GetRedemptions(promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := model.PromoCodeReport{
+		PromoCode:   *promoCode,
+		Redemptions: *redemptions,
+	}
+
+	users := []string{}
+	var totalDiscount int64 = 0
+	for _, r := range *redemptions {
+		users = append(users, r.UserID)
+		totalDiscount += toSatang(r.DiscountAmount)
+	}
+
+	report.RedemptionCount = len(*redemptions)
+	report.UserCount = len(uniqueStrings(users))
+	report.TotalDiscount = float64(totalDiscount) / 100
+
+	return &report, nil
+}
@@ -0,0 +1,52 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+)
+
+func TestPromoDiscount(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	branch := "branch-1"
+	maxDiscount := 30.0
+	one := 1
+	three := 3
+
+	tests := []struct {
+		name      string
+		promo     model.PromoCode
+		branchID  string
+		subtotal  float64
+		usage     model.PromoUsage
+		expected  float64
+		expectErr bool
+	}{
+		{"percentage", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, ValidFrom: yesterday}, "branch-1", 150, model.PromoUsage{}, 15, false},
+		{"percentage rounded to satang", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 15, ValidFrom: yesterday}, "branch-1", 33.33, model.PromoUsage{}, 5, false},
+		{"percentage capped", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 50, MaxDiscount: &maxDiscount, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{}, 30, false},
+		{"fixed", model.PromoCode{DiscountType: model.FixedDiscount, DiscountValue: 20, MinOrderAmount: 100, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{}, 20, false},
+		{"below min amount", model.PromoCode{DiscountType: model.FixedDiscount, DiscountValue: 20, MinOrderAmount: 100, ValidFrom: yesterday}, "branch-1", 99.99, model.PromoUsage{}, 0, true},
+		{"not started", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, ValidFrom: tomorrow}, "branch-1", 100, model.PromoUsage{}, 0, true},
+		{"ended", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, ValidFrom: yesterday, ValidUntil: &now}, "branch-1", 100, model.PromoUsage{}, 0, true},
+		{"own branch", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, BranchID: &branch, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{}, 10, false},
+		{"other branch", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, BranchID: &branch, ValidFrom: yesterday}, "branch-2", 100, model.PromoUsage{}, 0, true},
+		{"fully redeemed", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, MaxRedemptions: &three, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{Total: 3}, 0, true},
+		{"used by the user", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, MaxRedemptionsPerUser: &one, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{Total: 1, ByUser: 1}, 0, true},
+		{"used by others", model.PromoCode{DiscountType: model.PercentageDiscount, DiscountValue: 10, MaxRedemptionsPerUser: &one, ValidFrom: yesterday}, "branch-1", 100, model.PromoUsage{Total: 2}, 10, false},
+		{"whole order", model.PromoCode{DiscountType: model.FixedDiscount, DiscountValue: 50, ValidFrom: yesterday}, "branch-1", 50, model.PromoUsage{}, 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := promoDiscount(&test.promo, test.branchID, test.subtotal, now, &test.usage)
+		if (err != nil) != test.expectErr {
+			t.Errorf("%s: expected error %v, but got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.expected, result)
+		}
+	}
+}
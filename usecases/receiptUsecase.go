@@ -0,0 +1,196 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type receiptUsecase struct {
+	receiptRepo  model.ReceiptRepository
+	paymentRepo  model.PaymentRepository
+	walletRepo   model.WalletRepository
+	branchRepo   repository.BranchReopository
+	contractRepo repository.EmployeeContractRepository
+	orderUsecase OrderUsecase
+	unitOfWork   repository.UnitOfWork
+}
+
+func CreateNewReceiptUsecase(receiptRepo model.ReceiptRepository, paymentRepo model.PaymentRepository, walletRepo model.WalletRepository, branchRepo repository.BranchReopository, contractRepo repository.EmployeeContractRepository, orderUsecase OrderUsecase, unitOfWork repository.UnitOfWork) model.ReceiptUsecase {
+	return &receiptUsecase{
+		receiptRepo:  receiptRepo,
+		paymentRepo:  paymentRepo,
+		walletRepo:   walletRepo,
+		branchRepo:   branchRepo,
+		contractRepo: contractRepo,
+		orderUsecase: orderUsecase,
+		unitOfWork:   unitOfWork,
+	}
+}
+
+// vatBreakdown splits a VAT included total into the price before VAT and the VAT
+func vatBreakdown(total float64) (float64, float64) {
+	totalSatang := toSatang(total)
+	vatSatang := toSatang(float64(totalSatang) * model.VATRate / (100 + model.VATRate) / 100)
+	return float64(totalSatang-vatSatang) / 100, float64(vatSatang) / 100
+}
+
+func receiptLineDescription(line model.OrderPriceLine) string {
+	switch line.ServiceType {
+	case model.Washing, model.Drying:
+		if line.ProgramName != nil {
+			return fmt.Sprintf("%s %d kg, %s", line.ServiceType, line.Weight, *line.ProgramName)
+		}
+		return fmt.Sprintf("%s %d kg", line.ServiceType, line.Weight)
+	case model.DiscountLine:
+		return "Promo discount"
+	case model.PointsLine:
+		return "Points redeemed"
+	}
+	return string(line.ServiceType)
+}
+
+// receiptLines lists what the order was charged for. Orders placed before prices
+// were kept per line only know their baskets, they get one line for the whole amount.
+func receiptLines(order *model.FullOrder, total float64) []model.ReceiptLine {
+	lines := []model.ReceiptLine{}
+
+	for _, line := range order.PriceLines {
+		lines = append(lines, model.ReceiptLine{
+			Description: receiptLineDescription(line),
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Amount:      line.Amount,
+		})
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, model.ReceiptLine{
+			Description: fmt.Sprintf("Laundry service, %d baskets", len(order.OrderDetails)),
+			Quantity:    1,
+			UnitPrice:   total,
+			Amount:      total,
+		})
+	}
+
+	return lines
+}
+
+// issueReceipt stores the receipt of a paid order the first time it's asked for.
+// The payment lock makes sure an order only ever takes one number.
+func (u *receiptUsecase) issueReceipt(order *model.FullOrder, userID string) (*model.Receipt, error) {
+	var issued *model.Receipt
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		receiptRepo := u.receiptRepo.WithTx(tx)
+
+		payment, err := u.paymentRepo.WithTx(tx).LockPayment(order.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if payment.Payment_Status != model.Paid {
+			return errors.New("ERR 400: order is not paid")
+		}
+
+		issued, err = receiptRepo.FindByOrderHeaderID(order.OrderHeaderID)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		number, err := receiptRepo.NextNumber(order.BranchID)
+		if err != nil {
+			return err
+		}
+
+		paidAt := payment.CreatedAt
+		if payment.PaidAt != nil {
+			paidAt = *payment.PaidAt
+		}
+
+		subtotal, vat := vatBreakdown(payment.Amount)
+
+		issued = &model.Receipt{
+			ReceiptID:     uuid.New().String(),
+			BranchID:      order.BranchID,
+			ReceiptNo:     number,
+			OrderHeaderID: order.OrderHeaderID,
+			PaymentID:     payment.PaymentID,
+			Subtotal:      subtotal,
+			VAT:           vat,
+			Total:         payment.Amount,
+			PaidAt:        paidAt,
+			IssuedAt:      time.Now().UTC(),
+			IssuedBy:      userID,
+		}
+
+		return receiptRepo.CreateReceipt(issued)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+// GetReceipt returns the receipt of a paid order, numbering it the first time.
+// Customers can only get the receipt of their own order, staff of the branch it was placed at.
+func (u *receiptUsecase) GetReceipt(orderHeaderID string, userID string, userRole string) (*model.ReceiptDocument, error) {
+	result, err := u.orderUsecase.GetByHeaderID(orderHeaderID, false, "full")
+	if err != nil {
+		return nil, err
+	}
+	order := result.(*model.FullOrder)
+
+	if err := checkOrderAccess(u.branchRepo, u.contractRepo, order.UserID, order.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	receipt, err := u.receiptRepo.FindByOrderHeaderID(orderHeaderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		receipt, err = u.issueReceipt(order, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	branch, err := u.branchRepo.GetByBranchID(order.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	debit, err := u.walletRepo.FindDebitByPaymentID(order.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentMethod := "PromptPay"
+	if debit != nil {
+		paymentMethod = model.WalletMethod
+	}
+
+	document := model.ReceiptDocument{
+		Receipt:         *receipt,
+		ReceiptNumber:   receipt.Number(),
+		BranchName:      branch.BranchName,
+		BranchDetail:    branch.BranchDetail,
+		BranchTaxID:     branch.TaxID,
+		CustomerName:    order.UserDetail.FirstName + " " + order.UserDetail.LastName,
+		CustomerEmail:   order.UserDetail.Email,
+		ZuckOnsite:      order.ZuckOnsite,
+		DeliveryAddress: order.DeliveryAddress,
+		PaymentMethod:   paymentMethod,
+		Lines:           receiptLines(order, receipt.Total),
+	}
+
+	return &document, nil
+}
@@ -0,0 +1,142 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+
+	"gorm.io/gorm"
+)
+
+type fakeReceiptRepo struct {
+	model.ReceiptRepository
+	numbers  map[string]int64
+	receipts []model.Receipt
+}
+
+func (f *fakeReceiptRepo) WithTx(tx *platform.Postgres) model.ReceiptRepository { return f }
+
+func (f *fakeReceiptRepo) NextNumber(branchID string) (int64, error) {
+	f.numbers[branchID]++
+	return f.numbers[branchID], nil
+}
+
+func (f *fakeReceiptRepo) CreateReceipt(receipt *model.Receipt) error {
+	f.receipts = append(f.receipts, *receipt)
+	return nil
+}
+
+func (f *fakeReceiptRepo) FindByOrderHeaderID(orderHeaderID string) (*model.Receipt, error) {
+	for _, receipt := range f.receipts {
+		if receipt.OrderHeaderID == orderHeaderID {
+			return &receipt, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeFullOrderUsecase struct {
+	OrderUsecase
+	orders map[string]model.FullOrder
+}
+
+func (f *fakeFullOrderUsecase) GetByHeaderID(orderHeaderID string, isAdminView bool, option string) (interface{}, error) {
+	order, ok := f.orders[orderHeaderID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &order, nil
+}
+
+func TestVATBreakdown(t *testing.T) {
+	tests := []struct {
+		total    float64
+		subtotal float64
+		vat      float64
+	}{
+		{107, 100, 7},
+		{100, 93.46, 6.54},
+		{0.5, 0.47, 0.03},
+		{0, 0, 0},
+	}
+
+	for _, test := range tests {
+		subtotal, vat := vatBreakdown(test.total)
+		if subtotal != test.subtotal || vat != test.vat {
+			t.Errorf("For input '%v', expected %v + %v, but got %v + %v", test.total, test.subtotal, test.vat, subtotal, vat)
+		}
+	}
+}
+
+func TestGetReceipt(t *testing.T) {
+	paidAt := time.Date(2024, 10, 1, 8, 30, 0, 0, time.UTC)
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"pay-1": {PaymentID: "pay-1", Amount: 107, Payment_Status: model.Paid, PaidAt: &paidAt},
+		"pay-2": {PaymentID: "pay-2", Amount: 60, Payment_Status: model.Paid, PaidAt: &paidAt},
+		"pay-3": {PaymentID: "pay-3", Amount: 60, Payment_Status: model.Pending},
+	}}
+	orders := &fakeFullOrderUsecase{orders: map[string]model.FullOrder{
+		"order-1": {OrderHeaderID: "order-1", UserID: "user-1", BranchID: "branch-1", PaymentID: "pay-1", PriceLines: []model.OrderPriceLine{
+			{ServiceType: model.Washing, Weight: 14, Quantity: 1, UnitPrice: 100, Amount: 100},
+			{ServiceType: model.Delivery, Quantity: 1, UnitPrice: 20, Amount: 20},
+			{ServiceType: model.DiscountLine, Quantity: 1, UnitPrice: -13, Amount: -13},
+		}},
+		"order-2": {OrderHeaderID: "order-2", UserID: "user-2", BranchID: "branch-1", PaymentID: "pay-2", OrderDetails: []model.OrderDetail{{}, {}}},
+		"order-3": {OrderHeaderID: "order-3", UserID: "user-1", BranchID: "branch-1", PaymentID: "pay-3"},
+	}}
+	receiptRepo := &fakeReceiptRepo{numbers: map[string]int64{}}
+	contractRepo := &fakeContractRepo{contracts: []model.EmployeeContract{
+		{UserID: "employee-1", BranchID: "branch-1"},
+		{UserID: "employee-2", BranchID: "branch-2"},
+	}}
+	u := CreateNewReceiptUsecase(receiptRepo, paymentRepo, &fakeWalletRepo{}, &fakeBranchRepo{branch: model.Branch{BranchID: "branch-1", BranchName: "Zuck", OwnerUserID: "manager"}}, contractRepo, orders, &fakeUnitOfWork{})
+
+	tests := []struct {
+		name          string
+		orderHeaderID string
+		userID        string
+		userRole      model.Roles
+		receiptNo     int64
+		lines         int
+		expectErr     string
+	}{
+		{"first receipt", "order-1", "user-1", model.Client, 1, 3, ""},
+		{"asked again keeps its number", "order-1", "user-1", model.Client, 1, 3, ""},
+		{"next order takes the next number", "order-2", "manager", model.BranchManager, 2, 1, ""},
+		{"employee of the branch", "order-2", "employee-1", model.Employee, 2, 1, ""},
+		{"someone else's order", "order-1", "user-2", model.Client, 0, 0, "403"},
+		{"employee of another branch", "order-1", "employee-2", model.Employee, 0, 0, "403"},
+		{"manager of another branch", "order-1", "other-manager", model.BranchManager, 0, 0, "403"},
+		{"not paid", "order-3", "user-1", model.Client, 0, 0, "400"},
+	}
+
+	for _, test := range tests {
+		document, err := u.GetReceipt(test.orderHeaderID, test.userID, string(test.userRole))
+		if test.expectErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, but got %v", test.name, err)
+			continue
+		}
+
+		if document.ReceiptNo != test.receiptNo {
+			t.Errorf("%s: expected receipt no %d, but got %d", test.name, test.receiptNo, document.ReceiptNo)
+		}
+		if len(document.Lines) != test.lines {
+			t.Errorf("%s: expected %d lines, but got %v", test.name, test.lines, document.Lines)
+		}
+		if !document.PaidAt.Equal(paidAt) {
+			t.Errorf("%s: expected paid at %v, but got %v", test.name, paidAt, document.PaidAt)
+		}
+	}
+
+	if receiptRepo.numbers["branch-1"] != 2 || len(receiptRepo.receipts) != 2 {
+		t.Errorf("expected 2 numbers taken for 2 receipts, but got %d for %d", receiptRepo.numbers["branch-1"], len(receiptRepo.receipts))
+	}
+}
@@ -0,0 +1,168 @@
+package usecases
+
+import (
+	"errors"
+	"math"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type refundUsecase struct {
+	refundRepo      model.RefundRepository
+	walletRepo      model.WalletRepository
+	paymentRepo     model.PaymentRepository
+	orderHeaderRepo repository.OrderHeaderRepository
+	branchRepo      repository.BranchReopository
+	unitOfWork      repository.UnitOfWork
+}
+
+func CreateNewRefundUsecase(refundRepo model.RefundRepository, walletRepo model.WalletRepository, paymentRepo model.PaymentRepository, orderHeaderRepo repository.OrderHeaderRepository, branchRepo repository.BranchReopository, unitOfWork repository.UnitOfWork) model.RefundUsecase {
+	return &refundUsecase{
+		refundRepo:      refundRepo,
+		walletRepo:      walletRepo,
+		paymentRepo:     paymentRepo,
+		orderHeaderRepo: orderHeaderRepo,
+		branchRepo:      branchRepo,
+		unitOfWork:      unitOfWork,
+	}
+}
+
+// toSatang compares baht amounts without float rounding errors
+func toSatang(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// checkRefundable tells if amount more can be refunded when refunded is already refunded of paid
+func checkRefundable(paid float64, refunded float64, amount float64) error {
+	if toSatang(amount) <= 0 {
+		return errors.New("ERR 400: refund amount must be more than 0")
+	}
+
+	if toSatang(refunded)+toSatang(amount) > toSatang(paid) {
+		return errors.New("ERR 400: refunds can't be more than the paid amount")
+	}
+
+	return nil
+}
+
+// checkBranchManager allows super admin and the owner of the branch the payment was made at
+func (u *refundUsecase) checkBranchManager(paymentID string, userID string, userRole string) (*model.OrderHeader, error) {
+	order, err := u.orderHeaderRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBranchOwner(u.branchRepo, order.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// CreateRefund requests a refund of part or all of a paid payment. The payment
+// stays locked while the refunds are summed so two managers can't refund the same money.
+func (u *refundUsecase) CreateRefund(paymentID string, newRefund model.NewRefund, userID string, userRole string) (*model.Refund, error) {
+	order, err := u.checkBranchManager(paymentID, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := model.Refund{
+		RefundID:      uuid.New().String(),
+		PaymentID:     paymentID,
+		OrderHeaderID: order.OrderHeaderID,
+		Amount:        newRefund.Amount,
+		RefundStatus:  model.RefundRequested,
+		Reason:        newRefund.Reason,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     userID,
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		payment, err := u.paymentRepo.WithTx(tx).LockPayment(paymentID)
+		if err != nil {
+			return err
+		}
+
+		if payment.Payment_Status != model.Paid {
+			return errors.New("ERR 400: only paid payments can be refunded")
+		}
+
+		refunded, err := u.refundRepo.WithTx(tx).SumByPaymentID(paymentID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkRefundable(payment.Amount, refunded, refund.Amount); err != nil {
+			return err
+		}
+
+		return u.refundRepo.WithTx(tx).CreateRefund(&refund)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return u.refundRepo.FindByRefundID(refund.RefundID)
+}
+
+func (u *refundUsecase) GetByPaymentID(paymentID string, userID string, userRole string) (*[]model.Refund, error) {
+	if _, err := u.checkBranchManager(paymentID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	return u.refundRepo.FindByPaymentID(paymentID)
+}
+
+// settleRefund moves a requested refund to status once the money is sent back or the request is turned down.
+// Completing a refund of a wallet payment puts the money back into that wallet.
+func (u *refundUsecase) settleRefund(refundID string, status model.RefundStatus, userID string, userRole string) (*model.Refund, error) {
+	refund, err := u.refundRepo.FindByRefundID(refundID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.checkBranchManager(refund.PaymentID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	if refund.RefundStatus != model.RefundRequested {
+		return nil, errors.New("ERR 400: refund is already " + string(refund.RefundStatus))
+	}
+
+	err = u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		if err := u.refundRepo.WithTx(tx).UpdateRefundStatus(refundID, status, userID); err != nil {
+			return err
+		}
+
+		if status != model.RefundCompleted {
+			return nil
+		}
+
+		debit, err := u.walletRepo.WithTx(tx).FindDebitByPaymentID(refund.PaymentID)
+		if err != nil || debit == nil {
+			return err
+		}
+
+		return creditRefund(u.walletRepo.WithTx(tx), debit, *refund, userID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return u.refundRepo.FindByRefundID(refundID)
+}
+
+func (u *refundUsecase) CompleteRefund(refundID string, userID string, userRole string) (*model.Refund, error) {
+	return u.settleRefund(refundID, model.RefundCompleted, userID, userRole)
+}
+
+func (u *refundUsecase) RejectRefund(refundID string, userID string, userRole string) (*model.Refund, error) {
+	return u.settleRefund(refundID, model.RefundRejected, userID, userRole)
+}
@@ -0,0 +1,111 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+)
+
+type fakeOrderByPaymentRepo struct {
+	repository.OrderHeaderRepository
+	order model.OrderHeader
+}
+
+func (f *fakeOrderByPaymentRepo) WithTx(tx *platform.Postgres) repository.OrderHeaderRepository {
+	return f
+}
+
+func (f *fakeOrderByPaymentRepo) GetByPaymentID(paymentID string) (*model.OrderHeader, error) {
+	order := f.order
+	return &order, nil
+}
+
+type fakeBranchRepo struct {
+	repository.BranchReopository
+	branch model.Branch
+}
+
+func (f *fakeBranchRepo) GetByBranchID(branchID string) (*model.Branch, error) {
+	branch := f.branch
+	return &branch, nil
+}
+
+func TestCheckRefundable(t *testing.T) {
+	tests := []struct {
+		paid     float64
+		refunded float64
+		amount   float64
+		expected bool
+	}{
+		{100, 0, 100, true},
+		{100, 0, 40.5, true},
+		{100, 59.5, 40.5, true},
+		{0.3, 0.1, 0.2, true},
+		{100, 60, 40.01, false},
+		{100, 0, 100.01, false},
+		{100, 0, 0, false},
+		{100, 0, -5, false},
+	}
+
+	for _, test := range tests {
+		err := checkRefundable(test.paid, test.refunded, test.amount)
+		if (err == nil) != test.expected {
+			t.Errorf("For input '%v %v %v', expected %v, but got %v", test.paid, test.refunded, test.amount, test.expected, err)
+		}
+	}
+}
+
+func TestCreateRefund(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        model.PaymentStatus
+		userID        string
+		userRole      string
+		amounts       []float64
+		expectErr     string
+		expectRefunds int
+	}{
+		{"partial refunds up to the paid amount", model.Paid, "manager-1", "BranchManager", []float64{30, 70}, "", 2},
+		{"refunds over the paid amount", model.Paid, "manager-1", "BranchManager", []float64{80, 30}, "400", 1},
+		{"pending payment", model.Pending, "manager-1", "BranchManager", []float64{10}, "400", 0},
+		{"expired payment", model.Expired, "manager-1", "BranchManager", []float64{10}, "400", 0},
+		{"manager of another branch", model.Paid, "manager-2", "BranchManager", []float64{10}, "403", 0},
+		{"super admin", model.Paid, "admin-1", "SuperAdmin", []float64{100}, "", 1},
+	}
+
+	for _, test := range tests {
+		paymentRepo := &fakePaymentRepo{
+			payments: map[string]*model.Payments{
+				"payment-1": {PaymentID: "payment-1", Amount: 100, Payment_Status: test.status},
+			},
+		}
+		refundRepo := &fakeRefundRepo{}
+		orderRepo := &fakeOrderByPaymentRepo{order: model.OrderHeader{OrderHeaderID: "order-1", BranchID: "branch-1", PaymentID: "payment-1"}}
+		branchRepo := &fakeBranchRepo{branch: model.Branch{BranchID: "branch-1", OwnerUserID: "manager-1"}}
+		u := CreateNewRefundUsecase(refundRepo, &fakeWalletRepo{}, paymentRepo, orderRepo, branchRepo, &fakeUnitOfWork{})
+
+		var err error
+		for _, amount := range test.amounts {
+			var refund *model.Refund
+			refund, err = u.CreateRefund("payment-1", model.NewRefund{Amount: amount, Reason: "machine broke"}, test.userID, test.userRole)
+			if err != nil {
+				break
+			}
+			if refund.OrderHeaderID != "order-1" || refund.RefundStatus != model.RefundRequested {
+				t.Errorf("%s: expected a requested refund of order-1, but got %+v", test.name, refund)
+			}
+		}
+
+		if test.expectErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if test.expectErr != "" && (err == nil || !strings.Contains(err.Error(), test.expectErr)) {
+			t.Errorf("%s: expected error %s, but got %v", test.name, test.expectErr, err)
+		}
+		if len(refundRepo.refunds) != test.expectRefunds {
+			t.Errorf("%s: expected %d refunds, but got %d", test.name, test.expectRefunds, len(refundRepo.refunds))
+		}
+	}
+}
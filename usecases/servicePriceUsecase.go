@@ -0,0 +1,105 @@
+package usecases
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type servicePriceUsecase struct {
+	servicePriceRepository model.ServicePriceRepository
+	branchRepository       repository.BranchReopository
+}
+
+func CreateNewServicePriceUsecase(servicePriceRepository model.ServicePriceRepository, branchRepository repository.BranchReopository) model.ServicePriceUsecase {
+	return &servicePriceUsecase{
+		servicePriceRepository: servicePriceRepository,
+		branchRepository:       branchRepository,
+	}
+}
+
+func (u *servicePriceUsecase) CreateServicePrice(newPrice *model.AddServicePriceDTO, userID string, userRole string) (*model.ServicePrice, error) {
+	if err := checkDefaultOrBranchOwner(u.branchRepository, newPrice.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	effectiveFrom := time.Now().UTC()
+	if newPrice.EffectiveFrom != nil {
+		effectiveFrom = newPrice.EffectiveFrom.UTC()
+	}
+
+	data := model.ServicePrice{
+		PriceID:       uuid.New().String(),
+		BranchID:      newPrice.BranchID,
+		ServiceType:   newPrice.ServiceType,
+		Weight:        newPrice.Weight,
+		Price:         newPrice.Price,
+		EffectiveFrom: effectiveFrom,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     userID,
+		UpdatedAt:     time.Now().UTC(),
+		UpdatedBy:     userID,
+	}
+
+	if err := u.servicePriceRepository.CreateServicePrice(&data); err != nil {
+		return nil, err
+	}
+
+	return u.servicePriceRepository.FindByPriceID(data.PriceID)
+}
+
+func (u *servicePriceUsecase) FindByBranchID(branchID string) (*[]model.ServicePrice, error) {
+	return u.servicePriceRepository.FindByBranchID(branchID)
+}
+
+// UpdateServicePrice adds a new catalog entry for the same service, the old one is kept
+// so orders quoted before the change still find the price they were charged
+func (u *servicePriceUsecase) UpdateServicePrice(price *model.UpdateServicePriceDTO, userID string, userRole string) (*model.ServicePrice, error) {
+	current, err := u.servicePriceRepository.FindByPriceID(price.PriceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDefaultOrBranchOwner(u.branchRepository, current.BranchID, userID, userRole); err != nil {
+		return nil, err
+	}
+
+	effectiveFrom := time.Now().UTC()
+	if price.EffectiveFrom != nil {
+		effectiveFrom = price.EffectiveFrom.UTC()
+	}
+
+	data := model.ServicePrice{
+		PriceID:       uuid.New().String(),
+		BranchID:      current.BranchID,
+		ServiceType:   current.ServiceType,
+		Weight:        current.Weight,
+		Price:         price.Price,
+		EffectiveFrom: effectiveFrom,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     userID,
+		UpdatedAt:     time.Now().UTC(),
+		UpdatedBy:     userID,
+	}
+
+	if err := u.servicePriceRepository.CreateServicePrice(&data); err != nil {
+		return nil, err
+	}
+
+	return u.servicePriceRepository.FindByPriceID(data.PriceID)
+}
+
+func (u *servicePriceUsecase) DeleteServicePrice(priceID string, userID string, userRole string) error {
+	current, err := u.servicePriceRepository.FindByPriceID(priceID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkDefaultOrBranchOwner(u.branchRepository, current.BranchID, userID, userRole); err != nil {
+		return err
+	}
+
+	return u.servicePriceRepository.DeleteServicePrice(priceID, userID)
+}
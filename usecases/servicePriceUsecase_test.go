@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"gorm.io/gorm"
+)
+
+type fakeCatalogRepo struct {
+	model.ServicePriceRepository
+	prices map[string]model.ServicePrice
+}
+
+func (f *fakeCatalogRepo) CreateServicePrice(newPrice *model.ServicePrice) error {
+	f.prices[newPrice.PriceID] = *newPrice
+	return nil
+}
+
+func (f *fakeCatalogRepo) FindByPriceID(priceID string) (*model.ServicePrice, error) {
+	price, ok := f.prices[priceID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &price, nil
+}
+
+func TestUpdateServicePrice(t *testing.T) {
+	branchID := "b1"
+	since := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	from := since.AddDate(0, 1, 0)
+
+	repo := &fakeCatalogRepo{prices: map[string]model.ServicePrice{
+		"p1": {PriceID: "p1", BranchID: &branchID, ServiceType: model.Washing, Weight: 14, Price: 40, EffectiveFrom: since},
+	}}
+	usecase := CreateNewServicePriceUsecase(repo, &fakeBranchRepo{branch: model.Branch{BranchID: branchID, OwnerUserID: "owner"}})
+
+	if _, err := usecase.UpdateServicePrice(&model.UpdateServicePriceDTO{PriceID: "p1", Price: 50}, "other", string(model.BranchManager)); err == nil {
+		t.Errorf("Expected a manager of another branch to be forbidden")
+	}
+
+	updated, err := usecase.UpdateServicePrice(&model.UpdateServicePriceDTO{PriceID: "p1", Price: 50, EffectiveFrom: &from}, "owner", string(model.BranchManager))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if updated.PriceID == "p1" || *updated.BranchID != branchID || updated.ServiceType != model.Washing || updated.Weight != 14 {
+		t.Errorf("Expected a new entry for the same service, but got %v", updated)
+	}
+	if updated.Price != 50 || !updated.EffectiveFrom.Equal(from) {
+		t.Errorf("Expected 50 from %s, but got %v from %s", from, updated.Price, updated.EffectiveFrom)
+	}
+
+	if old := repo.prices["p1"]; old.Price != 40 || !old.EffectiveFrom.Equal(since) || len(repo.prices) != 2 {
+		t.Errorf("Expected the old price to be kept, but got %v", repo.prices)
+	}
+}
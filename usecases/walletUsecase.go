@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"errors"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+	"zuck-my-clothe/zuck-my-clothe-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+type walletUsecase struct {
+	walletRepo model.WalletRepository
+	userRepo   repository.UserRepository
+	unitOfWork repository.UnitOfWork
+}
+
+func CreateNewWalletUsecase(walletRepo model.WalletRepository, userRepo repository.UserRepository, unitOfWork repository.UnitOfWork) model.WalletUsecase {
+	return &walletUsecase{
+		walletRepo: walletRepo,
+		userRepo:   userRepo,
+		unitOfWork: unitOfWork,
+	}
+}
+
+// creditRefund gives a refund of a wallet paid payment back to the wallet it was paid from
+func creditRefund(walletRepo model.WalletRepository, debit *model.WalletTransaction, refund model.Refund, actorID string) error {
+	balance, err := walletRepo.Credit(debit.UserID, refund.Amount)
+	if err != nil {
+		return err
+	}
+
+	transaction := model.WalletTransaction{
+		TransactionID:   uuid.New().String(),
+		UserID:          debit.UserID,
+		TransactionType: model.WalletRefund,
+		Amount:          refund.Amount,
+		BalanceAfter:    balance,
+		PaymentID:       &refund.PaymentID,
+		RefundID:        &refund.RefundID,
+		Note:            &refund.Reason,
+		CreatedAt:       time.Now().UTC(),
+		CreatedBy:       actorID,
+	}
+
+	return walletRepo.CreateTransaction(&transaction)
+}
+
+// TopUp adds money a customer handed to staff to their wallet
+func (u *walletUsecase) TopUp(topUp model.WalletTopUpRequest, staffID string) (*model.WalletTransaction, error) {
+	if toSatang(topUp.Amount) <= 0 {
+		return nil, errors.New("ERR 400: top up amount must be more than 0")
+	}
+
+	if _, err := u.userRepo.FindUserByUserID(topUp.UserID); err != nil {
+		return nil, err
+	}
+
+	transaction := model.WalletTransaction{
+		TransactionID:   uuid.New().String(),
+		UserID:          topUp.UserID,
+		TransactionType: model.WalletTopUp,
+		Amount:          topUp.Amount,
+		Note:            topUp.Note,
+		CreatedAt:       time.Now().UTC(),
+		CreatedBy:       staffID,
+	}
+
+	err := u.unitOfWork.Do(func(tx *platform.Postgres) error {
+		balance, err := u.walletRepo.WithTx(tx).Credit(topUp.UserID, topUp.Amount)
+		if err != nil {
+			return err
+		}
+
+		transaction.BalanceAfter = balance
+		return u.walletRepo.WithTx(tx).CreateTransaction(&transaction)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// GetStatement returns the current balance and the ledger between from and to, both optional
+func (u *walletUsecase) GetStatement(userID string, from *time.Time, to *time.Time) (*model.WalletStatement, error) {
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, errors.New("ERR 400: from must be before to")
+	}
+
+	wallet, err := u.walletRepo.GetWallet(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := u.walletRepo.GetTransactions(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := model.WalletStatement{
+		UserID:       userID,
+		Balance:      wallet.Balance,
+		From:         from,
+		To:           to,
+		Transactions: *transactions,
+	}
+
+	return &statement, nil
+}
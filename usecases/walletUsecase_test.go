@@ -0,0 +1,130 @@
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/paymentgateway"
+	"zuck-my-clothe/zuck-my-clothe-backend/platform"
+)
+
+// fakeWalletRepo checks and changes the balance under one lock,
+// like the conditional UPDATE of the real repository
+type fakeWalletRepo struct {
+	model.WalletRepository
+	mu           sync.Mutex
+	balances     map[string]float64
+	transactions []model.WalletTransaction
+}
+
+func (f *fakeWalletRepo) WithTx(tx *platform.Postgres) model.WalletRepository { return f }
+
+func (f *fakeWalletRepo) Credit(userID string, amount float64) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.balances == nil {
+		f.balances = map[string]float64{}
+	}
+	f.balances[userID] += amount
+	return f.balances[userID], nil
+}
+
+func (f *fakeWalletRepo) Debit(userID string, amount float64) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if toSatang(f.balances[userID]) < toSatang(amount) {
+		return 0, errors.New("ERR 400: not enough wallet balance")
+	}
+	f.balances[userID] -= amount
+	return f.balances[userID], nil
+}
+
+func (f *fakeWalletRepo) CreateTransaction(transaction *model.WalletTransaction) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transactions = append(f.transactions, *transaction)
+	return nil
+}
+
+func (f *fakeWalletRepo) FindDebitByPaymentID(paymentID string) (*model.WalletTransaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, transaction := range f.transactions {
+		if transaction.TransactionType == model.WalletDebit && *transaction.PaymentID == paymentID {
+			return &transaction, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestPayWithWalletNoDoubleSpend(t *testing.T) {
+	payments := map[string]*model.Payments{}
+	for _, id := range []string{"payment-1", "payment-2", "payment-3", "payment-4", "payment-5"} {
+		payments[id] = &model.Payments{PaymentID: id, Amount: 40, Payment_Status: model.Pending, DueDate: time.Now().Add(time.Minute)}
+	}
+	paymentRepo := &fakePaymentRepo{payments: payments}
+	walletRepo := &fakeWalletRepo{balances: map[string]float64{"user-1": 100}}
+	u := CreateNewPaymentUsecase(paymentRepo, &fakeRefundRepo{}, walletRepo, &fakePaymentPolicyRepo{}, paymentgateway.CreateFakeProvider("secret"), &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, &fakeOrderByPaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(payments))
+	for id := range payments {
+		wg.Add(1)
+		go func(paymentID string) {
+			defer wg.Done()
+			_, err := u.PayWithWallet(paymentID, "user-1")
+			errs <- err
+		}(id)
+	}
+	wg.Wait()
+	close(errs)
+
+	paid := 0
+	for err := range errs {
+		if err == nil {
+			paid++
+		} else if !strings.Contains(err.Error(), "400") {
+			t.Errorf("Expected 400 when the balance runs out, but got %v", err)
+		}
+	}
+
+	if paid != 2 {
+		t.Errorf("Expected 2 payments paid from a balance of 100, but got %d", paid)
+	}
+	if walletRepo.balances["user-1"] != 20 {
+		t.Errorf("Expected balance 20, but got %v", walletRepo.balances["user-1"])
+	}
+	if len(walletRepo.transactions) != 2 {
+		t.Errorf("Expected 2 ledger lines, but got %d", len(walletRepo.transactions))
+	}
+}
+
+func TestCancelWalletPaymentRefundsWallet(t *testing.T) {
+	paymentRepo := &fakePaymentRepo{payments: map[string]*model.Payments{
+		"payment-1": {PaymentID: "payment-1", Amount: 40, Payment_Status: model.Pending, DueDate: time.Now().Add(time.Minute)},
+	}}
+	refundRepo := &fakeRefundRepo{}
+	walletRepo := &fakeWalletRepo{balances: map[string]float64{"user-1": 50}}
+	u := CreateNewPaymentUsecase(paymentRepo, refundRepo, walletRepo, &fakePaymentPolicyRepo{}, paymentgateway.CreateFakeProvider("secret"), &fakeUnitOfWork{}, &fakeMachineAssignment{}, &fakeJobRepo{}, &fakeOrderByPaymentRepo{}, &fakeBranchRepo{}, &fakeContractRepo{})
+
+	if _, err := u.PayWithWallet("payment-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := u.CancelPayment("payment-1", "order-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if walletRepo.balances["user-1"] != 50 {
+		t.Errorf("Expected balance back to 50, but got %v", walletRepo.balances["user-1"])
+	}
+	if len(refundRepo.refunds) != 1 || refundRepo.refunds[0].RefundStatus != model.RefundCompleted {
+		t.Errorf("Expected one completed refund, but got %+v", refundRepo.refunds)
+	}
+	if last := walletRepo.transactions[len(walletRepo.transactions)-1]; last.TransactionType != model.WalletRefund || last.Amount != 40 {
+		t.Errorf("Expected a refund ledger line of 40, but got %+v", last)
+	}
+}
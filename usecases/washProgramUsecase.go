@@ -0,0 +1,67 @@
+package usecases
+
+import (
+	"time"
+	"zuck-my-clothe/zuck-my-clothe-backend/model"
+
+	"github.com/google/uuid"
+)
+
+type washProgramUsecase struct {
+	washProgramRepository model.WashProgramRepository
+}
+
+func CreateNewWashProgramUsecase(washProgramRepository model.WashProgramRepository) model.WashProgramUsecase {
+	return &washProgramUsecase{washProgramRepository: washProgramRepository}
+}
+
+func (u *washProgramUsecase) CreateProgram(newProgram *model.AddWashProgramDTO, userID string) (*model.WashProgram, error) {
+	data := model.WashProgram{
+		ProgramID:       uuid.New().String(),
+		MachineType:     newProgram.MachineType,
+		Name:            newProgram.Name,
+		DurationMinutes: newProgram.DurationMinutes,
+		Temperature:     newProgram.Temperature,
+		PriceModifier:   newProgram.PriceModifier,
+		CreatedAt:       time.Now().UTC(),
+		CreatedBy:       userID,
+		UpdatedAt:       time.Now().UTC(),
+		UpdatedBy:       userID,
+	}
+
+	if err := u.washProgramRepository.CreateProgram(&data); err != nil {
+		return nil, err
+	}
+
+	return u.washProgramRepository.FindByProgramID(data.ProgramID)
+}
+
+func (u *washProgramUsecase) FindByMachineType(machineType model.MachineType) (*[]model.WashProgram, error) {
+	return u.washProgramRepository.FindByMachineType(machineType)
+}
+
+// UpdateProgram doesn't change the price of orders already placed,
+// their price lines were copied when they were ordered
+func (u *washProgramUsecase) UpdateProgram(program *model.UpdateWashProgramDTO, userID string) (*model.WashProgram, error) {
+	current, err := u.washProgramRepository.FindByProgramID(program.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+
+	current.Name = program.Name
+	current.DurationMinutes = program.DurationMinutes
+	current.Temperature = program.Temperature
+	current.PriceModifier = program.PriceModifier
+	current.UpdatedAt = time.Now().UTC()
+	current.UpdatedBy = userID
+
+	if err := u.washProgramRepository.UpdateProgram(current); err != nil {
+		return nil, err
+	}
+
+	return u.washProgramRepository.FindByProgramID(program.ProgramID)
+}
+
+func (u *washProgramUsecase) DeleteProgram(programID string, userID string) error {
+	return u.washProgramRepository.DeleteProgram(programID, userID)
+}
@@ -3,6 +3,7 @@ package validatorboi
 import (
 	"reflect"
 	"zuck-my-clothe/zuck-my-clothe-backend/model"
+	"zuck-my-clothe/zuck-my-clothe-backend/promptpay"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -24,6 +25,7 @@ func CreateValidator() string {
 	validate.RegisterValidation("requiredBool", requiredBool)
 	validate.RegisterValidation("employeeContractPosition", employeeContractValidation)
 	validate.RegisterValidation("userRoles", userRolesValidation)
+	validate.RegisterValidation("promptpayID", promptpayIDValidation)
 
 	return "success"
 }
@@ -53,7 +55,9 @@ func serviceTypeValidation(fl validator.FieldLevel) bool {
 
 	if serviceType == "Washing" ||
 		serviceType == "Drying" ||
-		serviceType == "Delivery" {
+		serviceType == "Pickup" ||
+		serviceType == "Delivery" ||
+		serviceType == "Agents" {
 		return true
 	} else {
 		return false
@@ -93,3 +97,7 @@ func userRolesValidation(fl validator.FieldLevel) bool {
 		return false
 	}
 }
+
+func promptpayIDValidation(fl validator.FieldLevel) bool {
+	return promptpay.ValidateID(fl.Field().String()) == nil
+}